@@ -0,0 +1,461 @@
+// Package analysis inspects silk programs without running them.
+package analysis
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"silk/internal/models"
+)
+
+// DefaultAssumedIterations is the iteration count charged for loops whose
+// bound cannot be inferred when Options.AssumedIterations is zero.
+const DefaultAssumedIterations = 100
+
+// Options tunes the cost estimate.
+type Options struct {
+	// AssumedIterations is charged for each loop whose iteration count cannot be inferred.
+	AssumedIterations int
+}
+
+// Report is the estimated worst-case cost of a program.
+type Report struct {
+	Cost           float64       // Estimated number of node evaluations.
+	MaxParallelism int           // Most parallel branches that may run at once.
+	Bounded        bool          // Whether every loop bound was inferred and no recursion was found.
+	UnboundedLoops []models.Node // Loops whose iteration count could not be inferred.
+	Recursive      []string      // Functions that may call themselves, directly or indirectly, sorted.
+}
+
+// Analyze estimates how many nodes program evaluates in the worst case: both
+// branches of a conditional are priced at the more expensive one, loops with a
+// constant counter are priced at their exact iteration count, and calls to
+// functions declared in the program are priced at the cost of their body.
+// Builtin calls count as a single evaluation plus their arguments, and the body
+// of a function literal is charged once, where the literal appears.
+func Analyze(program models.Node, opts Options) *Report {
+	if opts.AssumedIterations <= 0 {
+		opts.AssumedIterations = DefaultAssumedIterations
+	}
+	a := &analyzer{
+		opts:      opts,
+		functions: make(map[string]*models.FunctionDeclaration),
+		costs:     make(map[string]estimate),
+		active:    make(map[string]bool),
+		report:    &Report{Bounded: true},
+	}
+	models.Walk(program, func(node models.Node) bool {
+		if fn, ok := node.(*models.FunctionDeclaration); ok {
+			a.functions[fn.Name] = fn
+		}
+		return true
+	})
+	a.report.Recursive = a.recursiveFunctions()
+	if len(a.report.Recursive) > 0 {
+		a.report.Bounded = false
+	}
+
+	est := a.estimate(program)
+	a.report.Cost = est.cost
+	a.report.MaxParallelism = max(est.width, 1)
+	return a.report
+}
+
+// estimate is the cost of evaluating a node and the parallelism it may reach.
+type estimate struct {
+	cost  float64
+	width int
+}
+
+type analyzer struct {
+	opts      Options
+	functions map[string]*models.FunctionDeclaration
+	costs     map[string]estimate // Memoized cost of calling each function.
+	active    map[string]bool     // Functions whose cost is being computed.
+	report    *Report
+}
+
+func (a *analyzer) estimate(node models.Node) estimate {
+	switch n := node.(type) {
+	case nil, *models.Comment:
+		return estimate{}
+	case *models.Program:
+		return a.sequence(n.Body).plus(1)
+	case *models.Assignment:
+		return a.estimate(n.Value).plus(1)
+	case *models.VariableDeclaration:
+		return a.estimate(n.Value).plus(1)
+	case *models.ConstDeclaration:
+		return a.estimate(n.Value).plus(1)
+	case *models.CompoundAssignment:
+		return a.estimate(n.Value).plus(2)
+	case *models.IncDecStatement:
+		return estimate{cost: 1}
+	case *models.BinaryExpression:
+		return a.estimate(n.Left).then(a.estimate(n.Right)).plus(1)
+	case *models.ComparisonExpression:
+		return a.estimate(n.Left).then(a.estimate(n.Right)).plus(1)
+	case *models.ComparisonChain:
+		return a.sequence(n.Operands).plus(float64(len(n.Operators)))
+	case *models.IfStatement:
+		consequent, alternate := a.estimate(n.Consequent), a.estimate(n.Alternate)
+		branch := estimate{math.Max(consequent.cost, alternate.cost), max(consequent.width, alternate.width)}
+		return a.estimate(n.Condition).then(branch).plus(1)
+	case *models.MatchExpression:
+		// Every pattern and guard may be tested before the most expensive body runs.
+		tests, body := estimate{}, estimate{}
+		for _, arm := range n.Arms {
+			tests = tests.then(a.estimate(arm.Pattern)).then(a.estimate(arm.Guard))
+			armBody := a.estimate(arm.Body)
+			body = estimate{math.Max(body.cost, armBody.cost), max(body.width, armBody.width)}
+		}
+		return a.estimate(n.Value).then(tests).then(body).plus(1)
+	case *models.ParallelBlock:
+		total := estimate{cost: 1}
+		for _, stmt := range n.Body {
+			branch := a.estimate(stmt)
+			total.cost += branch.cost
+			total.width += max(branch.width, 1)
+		}
+		if n.MaxConcurrency > 0 {
+			total.width = min(total.width, n.MaxConcurrency)
+		}
+		return total
+	case *models.Race:
+		// Every statement may run to completion before one succeeds.
+		total := estimate{cost: 1}
+		for _, stmt := range n.Body {
+			branch := a.estimate(stmt)
+			total.cost += branch.cost
+			total.width += max(branch.width, 1)
+		}
+		return total
+	case *models.FunctionDeclaration, *models.MethodDeclaration:
+		return estimate{cost: 1}
+	case *models.FunctionLiteral:
+		// The calls made to a closure cannot be counted, so its body is
+		// charged once, where the closure is created.
+		return a.sequence(n.Body).plus(1)
+	case *models.FunctionCall:
+		return a.estimate(n.Callee).then(a.sequence(n.Args)).then(a.estimate(n.IdempotencyKey)).then(a.call(n.Name)).plus(1)
+	case *models.ForLoop:
+		return a.forLoop(n)
+	case *models.WhileLoop:
+		iterations := a.unbounded(n)
+		body := a.sequence(n.Body).then(a.estimate(n.Condition))
+		return estimate{1 + a.estimate(n.Condition).cost + iterations*body.cost, body.width}
+	case *models.ForEachLoop:
+		iterations, ok := collectionSize(n.Collection)
+		if !ok {
+			iterations = a.unbounded(n)
+		}
+		body := a.sequence(n.Body)
+		return estimate{1 + a.estimate(n.Collection).cost + iterations*body.cost, body.width}
+	case *models.ParallelForLoop:
+		iterations, ok := collectionSize(n.Collection)
+		if !ok {
+			iterations = a.unbounded(n)
+		}
+		body := a.sequence(n.Body)
+		width := int(iterations) * max(body.width, 1)
+		if n.MaxConcurrency > 0 {
+			width = min(width, n.MaxConcurrency)
+		}
+		return estimate{1 + a.estimate(n.Collection).cost + iterations*body.cost, width}
+	case *models.ReturnStatement:
+		return a.estimate(n.Value).then(a.sequence(n.Values)).plus(1)
+	case *models.MultiAssignment:
+		return a.estimate(n.Value).plus(1)
+	case *models.Cached:
+		return a.estimate(n.Key).then(a.sequence(n.Body)).plus(1)
+	case *models.Retry:
+		attempts := float64(max(n.Attempts, 1))
+		body := a.sequence(n.Body)
+		return estimate{1 + a.estimate(n.RetryOn).cost + attempts*body.cost, body.width}
+	case *models.WithTimeout:
+		return a.sequence(n.Body).then(a.estimate(n.Default)).plus(1)
+	default:
+		// Literals and variables, and any node without a dedicated rule: one
+		// evaluation for the node, plus the estimate of each of its children,
+		// which may hold calls and loops.
+		total, root := estimate{cost: 1}, true
+		models.Walk(node, func(child models.Node) bool {
+			if root {
+				root = false
+				return true
+			}
+			total = total.then(a.estimate(child))
+			return false
+		})
+		return total
+	}
+}
+
+// sequence is the cost of evaluating nodes one after another.
+func (a *analyzer) sequence(nodes []models.Node) estimate {
+	var total estimate
+	for _, node := range nodes {
+		total = total.then(a.estimate(node))
+	}
+	return total
+}
+
+// call is the cost of running the body of a function declared in the program.
+// Calls to builtins and undeclared functions cost nothing beyond the call
+// itself, and a recursive call is charged only once.
+func (a *analyzer) call(name string) estimate {
+	fn, ok := a.functions[name]
+	if !ok || a.active[name] {
+		return estimate{}
+	}
+	if est, ok := a.costs[name]; ok {
+		return est
+	}
+	a.active[name] = true
+	est := a.sequence(fn.Body)
+	delete(a.active, name)
+	a.costs[name] = est
+	return est
+}
+
+// forLoop prices a for loop, using the exact iteration count when it can be inferred.
+func (a *analyzer) forLoop(n *models.ForLoop) estimate {
+	iterations, ok := loopBound(n)
+	if !ok {
+		iterations = a.unbounded(n)
+	}
+	cond := a.estimate(n.Condition)
+	body := a.sequence(n.Body).then(a.estimate(n.Post))
+	cost := 1 + a.estimate(n.Initialization).cost + (iterations+1)*cond.cost + iterations*body.cost
+	return estimate{cost, max(body.width, cond.width)}
+}
+
+// unbounded records a loop whose bound is unknown and returns the iterations to charge for it.
+func (a *analyzer) unbounded(loop models.Node) float64 {
+	a.report.Bounded = false
+	a.report.UnboundedLoops = append(a.report.UnboundedLoops, loop)
+	return float64(a.opts.AssumedIterations)
+}
+
+// loopBound infers the iteration count of a loop of the form
+//
+//	for (i = a; i < b; i = i + step)
+//
+// where a, b, and step are constants and the body does not assign i. The
+// comparison may be <, >, <=, or >=, with the counter on either side.
+func loopBound(n *models.ForLoop) (float64, bool) {
+	init, ok := n.Initialization.(*models.Assignment)
+	if !ok || init.Variable == nil {
+		return 0, false
+	}
+	counter := init.Variable.Name
+	start, ok := constant(init.Value)
+	if !ok {
+		return 0, false
+	}
+
+	cond, ok := n.Condition.(*models.ComparisonExpression)
+	if !ok {
+		return 0, false
+	}
+	operator := cond.Operator
+	limit, ok := constant(cond.Right)
+	if !isVariable(cond.Left, counter) || !ok {
+		// Normalize "b > i" to "i < b".
+		limit, ok = constant(cond.Left)
+		if !ok || !isVariable(cond.Right, counter) {
+			return 0, false
+		}
+		operator = map[string]string{"<": ">", ">": "<", "<=": ">=", ">=": "<="}[operator]
+	}
+
+	step, ok := counterStep(n.Post, counter)
+	if !ok || assigns(n.Body, counter) {
+		return 0, false
+	}
+
+	switch {
+	case operator == "<" && start >= limit, operator == ">" && start <= limit,
+		operator == "<=" && start > limit, operator == ">=" && start < limit:
+		return 0, true
+	case operator == "<" && step > 0:
+		return math.Ceil((limit - start) / step), true
+	case operator == ">" && step < 0:
+		return math.Ceil((start - limit) / -step), true
+	case operator == "<=" && step > 0:
+		return math.Floor((limit-start)/step) + 1, true
+	case operator == ">=" && step < 0:
+		return math.Floor((start-limit)/-step) + 1, true
+	default:
+		return 0, false
+	}
+}
+
+// collectionSize returns the number of elements of a literal collection.
+func collectionSize(node models.Node) (float64, bool) {
+	switch n := node.(type) {
+	case *models.ArrayLiteral:
+		for _, elem := range n.Elements {
+			if _, ok := elem.(*models.Spread); ok {
+				return 0, false
+			}
+		}
+		return float64(len(n.Elements)), true
+	case *models.MapLiteral:
+		return float64(len(n.Entries)), true
+	case *models.String:
+		return float64(utf8.RuneCountInString(n.Value)), true
+	default:
+		return 0, false
+	}
+}
+
+// counterStep returns the constant amount added to counter by a post statement
+// of the form i = i + step, i = step + i, i = i - step, i += step, i -= step,
+// i++, or i--.
+func counterStep(post models.Node, counter string) (float64, bool) {
+	if incDec, ok := post.(*models.IncDecStatement); ok {
+		if incDec.Variable == nil || incDec.Variable.Name != counter {
+			return 0, false
+		}
+		switch incDec.Operator {
+		case "++":
+			return 1, true
+		case "--":
+			return -1, true
+		default:
+			return 0, false
+		}
+	}
+	if compound, ok := post.(*models.CompoundAssignment); ok {
+		if compound.Variable == nil || compound.Variable.Name != counter {
+			return 0, false
+		}
+		step, ok := constant(compound.Value)
+		switch compound.Operator {
+		case "+=":
+			return step, ok
+		case "-=":
+			return -step, ok
+		default:
+			return 0, false
+		}
+	}
+
+	assign, ok := post.(*models.Assignment)
+	if !ok || assign.Variable == nil || assign.Variable.Name != counter {
+		return 0, false
+	}
+	expr, ok := assign.Value.(*models.BinaryExpression)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case expr.Operator == "+" && isVariable(expr.Left, counter):
+		return constant(expr.Right)
+	case expr.Operator == "+" && isVariable(expr.Right, counter):
+		return constant(expr.Left)
+	case expr.Operator == "-" && isVariable(expr.Left, counter):
+		step, ok := constant(expr.Right)
+		return -step, ok
+	default:
+		return 0, false
+	}
+}
+
+// assigns reports whether any statement in body assigns to name.
+func assigns(body []models.Node, name string) bool {
+	found := false
+	assigned := func(v *models.Variable) {
+		if v != nil && v.Name == name {
+			found = true
+		}
+	}
+	for _, stmt := range body {
+		models.Walk(stmt, func(node models.Node) bool {
+			switch assign := node.(type) {
+			case *models.Assignment:
+				assigned(assign.Variable)
+			case *models.CompoundAssignment:
+				assigned(assign.Variable)
+			case *models.IncDecStatement:
+				assigned(assign.Variable)
+			case *models.MultiAssignment:
+				for _, v := range assign.Variables {
+					assigned(v)
+				}
+			}
+			return !found
+		})
+		if found {
+			break
+		}
+	}
+	return found
+}
+
+func constant(node models.Node) (float64, bool) {
+	switch n := node.(type) {
+	case *models.Number:
+		return n.Value, true
+	case *models.Integer:
+		return float64(n.Value), true
+	default:
+		return 0, false
+	}
+}
+
+func isVariable(node models.Node, name string) bool {
+	v, ok := node.(*models.Variable)
+	return ok && v.Name == name
+}
+
+// recursiveFunctions returns the declared functions that can reach themselves
+// through calls to other declared functions.
+func (a *analyzer) recursiveFunctions() []string {
+	calls := make(map[string][]string)
+	for name, fn := range a.functions {
+		for _, stmt := range fn.Body {
+			models.Walk(stmt, func(node models.Node) bool {
+				if call, ok := node.(*models.FunctionCall); ok {
+					if _, declared := a.functions[call.Name]; declared {
+						calls[name] = append(calls[name], call.Name)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var recursive []string
+	for name := range a.functions {
+		seen := make(map[string]bool)
+		stack := append([]string(nil), calls[name]...)
+		for len(stack) > 0 {
+			next := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if next == name {
+				recursive = append(recursive, name)
+				break
+			}
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, calls[next]...)
+			}
+		}
+	}
+	sort.Strings(recursive)
+	return recursive
+}
+
+// then is the cost of evaluating e followed by next.
+func (e estimate) then(next estimate) estimate {
+	return estimate{e.cost + next.cost, max(e.width, next.width)}
+}
+
+// plus adds the cost of evaluating the node itself.
+func (e estimate) plus(cost float64) estimate {
+	e.cost += cost
+	return e
+}
@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"testing"
+
+	"silk/internal/models"
+)
+
+func num(v int64) models.Node { return &models.Integer{Value: v} }
+
+func ref(name string) *models.Variable { return &models.Variable{Name: name} }
+
+func call(name string, args ...models.Node) *models.FunctionCall {
+	return &models.FunctionCall{Name: name, Args: args}
+}
+
+// countTo is the loop "for (i = 0; i < n; i++) { body }".
+func countTo(n int64, body ...models.Node) *models.ForLoop {
+	return &models.ForLoop{
+		Initialization: &models.Assignment{Variable: ref("i"), Value: num(0)},
+		Condition:      &models.ComparisonExpression{Left: ref("i"), Operator: "<", Right: num(n)},
+		Post:           &models.IncDecStatement{Variable: ref("i"), Operator: "++"},
+		Body:           body,
+	}
+}
+
+func whileTrue() models.Node {
+	return &models.WhileLoop{Condition: &models.Boolean{Value: true}, Body: []models.Node{call("work")}}
+}
+
+func program(body ...models.Node) *models.Program { return &models.Program{Body: body} }
+
+func TestLoopBound(t *testing.T) {
+	small := Analyze(program(countTo(10, call("work"))), Options{})
+	large := Analyze(program(countTo(1000, call("work"))), Options{})
+	if !small.Bounded || !large.Bounded {
+		t.Fatalf("bounded = %v, %v, want true", small.Bounded, large.Bounded)
+	}
+	if large.Cost < 50*small.Cost {
+		t.Errorf("cost of 1000 iterations = %v, cost of 10 = %v", large.Cost, small.Cost)
+	}
+}
+
+func TestUnboundedLoop(t *testing.T) {
+	loop := whileTrue()
+	report := Analyze(program(loop), Options{AssumedIterations: 7})
+	if report.Bounded || len(report.UnboundedLoops) != 1 || report.UnboundedLoops[0] != loop {
+		t.Errorf("report = %+v, want the while loop unbounded", report)
+	}
+}
+
+func TestRecursion(t *testing.T) {
+	f := &models.FunctionDeclaration{Name: "f", Body: []models.Node{call("g")}}
+	g := &models.FunctionDeclaration{Name: "g", Body: []models.Node{call("f")}}
+	report := Analyze(program(f, g, call("f")), Options{})
+	if report.Bounded || len(report.Recursive) != 2 || report.Recursive[0] != "f" || report.Recursive[1] != "g" {
+		t.Errorf("report = %+v, want f and g recursive", report)
+	}
+}
+
+func TestParallelism(t *testing.T) {
+	block := &models.ParallelBlock{Body: []models.Node{call("a"), call("b"), call("c")}}
+	if width := Analyze(program(block), Options{}).MaxParallelism; width != 3 {
+		t.Errorf("MaxParallelism = %d, want 3", width)
+	}
+	block.MaxConcurrency = 2
+	if width := Analyze(program(block), Options{}).MaxParallelism; width != 2 {
+		t.Errorf("MaxParallelism = %d, want 2", width)
+	}
+}
+
+// Loops and parallel blocks under nodes without a rule of their own, and in
+// closures, are still found.
+func TestNestedNodes(t *testing.T) {
+	heavy := &models.FunctionDeclaration{Name: "heavy", Body: []models.Node{countTo(1000, call("work"))}}
+	tests := []struct {
+		name string
+		node models.Node
+	}{
+		{"unary", &models.UnaryExpression{Operator: "-", Operand: call("heavy")}},
+		{"index", &models.IndexExpression{Object: &models.ArrayLiteral{Elements: []models.Node{call("heavy")}}, Index: num(0)}},
+		{"async", &models.AsyncCall{Call: call("heavy")}},
+		{"spawn", &models.Spawn{Body: []models.Node{call("heavy")}}},
+		{"closure", &models.FunctionLiteral{Body: []models.Node{call("heavy")}}},
+	}
+	base := Analyze(program(heavy), Options{}).Cost
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cost := Analyze(program(heavy, tt.node), Options{}).Cost; cost < base+1000 {
+				t.Errorf("cost = %v, want the call to heavy charged (%v)", cost, base)
+			}
+		})
+	}
+
+	closure := &models.FunctionLiteral{Body: []models.Node{whileTrue()}}
+	if report := Analyze(program(closure), Options{}); report.Bounded {
+		t.Error("a closure with an unbounded loop was reported bounded")
+	}
+	spawn := &models.Spawn{Body: []models.Node{&models.ParallelBlock{Body: []models.Node{call("a"), call("b")}}}}
+	if width := Analyze(program(spawn), Options{}).MaxParallelism; width != 2 {
+		t.Errorf("MaxParallelism under spawn = %d, want 2", width)
+	}
+}
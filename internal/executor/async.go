@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Future is the value of an AsyncCall or Spawn: the result of work running in
+// the background. The work runs on the scheduler when a slot is free, or at
+// the latest on the goroutine that awaits it.
+type Future struct {
+	group *taskGroup
+	val   interface{}
+	err   error
+}
+
+// String renders the future for output.
+func (f *Future) String() string {
+	return "<future>"
+}
+
+// wait blocks until the call has finished and returns its result. A future
+// may be awaited any number of times, from any goroutine.
+func (f *Future) wait() (interface{}, error) {
+	f.group.Wait()
+	return f.val, f.err
+}
+
+// handleAsyncCall starts the call of n in the background.
+func (e *Executor) handleAsyncCall(n *models.AsyncCall, env *Environment) (interface{}, error) {
+	if n.Call == nil {
+		return nil, errors.New("async: missing call")
+	}
+	return e.start(n, func() (interface{}, error) {
+		if e.dispatcher != nil {
+			return e.executeRemote(n.Call, env)
+		}
+		return e.eval(n.Call, newEnvironment(env))
+	}), nil
+}
+
+// handleSpawn starts the body of n in the background.
+func (e *Executor) handleSpawn(n *models.Spawn, env *Environment) (interface{}, error) {
+	return e.start(n, func() (interface{}, error) {
+		val, err := e.blockValue(n.Body, newEnvironment(env))
+		return val, loopSignalError(err)
+	}), nil
+}
+
+// start runs work for node on the scheduler and returns a future for its
+// result.
+func (e *Executor) start(node models.Node, work func() (interface{}, error)) *Future {
+	f := &Future{group: e.scheduler.group()}
+	f.group.Go(func() {
+		f.val, f.err = protect(node, work)
+	})
+	return f
+}
+
+// handleAwait waits for the future n.Value evaluates to.
+func (e *Executor) handleAwait(n *models.Await, env *Environment) (interface{}, error) {
+	val, err := e.eval(n.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := val.(*Future)
+	if !ok {
+		return nil, fmt.Errorf("await expects a future, got %v", val)
+	}
+	return f.wait()
+}
+
+// registerFutureBuiltins registers builtins for waiting on futures, such as the
+// task handles of Spawn:
+//
+//	join(task)                the result of task, waiting for it to finish
+//	joinAll(tasks)            list of the results of a list of tasks, in order
+//
+// joinAll waits for every task, then fails with the error of the first failed
+// task in the list, if any.
+func (e *Executor) registerFutureBuiltins() {
+	e.RegisterBuiltin("join", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("join", args, 1); err != nil {
+			return nil, err
+		}
+		f, err := futureArg("join", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f.wait()
+	})
+	e.RegisterBuiltin("joinAll", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("joinAll", args, 1); err != nil {
+			return nil, err
+		}
+		tasks, err := listArg("joinAll", args[0])
+		if err != nil {
+			return nil, err
+		}
+		futures := make([]*Future, len(tasks))
+		for i, task := range tasks {
+			if futures[i], err = futureArg("joinAll", task); err != nil {
+				return nil, err
+			}
+		}
+		results := make([]interface{}, len(futures))
+		var first error
+		for i, f := range futures {
+			if results[i], err = f.wait(); err != nil && first == nil {
+				first = err
+			}
+		}
+		if first != nil {
+			return nil, first
+		}
+		return results, nil
+	})
+}
+
+func futureArg(name string, v interface{}) (*Future, error) {
+	f, ok := v.(*Future)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a task or future, got %v", name, v)
+	}
+	return f, nil
+}
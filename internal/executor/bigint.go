@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Big integers are *big.Int values, created by BigInt literals and the bigint
+// builtin. Arithmetic on two big integers, or on a big integer and a whole
+// number, gives a big integer, so integer results are never rounded. Division
+// truncates toward zero and % takes the sign of the dividend.
+
+// parseBigInt parses the decimal text of a big integer.
+func parseBigInt(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid big integer %q", s)
+	}
+	return n, nil
+}
+
+// toBigInt converts a big integer, an integer, or a whole number to a big integer.
+func toBigInt(v interface{}) (*big.Int, bool) {
+	switch v := v.(type) {
+	case *big.Int:
+		return v, true
+	case int64:
+		return big.NewInt(v), true
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		n, _ := big.NewFloat(v).Int(nil)
+		return n, true
+	default:
+		return nil, false
+	}
+}
+
+// bigOperands converts the operands of an operator to big integers when at
+// least one of them is a big integer. ok is false if neither is; err is set if
+// the other operand cannot be converted.
+func bigOperands(operator string, left, right interface{}) (l, r *big.Int, ok bool, err error) {
+	_, leftBig := left.(*big.Int)
+	_, rightBig := right.(*big.Int)
+	if !leftBig && !rightBig {
+		return nil, nil, false, nil
+	}
+	l, lok := toBigInt(left)
+	if !lok {
+		return nil, nil, true, fmt.Errorf("operator %s on a big integer needs a big integer or whole number, got %v", operator, left)
+	}
+	r, rok := toBigInt(right)
+	if !rok {
+		return nil, nil, true, fmt.Errorf("operator %s on a big integer needs a big integer or whole number, got %v", operator, right)
+	}
+	return l, r, true, nil
+}
+
+// bigBinary applies an arithmetic or bitwise operator to big integers,
+// reporting false if neither operand is one.
+func bigBinary(operator string, left, right interface{}) (interface{}, bool, error) {
+	l, r, ok, err := bigOperands(operator, left, right)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	z := new(big.Int)
+	switch operator {
+	case "+":
+		return z.Add(l, r), true, nil
+	case "-":
+		return z.Sub(l, r), true, nil
+	case "*":
+		return z.Mul(l, r), true, nil
+	case "/":
+		if r.Sign() == 0 {
+			return nil, true, errors.New("division by zero")
+		}
+		return z.Quo(l, r), true, nil
+	case "%":
+		if r.Sign() == 0 {
+			return nil, true, errors.New("modulo by zero")
+		}
+		return z.Rem(l, r), true, nil
+	case "**":
+		if r.Sign() < 0 {
+			return nil, true, errors.New("big integer exponent must not be negative")
+		}
+		return z.Exp(l, r, nil), true, nil
+	case "&":
+		return z.And(l, r), true, nil
+	case "|":
+		return z.Or(l, r), true, nil
+	case "^":
+		return z.Xor(l, r), true, nil
+	case "<<", ">>":
+		if r.Sign() < 0 || !r.IsUint64() || r.Uint64() > math.MaxUint32 {
+			return nil, true, fmt.Errorf("shift count out of range: %v", r)
+		}
+		if operator == "<<" {
+			return z.Lsh(l, uint(r.Uint64())), true, nil
+		}
+		return z.Rsh(l, uint(r.Uint64())), true, nil
+	default:
+		return nil, true, fmt.Errorf("unknown operator: %s", operator)
+	}
+}
+
+// bigOrder compares a big integer with a big integer, integer, or number, returning
+// -1, 0, or +1. It reports false if neither operand is a big integer.
+func bigOrder(left, right interface{}) (int, bool) {
+	l, lok := left.(*big.Int)
+	r, rok := right.(*big.Int)
+	switch {
+	case lok && rok:
+		return l.Cmp(r), true
+	case lok:
+		if i, ok := right.(int64); ok {
+			return l.Cmp(big.NewInt(i)), true
+		}
+		if f, ok := right.(float64); ok && !math.IsNaN(f) {
+			return new(big.Float).SetInt(l).Cmp(big.NewFloat(f)), true
+		}
+	case rok:
+		if i, ok := left.(int64); ok {
+			return big.NewInt(i).Cmp(r), true
+		}
+		if f, ok := left.(float64); ok && !math.IsNaN(f) {
+			return big.NewFloat(f).Cmp(new(big.Float).SetInt(r)), true
+		}
+	}
+	return 0, false
+}
+
+// registerBigIntBuiltins registers builtins for converting to and from big
+// integers:
+//
+//	bigint(value)             big integer from a whole number or decimal text
+//	number(value)             number from an integer, big integer, decimal, or numeric text,
+//	                          rounded to the nearest number if it is too precise
+func (e *Executor) registerBigIntBuiltins() {
+	e.RegisterBuiltin("bigint", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("bigint", args, 1); err != nil {
+			return nil, err
+		}
+		if s, ok := args[0].(string); ok {
+			return parseBigInt(s)
+		}
+		n, ok := toBigInt(args[0])
+		if !ok {
+			return nil, fmt.Errorf("bigint: expected a whole number or text, got %v", args[0])
+		}
+		return n, nil
+	})
+	e.RegisterBuiltin("number", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("number", args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case *big.Int:
+			f, _ := new(big.Float).SetInt(v).Float64()
+			return f, nil
+		case *Decimal:
+			return v.Float64(), nil
+		case string:
+			f, ok := parseNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("number: invalid number %q", v)
+			}
+			return f, nil
+		default:
+			return nil, fmt.Errorf("number: expected an integer, big integer, decimal, or text, got %v", v)
+		}
+	})
+}
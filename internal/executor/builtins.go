@@ -0,0 +1,160 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// registerStandardBuiltins registers the builtins every executor provides.
+// Hosts may replace any of them with RegisterBuiltin.
+//
+//	print(values...)          write values separated by spaces, then a newline, to Stdout
+//	printf(format, values...) write values formatted with a Go format string to Stdout
+//	eprint(values...)         like print, but to Stderr
+//	len(value)                number of elements in a list or map, or characters in a string
+//	keys(map)                 sorted list of a map's keys
+//	hasKey(map, key)          whether a map contains key
+//	deleteKey(map, key)       remove key from a map
+//	typeof(value)             type name of a value: "number", "int", "string", "bool",
+//	                          "null", "list", "map", "tuple", "function", "generator",
+//	                          "matrix", "range", "time", "duration", "bigint", "decimal",
+//	                          "regex", "future", "mutex", "semaphore", "atomic", "enum",
+//	                          "module", or the name of a struct or enum type
+func (e *Executor) registerStandardBuiltins() {
+	e.RegisterBuiltin("print", func(args []interface{}) (interface{}, error) {
+		return nil, e.writeOutput(e.stdout, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, args...)
+			return err
+		})
+	})
+	e.RegisterBuiltin("printf", func(args []interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, errors.New("printf expects a format string")
+		}
+		format, err := stringArg("printf", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, e.writeOutput(e.stdout, func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, format, args[1:]...)
+			return err
+		})
+	})
+	e.RegisterBuiltin("eprint", func(args []interface{}) (interface{}, error) {
+		return nil, e.writeOutput(e.stderr, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, args...)
+			return err
+		})
+	})
+	e.RegisterBuiltin("len", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("len", args, 1); err != nil {
+			return nil, err
+		}
+		n, ok := length(args[0])
+		if !ok {
+			return nil, fmt.Errorf("len: value has no length: %v", args[0])
+		}
+		return float64(n), nil
+	})
+	e.RegisterBuiltin("keys", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("keys", args, 1); err != nil {
+			return nil, err
+		}
+		m, err := mapArg("keys", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return stringList(sortedKeys(m)), nil
+	})
+	e.RegisterBuiltin("hasKey", func(args []interface{}) (interface{}, error) {
+		m, key, err := mapKeyArgs("hasKey", args)
+		if err != nil {
+			return nil, err
+		}
+		_, ok := m[key]
+		return ok, nil
+	})
+	e.RegisterBuiltin("deleteKey", func(args []interface{}) (interface{}, error) {
+		m, key, err := mapKeyArgs("deleteKey", args)
+		if err != nil {
+			return nil, err
+		}
+		delete(m, key)
+		return nil, nil
+	})
+	e.RegisterBuiltin("typeof", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("typeof", args, 1); err != nil {
+			return nil, err
+		}
+		return typeName(args[0]), nil
+	})
+}
+
+// expectArgs checks that a builtin received exactly n arguments.
+func expectArgs(name string, args []interface{}, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("%s expects %d arguments, but got %d", name, n, len(args))
+	}
+	return nil
+}
+
+// intArg converts a numeric builtin argument to an int, rejecting fractional values.
+func intArg(name string, v interface{}) (int, error) {
+	i, ok := toInteger(v)
+	if !ok {
+		return 0, fmt.Errorf("%s: expected an integer, got %v", name, v)
+	}
+	return int(i), nil
+}
+
+// optionsArg extracts an optional trailing options map from a builtin's arguments.
+// It returns the options (empty if absent) and the remaining positional arguments.
+func optionsArg(name string, args []interface{}, positional int) (map[string]interface{}, []interface{}, error) {
+	switch len(args) {
+	case positional:
+		return map[string]interface{}{}, args, nil
+	case positional + 1:
+		opts, ok := args[positional].(map[string]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: options must be a map, got %v", name, args[positional])
+		}
+		return opts, args[:positional], nil
+	default:
+		return nil, nil, fmt.Errorf("%s expects %d or %d arguments, but got %d", name, positional, positional+1, len(args))
+	}
+}
+
+// mapArg converts a builtin argument to a map.
+func mapArg(name string, v interface{}) (map[string]interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a map, got %v", name, v)
+	}
+	return m, nil
+}
+
+// mapKeyArgs extracts the map and string key arguments of a builtin.
+func mapKeyArgs(name string, args []interface{}) (map[string]interface{}, string, error) {
+	if err := expectArgs(name, args, 2); err != nil {
+		return nil, "", err
+	}
+	m, err := mapArg(name, args[0])
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := stringArg(name, args[1])
+	if err != nil {
+		return nil, "", err
+	}
+	return m, key, nil
+}
+
+// stringArg converts a builtin argument to a string.
+func stringArg(name string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: expected a string, got %v", name, v)
+	}
+	return s, nil
+}
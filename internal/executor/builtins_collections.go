@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// registerCollectionBuiltins registers the higher-order list builtins. Each
+// takes a list or iterator and a function value or the name of a function, and
+// calls the function once per element, in order:
+//
+//	map(list, fn)               list of fn(element) for every element
+//	filter(list, fn)            list of the elements for which fn(element) is truthy
+//	reduce(list, fn[, initial]) fold the list with fn(accumulator, element), starting
+//	                            from initial, or from the first element if omitted
+func (e *Executor) registerCollectionBuiltins() {
+	e.RegisterContextBuiltin("map", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		list, fn, err := e.listFunctionArgs("map", args)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(list))
+		for i, elem := range list {
+			if out[i], err = e.callFunctionCtx(ctx, fn, []interface{}{elem}); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	})
+	e.RegisterContextBuiltin("filter", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		list, fn, err := e.listFunctionArgs("filter", args)
+		if err != nil {
+			return nil, err
+		}
+		out := []interface{}{}
+		for _, elem := range list {
+			keep, err := e.callFunctionCtx(ctx, fn, []interface{}{elem})
+			if err != nil {
+				return nil, err
+			}
+			if isTruthy(keep) {
+				out = append(out, elem)
+			}
+		}
+		return out, nil
+	})
+	e.RegisterContextBuiltin("reduce", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("reduce expects 2 or 3 arguments, but got %d", len(args))
+		}
+		list, fn, err := e.listFunctionArgs("reduce", args[:2])
+		if err != nil {
+			return nil, err
+		}
+		var acc interface{}
+		if len(args) == 3 {
+			acc = args[2]
+		} else {
+			if len(list) == 0 {
+				return nil, errors.New("reduce: empty list and no initial value")
+			}
+			acc, list = list[0], list[1:]
+		}
+		return e.fold(ctx, fn, acc, list)
+	})
+}
+
+// fold combines acc with each element of list in turn using fn, called as
+// by a builtin given ctx.
+func (e *Executor) fold(ctx context.Context, fn *Function, acc interface{}, list []interface{}) (interface{}, error) {
+	for _, elem := range list {
+		var err error
+		if acc, err = e.callFunctionCtx(ctx, fn, []interface{}{acc, elem}); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// listFunctionArgs extracts the list and function arguments of a higher-order builtin.
+func (e *Executor) listFunctionArgs(name string, args []interface{}) ([]interface{}, *Function, error) {
+	if err := expectArgs(name, args, 2); err != nil {
+		return nil, nil, err
+	}
+	list, err := e.sequenceArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := e.functionArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, fn, nil
+}
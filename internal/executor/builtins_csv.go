@@ -0,0 +1,277 @@
+package executor
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RegisterCSVBuiltins registers builtins for reading and writing CSV data.
+//
+// With a header row (the default), rows are maps from column name to cell text;
+// without one, rows are arrays of cell text. Every builtin accepts an optional
+// trailing options map:
+//
+//	delimiter  single-character field separator (default ",")
+//	header     whether the first row names the columns (default true)
+//	columns    column order used when writing map rows (default: sorted keys of the first row)
+//
+// The builtins are:
+//
+//	csvParse(text[, options])             parse CSV text into an array of rows
+//	csvRead(path[, options])              read a CSV file into an array of rows
+//	csvStream(path, handler[, options])   call handler, a function or function name, once per row; returns the row count
+//	csvFormat(rows[, options])            render rows as CSV text
+//	csvWrite(path, rows[, options])       write rows to a file; returns the row count
+func (e *Executor) RegisterCSVBuiltins() {
+	e.RegisterBuiltin("csvParse", func(args []interface{}) (interface{}, error) {
+		opts, args, err := csvOptionsArg("csvParse", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		text, err := stringArg("csvParse", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return readCSV(strings.NewReader(text), opts, nil)
+	})
+	e.RegisterBuiltin("csvRead", func(args []interface{}) (interface{}, error) {
+		opts, args, err := csvOptionsArg("csvRead", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		path, err := stringArg("csvRead", args[0])
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("csvRead: %w", err)
+		}
+		defer f.Close()
+		return readCSV(f, opts, nil)
+	}, CapFS)
+	e.RegisterContextBuiltin("csvStream", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		opts, args, err := csvOptionsArg("csvStream", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		path, err := stringArg("csvStream", args[0])
+		if err != nil {
+			return nil, err
+		}
+		handler, err := e.functionArg("csvStream", args[1])
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("csvStream: %w", err)
+		}
+		defer f.Close()
+		count := 0
+		_, err = readCSV(f, opts, func(row interface{}) error {
+			count++
+			_, err := e.callFunctionCtx(ctx, handler, []interface{}{row})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return float64(count), nil
+	}, CapFS)
+	e.RegisterBuiltin("csvFormat", func(args []interface{}) (interface{}, error) {
+		opts, args, err := csvOptionsArg("csvFormat", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		if _, err := writeCSV(&sb, args[0], opts); err != nil {
+			return nil, fmt.Errorf("csvFormat: %w", err)
+		}
+		return sb.String(), nil
+	})
+	e.RegisterBuiltin("csvWrite", func(args []interface{}) (interface{}, error) {
+		opts, args, err := csvOptionsArg("csvWrite", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		path, err := stringArg("csvWrite", args[0])
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("csvWrite: %w", err)
+		}
+		count, err := writeCSV(f, args[1], opts)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csvWrite: %w", err)
+		}
+		return float64(count), nil
+	}, CapFS)
+}
+
+// csvOptions are the parsed options shared by the CSV builtins.
+type csvOptions struct {
+	delimiter rune
+	header    bool
+	columns   []string
+}
+
+func csvOptionsArg(name string, args []interface{}, positional int) (csvOptions, []interface{}, error) {
+	raw, args, err := optionsArg(name, args, positional)
+	if err != nil {
+		return csvOptions{}, nil, err
+	}
+	opts := csvOptions{delimiter: ',', header: true}
+	if v, ok := raw["delimiter"]; ok {
+		d, isString := v.(string)
+		if !isString || utf8.RuneCountInString(d) != 1 {
+			return csvOptions{}, nil, fmt.Errorf("%s: delimiter must be a single character, got %v", name, v)
+		}
+		opts.delimiter, _ = utf8.DecodeRuneInString(d)
+	}
+	if v, ok := raw["header"]; ok {
+		h, isBool := v.(bool)
+		if !isBool {
+			return csvOptions{}, nil, fmt.Errorf("%s: header must be a boolean, got %v", name, v)
+		}
+		opts.header = h
+	}
+	if v, ok := raw["columns"]; ok {
+		cols, isList := v.([]interface{})
+		if !isList {
+			return csvOptions{}, nil, fmt.Errorf("%s: columns must be an array of strings, got %v", name, v)
+		}
+		for _, col := range cols {
+			c, isString := col.(string)
+			if !isString {
+				return csvOptions{}, nil, fmt.Errorf("%s: columns must be an array of strings, got %v", name, v)
+			}
+			opts.columns = append(opts.columns, c)
+		}
+	}
+	return opts, args, nil
+}
+
+// readCSV decodes rows from r. If emit is nil the rows are collected and returned;
+// otherwise each row is passed to emit as soon as it is read and nothing is retained.
+func readCSV(r io.Reader, opts csvOptions, emit func(row interface{}) error) ([]interface{}, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.delimiter
+	reader.ReuseRecord = emit != nil
+
+	var header []string
+	if opts.header {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return []interface{}{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		header = append([]string(nil), record...)
+		reader.FieldsPerRecord = len(header)
+	}
+
+	rows := []interface{}{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var row interface{}
+		if header != nil {
+			m := make(map[string]interface{}, len(header))
+			for i, col := range header {
+				m[col] = record[i]
+			}
+			row = m
+		} else {
+			row = stringList(record)
+		}
+
+		if emit == nil {
+			rows = append(rows, row)
+		} else if err := emit(row); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeCSV encodes an array of map or array rows to w and returns the number of rows written.
+func writeCSV(w io.Writer, value interface{}, opts csvOptions) (int, error) {
+	rows, ok := value.([]interface{})
+	if !ok {
+		return 0, fmt.Errorf("rows must be an array, got %v", value)
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.delimiter
+
+	columns := opts.columns
+	if len(rows) > 0 && columns == nil {
+		if first, isMap := rows[0].(map[string]interface{}); isMap {
+			for col := range first {
+				columns = append(columns, col)
+			}
+			sort.Strings(columns)
+		}
+	}
+	if opts.header && columns != nil {
+		if err := writer.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, row := range rows {
+		var record []string
+		switch row := row.(type) {
+		case map[string]interface{}:
+			record = make([]string, len(columns))
+			for j, col := range columns {
+				record[j] = formatCell(row[col])
+			}
+		case []interface{}:
+			record = make([]string, len(row))
+			for j, cell := range row {
+				record[j] = formatCell(cell)
+			}
+		default:
+			return 0, fmt.Errorf("row %d must be a map or an array, got %v", i, row)
+		}
+		if err := writer.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	writer.Flush()
+	return len(rows), writer.Error()
+}
+
+// formatCell renders a value as CSV cell text, avoiding exponent notation for numbers.
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
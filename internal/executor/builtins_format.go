@@ -0,0 +1,269 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale describes how numbers, currency amounts, and dates are rendered for a region.
+type Locale struct {
+	Tag              string
+	DecimalSeparator string
+	GroupSeparator   string
+	Currency         string            // Default ISO 4217 currency code.
+	CurrencyFirst    bool              // Whether the currency symbol precedes the amount.
+	CurrencySpacing  string            // Separator between the symbol and the amount.
+	DateLayouts      map[string]string // Go time layouts by style: "short", "medium", "long".
+	Months           []string          // Full month names, January first; nil keeps English.
+	ShortMonths      []string          // Abbreviated month names, January first; nil keeps English.
+}
+
+// locales are the built-in locale definitions, keyed by tag.
+var locales = map[string]*Locale{
+	"en-US": {
+		Tag: "en-US", DecimalSeparator: ".", GroupSeparator: ",",
+		Currency: "USD", CurrencyFirst: true,
+		DateLayouts: map[string]string{"short": "1/2/06", "medium": "Jan 2, 2006", "long": "January 2, 2006"},
+	},
+	"en-GB": {
+		Tag: "en-GB", DecimalSeparator: ".", GroupSeparator: ",",
+		Currency: "GBP", CurrencyFirst: true,
+		DateLayouts: map[string]string{"short": "02/01/2006", "medium": "2 Jan 2006", "long": "2 January 2006"},
+	},
+	"de-DE": {
+		Tag: "de-DE", DecimalSeparator: ",", GroupSeparator: ".",
+		Currency: "EUR", CurrencySpacing: " ",
+		DateLayouts: map[string]string{"short": "02.01.06", "medium": "02.01.2006", "long": "2. January 2006"},
+		Months:      []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: []string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+	},
+	"fr-FR": {
+		Tag: "fr-FR", DecimalSeparator: ",", GroupSeparator: " ",
+		Currency: "EUR", CurrencySpacing: " ",
+		DateLayouts: map[string]string{"short": "02/01/2006", "medium": "2 Jan 2006", "long": "2 January 2006"},
+		Months:      []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: []string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+	},
+	"es-ES": {
+		Tag: "es-ES", DecimalSeparator: ",", GroupSeparator: ".",
+		Currency: "EUR", CurrencySpacing: " ",
+		DateLayouts: map[string]string{"short": "2/1/06", "medium": "2 Jan 2006", "long": "2 de January de 2006"},
+		Months:      []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: []string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+	},
+	"ja-JP": {
+		Tag: "ja-JP", DecimalSeparator: ".", GroupSeparator: ",",
+		Currency: "JPY", CurrencyFirst: true,
+		DateLayouts: map[string]string{"short": "2006/01/02", "medium": "2006/01/02", "long": "2006年1月2日"},
+	},
+}
+
+// currencies maps ISO 4217 codes to their symbol and minor-unit digits.
+var currencies = map[string]struct {
+	symbol   string
+	decimals int
+}{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"CHF": {"CHF", 2},
+	"CAD": {"CA$", 2},
+	"AUD": {"A$", 2},
+}
+
+// LookupLocale returns the built-in locale with the given tag, such as "de-DE".
+func LookupLocale(tag string) (*Locale, bool) {
+	l, ok := locales[tag]
+	return l, ok
+}
+
+// SetLocale selects the locale used by the formatting builtins. Passing nil
+// restores the default, en-US.
+func (e *Executor) SetLocale(l *Locale) {
+	e.locale = l
+}
+
+// currentLocale returns the configured locale, defaulting to en-US.
+func (e *Executor) currentLocale() *Locale {
+	if e.locale == nil {
+		return locales["en-US"]
+	}
+	return e.locale
+}
+
+// FormatNumber renders f with grouped digits. A negative decimals value prints
+// up to three fraction digits with trailing zeros removed.
+func (l *Locale) FormatNumber(f float64, decimals int) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	digits := decimals
+	if digits < 0 {
+		digits = 3
+	}
+	// Round half away from zero, as reports expect, rather than to even.
+	scale := math.Pow(10, float64(digits))
+	text := strconv.FormatFloat(math.Round(math.Abs(f)*scale)/scale, 'f', digits, 64)
+	whole, frac, _ := strings.Cut(text, ".")
+	if decimals < 0 {
+		frac = strings.TrimRight(frac, "0")
+	}
+
+	var sb strings.Builder
+	if f < 0 && strings.Trim(text, "0.") != "" {
+		sb.WriteString("-")
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(l.GroupSeparator)
+		}
+		sb.WriteRune(r)
+	}
+	if frac != "" {
+		sb.WriteString(l.DecimalSeparator)
+		sb.WriteString(frac)
+	}
+	return sb.String()
+}
+
+// FormatCurrency renders an amount in the given ISO 4217 currency, or the
+// locale's default currency if code is empty.
+func (l *Locale) FormatCurrency(amount float64, code string) string {
+	if code == "" {
+		code = l.Currency
+	}
+	symbol, decimals := code, 2
+	if c, ok := currencies[code]; ok {
+		symbol, decimals = c.symbol, c.decimals
+	}
+	spacing := l.CurrencySpacing
+	if symbol == code && spacing == "" {
+		spacing = " " // Keep bare currency codes apart from the digits.
+	}
+
+	number := l.FormatNumber(math.Abs(amount), decimals)
+	sign := ""
+	if amount < 0 && strings.Trim(number, "0"+l.DecimalSeparator+l.GroupSeparator) != "" {
+		sign = "-"
+	}
+	if l.CurrencyFirst {
+		return sign + symbol + spacing + number
+	}
+	return sign + number + spacing + symbol
+}
+
+// FormatDate renders t in one of the locale's date styles ("short", "medium",
+// "long"), or as ISO 8601 for the style "iso".
+func (l *Locale) FormatDate(t time.Time, style string) (string, error) {
+	if style == "iso" {
+		return t.Format(time.RFC3339), nil
+	}
+	layout, ok := l.DateLayouts[style]
+	if !ok {
+		return "", fmt.Errorf("unknown date style: %s", style)
+	}
+	text := t.Format(layout)
+	month := int(t.Month()) - 1
+	switch {
+	case strings.Contains(layout, "January") && l.Months != nil:
+		text = strings.Replace(text, t.Month().String(), l.Months[month], 1)
+	case strings.Contains(layout, "Jan") && l.ShortMonths != nil:
+		text = strings.Replace(text, t.Month().String()[:3], l.ShortMonths[month], 1)
+	}
+	return text, nil
+}
+
+// RegisterFormatBuiltins registers builtins that format values for the
+// executor's locale (see SetLocale):
+//
+//	formatNumber(n[, decimals])     grouped number, e.g. "1,234.5" or "1.234,5"
+//	formatCurrency(n[, code])       currency amount, e.g. "$1,234.50" or "1.234,50 €"
+//	formatDate(t[, style])          date in the "short", "medium" (default), "long", or "iso" style
+//
+// formatDate accepts Unix timestamps in seconds (interpreted in UTC) and RFC 3339 strings.
+func (e *Executor) RegisterFormatBuiltins() {
+	e.RegisterBuiltin("formatNumber", func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("formatNumber expects 1 or 2 arguments, but got %d", len(args))
+		}
+		n, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("formatNumber: expected a number, got %v", args[0])
+		}
+		decimals := -1
+		if len(args) == 2 {
+			d, err := intArg("formatNumber", args[1])
+			if err != nil {
+				return nil, err
+			}
+			if d < 0 {
+				return nil, fmt.Errorf("formatNumber: decimals must not be negative, got %d", d)
+			}
+			decimals = d
+		}
+		return e.currentLocale().FormatNumber(n, decimals), nil
+	})
+	e.RegisterBuiltin("formatCurrency", func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("formatCurrency expects 1 or 2 arguments, but got %d", len(args))
+		}
+		n, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("formatCurrency: expected a number, got %v", args[0])
+		}
+		code := ""
+		if len(args) == 2 {
+			c, err := stringArg("formatCurrency", args[1])
+			if err != nil {
+				return nil, err
+			}
+			code = strings.ToUpper(c)
+		}
+		return e.currentLocale().FormatCurrency(n, code), nil
+	})
+	e.RegisterBuiltin("formatDate", func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("formatDate expects 1 or 2 arguments, but got %d", len(args))
+		}
+		t, err := timeArg("formatDate", args[0])
+		if err != nil {
+			return nil, err
+		}
+		style := "medium"
+		if len(args) == 2 {
+			if style, err = stringArg("formatDate", args[1]); err != nil {
+				return nil, err
+			}
+		}
+		text, err := e.currentLocale().FormatDate(t, style)
+		if err != nil {
+			return nil, fmt.Errorf("formatDate: %w", err)
+		}
+		return text, nil
+	})
+}
+
+// timeArg converts a time.Time, a Unix timestamp in seconds, or an RFC 3339 string to a time.
+func timeArg(name string, v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s: %w", name, err)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("%s: expected a time, got %v", name, v)
+	}
+}
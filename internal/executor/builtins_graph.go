@@ -0,0 +1,325 @@
+package executor
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Graph is a directed graph with weighted edges. Nodes are identified by strings
+// and are kept in insertion order so that traversals are deterministic. A Graph
+// is safe for use by concurrent parallel branches.
+type Graph struct {
+	mu    sync.RWMutex
+	nodes []string
+	edges map[string][]graphEdge
+}
+
+type graphEdge struct {
+	to     string
+	weight float64
+}
+
+// NewGraph creates an empty graph.
+func NewGraph() *Graph {
+	return &Graph{edges: make(map[string][]graphEdge)}
+}
+
+// String summarizes the graph's size.
+func (g *Graph) String() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	edges := 0
+	for _, out := range g.edges {
+		edges += len(out)
+	}
+	return fmt.Sprintf("graph(%d nodes, %d edges)", len(g.nodes), edges)
+}
+
+// AddNode adds id to the graph if it is not already present.
+func (g *Graph) AddNode(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.addNode(id)
+}
+
+func (g *Graph) addNode(id string) {
+	if _, ok := g.edges[id]; !ok {
+		g.nodes = append(g.nodes, id)
+		g.edges[id] = nil
+	}
+}
+
+// AddEdge adds a directed edge, creating either endpoint if needed.
+func (g *Graph) AddEdge(from, to string, weight float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.addNode(from)
+	g.addNode(to)
+	g.edges[from] = append(g.edges[from], graphEdge{to: to, weight: weight})
+}
+
+// Neighbors returns the targets of id's outgoing edges in insertion order.
+func (g *Graph) Neighbors(id string) ([]string, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	edges, ok := g.edges[id]
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, len(edges))
+	for i, edge := range edges {
+		out[i] = edge.to
+	}
+	return out, true
+}
+
+// ShortestPath returns the lowest-weight path from one node to another using
+// Dijkstra's algorithm, or nil if to is unreachable. Edge weights must not be negative.
+func (g *Graph) ShortestPath(from, to string) ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if _, ok := g.edges[from]; !ok {
+		return nil, fmt.Errorf("unknown node: %s", from)
+	}
+	if _, ok := g.edges[to]; !ok {
+		return nil, fmt.Errorf("unknown node: %s", to)
+	}
+
+	dist := map[string]float64{from: 0}
+	prev := map[string]string{}
+	queue := &pathQueue{{node: from}}
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(pathItem)
+		if item.dist > dist[item.node] {
+			continue
+		}
+		if item.node == to {
+			break
+		}
+		for _, edge := range g.edges[item.node] {
+			if edge.weight < 0 {
+				return nil, fmt.Errorf("negative edge weight from %s to %s", item.node, edge.to)
+			}
+			d := item.dist + edge.weight
+			if old, seen := dist[edge.to]; !seen || d < old {
+				dist[edge.to] = d
+				prev[edge.to] = item.node
+				heap.Push(queue, pathItem{node: edge.to, dist: d})
+			}
+		}
+	}
+
+	if _, ok := dist[to]; !ok {
+		return nil, nil
+	}
+	path := []string{to}
+	for node := to; node != from; {
+		node = prev[node]
+		path = append([]string{node}, path...)
+	}
+	return path, nil
+}
+
+// TopologicalSort orders the nodes so every edge points forward, preferring
+// insertion order among independent nodes. It fails if the graph has a cycle.
+func (g *Graph) TopologicalSort() ([]string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	indegree := make(map[string]int, len(g.nodes))
+	for _, node := range g.nodes {
+		for _, edge := range g.edges[node] {
+			indegree[edge.to]++
+		}
+	}
+
+	var ready, order []string
+	for _, node := range g.nodes {
+		if indegree[node] == 0 {
+			ready = append(ready, node)
+		}
+	}
+	for len(ready) > 0 {
+		node := ready[0]
+		ready = ready[1:]
+		order = append(order, node)
+		for _, edge := range g.edges[node] {
+			indegree[edge.to]--
+			if indegree[edge.to] == 0 {
+				ready = append(ready, edge.to)
+			}
+		}
+	}
+	if len(order) != len(g.nodes) {
+		return nil, errors.New("graph contains a cycle")
+	}
+	return order, nil
+}
+
+type pathItem struct {
+	node string
+	dist float64
+}
+
+// pathQueue is a min-heap of tentative distances for ShortestPath.
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// RegisterGraphBuiltins registers builtins for building and analyzing graphs.
+// Node identifiers may be strings or numbers.
+//
+//	graph()                         a new empty graph
+//	addNode(g, id)                  add a node; returns g
+//	addEdge(g, from, to[, weight])  add a directed edge (default weight 1); returns g
+//	neighbors(g, id)                targets of id's outgoing edges
+//	shortestPath(g, from, to)       lowest-weight path as an array of ids, or nil
+//	topoSort(g)                     nodes in dependency order; fails on cycles
+func (e *Executor) RegisterGraphBuiltins() {
+	e.RegisterBuiltin("graph", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("graph", args, 0); err != nil {
+			return nil, err
+		}
+		return NewGraph(), nil
+	})
+	e.RegisterBuiltin("addNode", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("addNode", args, 2); err != nil {
+			return nil, err
+		}
+		g, err := graphArg("addNode", args[0])
+		if err != nil {
+			return nil, err
+		}
+		id, err := graphNodeID("addNode", args[1])
+		if err != nil {
+			return nil, err
+		}
+		g.AddNode(id)
+		return g, nil
+	})
+	e.RegisterBuiltin("addEdge", func(args []interface{}) (interface{}, error) {
+		if len(args) != 3 && len(args) != 4 {
+			return nil, fmt.Errorf("addEdge expects 3 or 4 arguments, but got %d", len(args))
+		}
+		g, err := graphArg("addEdge", args[0])
+		if err != nil {
+			return nil, err
+		}
+		from, err := graphNodeID("addEdge", args[1])
+		if err != nil {
+			return nil, err
+		}
+		to, err := graphNodeID("addEdge", args[2])
+		if err != nil {
+			return nil, err
+		}
+		weight := 1.0
+		if len(args) == 4 {
+			w, ok := toFloat(args[3])
+			if !ok || math.IsNaN(w) {
+				return nil, fmt.Errorf("addEdge: weight must be a number, got %v", args[3])
+			}
+			weight = w
+		}
+		g.AddEdge(from, to, weight)
+		return g, nil
+	})
+	e.RegisterBuiltin("neighbors", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("neighbors", args, 2); err != nil {
+			return nil, err
+		}
+		g, err := graphArg("neighbors", args[0])
+		if err != nil {
+			return nil, err
+		}
+		id, err := graphNodeID("neighbors", args[1])
+		if err != nil {
+			return nil, err
+		}
+		out, ok := g.Neighbors(id)
+		if !ok {
+			return nil, fmt.Errorf("neighbors: unknown node: %s", id)
+		}
+		return stringList(out), nil
+	})
+	e.RegisterBuiltin("shortestPath", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("shortestPath", args, 3); err != nil {
+			return nil, err
+		}
+		g, err := graphArg("shortestPath", args[0])
+		if err != nil {
+			return nil, err
+		}
+		from, err := graphNodeID("shortestPath", args[1])
+		if err != nil {
+			return nil, err
+		}
+		to, err := graphNodeID("shortestPath", args[2])
+		if err != nil {
+			return nil, err
+		}
+		path, err := g.ShortestPath(from, to)
+		if err != nil {
+			return nil, fmt.Errorf("shortestPath: %w", err)
+		}
+		if path == nil {
+			return nil, nil
+		}
+		return stringList(path), nil
+	})
+	e.RegisterBuiltin("topoSort", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("topoSort", args, 1); err != nil {
+			return nil, err
+		}
+		g, err := graphArg("topoSort", args[0])
+		if err != nil {
+			return nil, err
+		}
+		order, err := g.TopologicalSort()
+		if err != nil {
+			return nil, fmt.Errorf("topoSort: %w", err)
+		}
+		return stringList(order), nil
+	})
+}
+
+func graphArg(name string, v interface{}) (*Graph, error) {
+	g, ok := v.(*Graph)
+	if !ok {
+		return nil, fmt.Errorf("%s: first argument must be a graph", name)
+	}
+	return g, nil
+}
+
+// graphNodeID normalizes a string or numeric node identifier.
+func graphNodeID(name string, v interface{}) (string, error) {
+	switch id := v.(type) {
+	case string:
+		return id, nil
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%s: node id must be a string or number, got %v", name, v)
+	}
+}
+
+// stringList converts a []string to an array value.
+func stringList(items []string) []interface{} {
+	out := make([]interface{}, len(items))
+	for i, item := range items {
+		out[i] = item
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GRPCCall describes one invocation of a gRPC method by a silk program.
+type GRPCCall struct {
+	Method   string                 // Full method name, e.g. "/orders.v1.Orders/Get".
+	Request  map[string]interface{} // Request message as a map of field names to values.
+	Metadata map[string]string      // Outgoing metadata to attach to the call.
+}
+
+// GRPCInvoker performs a gRPC call on behalf of silk programs. Hosts typically
+// implement it by converting the request map into the method's request message
+// (for example with protojson), invoking the generated client with the metadata
+// attached, and converting the response message back into a map. The context
+// carries the call's deadline, and is cancelled when the deadline of an
+// enclosing WithTimeout passes.
+type GRPCInvoker func(ctx context.Context, call *GRPCCall) (map[string]interface{}, error)
+
+// RegisterGRPCMethod makes a single gRPC method callable through grpcCall.
+// The builtin is registered on first use of this method:
+//
+//	grpcCall(method, request[, options])
+//
+// The options map accepts "timeout" (milliseconds) and "metadata" (a map of
+// string values).
+func (e *Executor) RegisterGRPCMethod(method string, invoker GRPCInvoker) {
+	if e.grpcMethods == nil {
+		e.grpcMethods = make(map[string]GRPCInvoker)
+		e.RegisterContextBuiltin("grpcCall", e.grpcCall, CapNet)
+	}
+	e.grpcMethods[normalizeGRPCMethod(method)] = invoker
+}
+
+// RegisterGRPCService registers every method of a service at once, keyed by
+// short method name, e.g. RegisterGRPCService("orders.v1.Orders", {"Get": ...}).
+func (e *Executor) RegisterGRPCService(service string, methods map[string]GRPCInvoker) {
+	for name, invoker := range methods {
+		e.RegisterGRPCMethod(service+"/"+name, invoker)
+	}
+}
+
+func (e *Executor) grpcCall(ctx context.Context, args []interface{}) (interface{}, error) {
+	opts, args, err := optionsArg("grpcCall", args, 2)
+	if err != nil {
+		return nil, err
+	}
+	method, err := stringArg("grpcCall", args[0])
+	if err != nil {
+		return nil, err
+	}
+	method = normalizeGRPCMethod(method)
+	invoker, ok := e.grpcMethods[method]
+	if !ok {
+		return nil, fmt.Errorf("grpcCall: unknown method: %s", method)
+	}
+	request, ok := args[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("grpcCall: request must be a map, got %v", args[1])
+	}
+
+	call := &GRPCCall{Method: method, Request: request, Metadata: map[string]string{}}
+	if raw, ok := opts["metadata"]; ok {
+		md, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return nil, fmt.Errorf("grpcCall: metadata must be a map, got %v", raw)
+		}
+		for k, v := range md {
+			s, isString := v.(string)
+			if !isString {
+				return nil, fmt.Errorf("grpcCall: metadata value for %q must be a string, got %v", k, v)
+			}
+			call.Metadata[k] = s
+		}
+	}
+
+	if raw, ok := opts["timeout"]; ok {
+		ms, isNumber := raw.(float64)
+		if !isNumber || ms <= 0 {
+			return nil, fmt.Errorf("grpcCall: timeout must be a positive number of milliseconds, got %v", raw)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ms*float64(time.Millisecond)))
+		defer cancel()
+	}
+
+	response, err := invoker(ctx, call)
+	if err != nil {
+		return nil, fmt.Errorf("grpcCall %s: %w", method, err)
+	}
+	if response == nil {
+		response = map[string]interface{}{}
+	}
+	return response, nil
+}
+
+// normalizeGRPCMethod accepts method names with or without the leading slash
+// and returns the canonical "/package.Service/Method" form.
+func normalizeGRPCMethod(method string) string {
+	return "/" + strings.TrimPrefix(method, "/")
+}
@@ -0,0 +1,300 @@
+package executor
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+)
+
+func init() {
+	gob.Register(&Matrix{})
+}
+
+// Matrix is a dense, row-major matrix of numbers.
+type Matrix struct {
+	Rows int
+	Cols int
+	Data []float64
+}
+
+// NewMatrix creates a zero-filled rows x cols matrix. The builtins create no
+// matrix with more than matrixMaxDim rows or columns.
+func NewMatrix(rows, cols int) *Matrix {
+	return &Matrix{Rows: rows, Cols: cols, Data: make([]float64, rows*cols)}
+}
+
+// At returns the element at row i, column j.
+func (m *Matrix) At(i, j int) float64 {
+	return m.Data[i*m.Cols+j]
+}
+
+// Set stores v at row i, column j.
+func (m *Matrix) Set(i, j int, v float64) {
+	m.Data[i*m.Cols+j] = v
+}
+
+// String formats the matrix one row per line.
+func (m *Matrix) String() string {
+	s := ""
+	for i := 0; i < m.Rows; i++ {
+		if i > 0 {
+			s += "\n"
+		}
+		s += fmt.Sprint(m.Data[i*m.Cols : (i+1)*m.Cols])
+	}
+	return s
+}
+
+var errSingularMatrix = errors.New("matrix is singular")
+
+// RegisterMatrixBuiltins registers builtins for constructing and operating on matrices:
+//
+//	matrix(rows, cols, data)  build a matrix from a row-major array
+//	identity(n)               the n x n identity matrix
+//
+// Neither matrix nor identity accepts a dimension above matrixMaxDim.
+//
+//	matMul(a, b)              matrix product
+//	transpose(m)              transposed copy
+//	inverse(m)                inverse of a square matrix
+//	solve(a, b)               solve a·x = b for a vector or matrix b
+func (e *Executor) RegisterMatrixBuiltins() {
+	e.RegisterBuiltin("matrix", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("matrix", args, 3); err != nil {
+			return nil, err
+		}
+		rows, err := intArg("matrix", args[0])
+		if err != nil {
+			return nil, err
+		}
+		cols, err := intArg("matrix", args[1])
+		if err != nil {
+			return nil, err
+		}
+		data, ok := toFloats(args[2])
+		if !ok {
+			return nil, errors.New("matrix: data must be an array of numbers")
+		}
+		if err := checkMatrixDims("matrix", rows, cols); err != nil {
+			return nil, err
+		}
+		if len(data) != rows*cols {
+			return nil, fmt.Errorf("matrix: %d values cannot fill a %dx%d matrix", len(data), rows, cols)
+		}
+		m := NewMatrix(rows, cols)
+		copy(m.Data, data)
+		return m, nil
+	})
+	e.RegisterBuiltin("identity", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("identity", args, 1); err != nil {
+			return nil, err
+		}
+		n, err := intArg("identity", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkMatrixDims("identity", n, n); err != nil {
+			return nil, err
+		}
+		return identityMatrix(n), nil
+	})
+	e.RegisterBuiltin("matMul", func(args []interface{}) (interface{}, error) {
+		a, b, err := matrixPair("matMul", args)
+		if err != nil {
+			return nil, err
+		}
+		return matMul(a, b)
+	})
+	e.RegisterBuiltin("transpose", func(args []interface{}) (interface{}, error) {
+		m, err := matrixArg("transpose", args)
+		if err != nil {
+			return nil, err
+		}
+		t := NewMatrix(m.Cols, m.Rows)
+		for i := 0; i < m.Rows; i++ {
+			for j := 0; j < m.Cols; j++ {
+				t.Set(j, i, m.At(i, j))
+			}
+		}
+		return t, nil
+	})
+	e.RegisterBuiltin("inverse", func(args []interface{}) (interface{}, error) {
+		m, err := matrixArg("inverse", args)
+		if err != nil {
+			return nil, err
+		}
+		if m.Rows != m.Cols {
+			return nil, fmt.Errorf("inverse: matrix must be square, got %dx%d", m.Rows, m.Cols)
+		}
+		inv, err := solveLinear(m, identityMatrix(m.Rows))
+		if err != nil {
+			return nil, fmt.Errorf("inverse: %w", err)
+		}
+		return inv, nil
+	})
+	e.RegisterBuiltin("solve", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("solve", args, 2); err != nil {
+			return nil, err
+		}
+		a, ok := args[0].(*Matrix)
+		if !ok {
+			return nil, errors.New("solve: first argument must be a matrix")
+		}
+		if a.Rows != a.Cols {
+			return nil, fmt.Errorf("solve: matrix must be square, got %dx%d", a.Rows, a.Cols)
+		}
+		// A vector right-hand side yields a vector solution.
+		if vec, ok := toFloats(args[1]); ok {
+			if len(vec) != a.Rows {
+				return nil, fmt.Errorf("solve: vector has %d elements, expected %d", len(vec), a.Rows)
+			}
+			b := NewMatrix(len(vec), 1)
+			copy(b.Data, vec)
+			x, err := solveLinear(a, b)
+			if err != nil {
+				return nil, fmt.Errorf("solve: %w", err)
+			}
+			return x.Data, nil
+		}
+		b, ok := args[1].(*Matrix)
+		if !ok {
+			return nil, errors.New("solve: second argument must be a matrix or an array of numbers")
+		}
+		if b.Rows != a.Rows {
+			return nil, fmt.Errorf("solve: right-hand side has %d rows, expected %d", b.Rows, a.Rows)
+		}
+		x, err := solveLinear(a, b)
+		if err != nil {
+			return nil, fmt.Errorf("solve: %w", err)
+		}
+		return x, nil
+	})
+}
+
+// matrixMaxDim bounds the rows and columns of the matrices the builtins build,
+// which are allocated up front. It also keeps rows*cols from overflowing.
+const matrixMaxDim = 1 << 12
+
+// checkMatrixDims reports an error unless a rows x cols matrix is within
+// matrixMaxDim in both dimensions.
+func checkMatrixDims(name string, rows, cols int) error {
+	if rows <= 0 || cols <= 0 || rows > matrixMaxDim || cols > matrixMaxDim {
+		return fmt.Errorf("%s: dimensions must be between 1 and %d, got %dx%d", name, matrixMaxDim, rows, cols)
+	}
+	return nil
+}
+
+// matrixArg extracts the single matrix argument of a builtin.
+func matrixArg(name string, args []interface{}) (*Matrix, error) {
+	if err := expectArgs(name, args, 1); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(*Matrix)
+	if !ok {
+		return nil, fmt.Errorf("%s: argument must be a matrix", name)
+	}
+	return m, nil
+}
+
+// matrixPair extracts two matrix arguments.
+func matrixPair(name string, args []interface{}) (*Matrix, *Matrix, error) {
+	if err := expectArgs(name, args, 2); err != nil {
+		return nil, nil, err
+	}
+	a, ok1 := args[0].(*Matrix)
+	b, ok2 := args[1].(*Matrix)
+	if !ok1 || !ok2 {
+		return nil, nil, fmt.Errorf("%s: arguments must be matrices", name)
+	}
+	return a, b, nil
+}
+
+func identityMatrix(n int) *Matrix {
+	m := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m.Set(i, i, 1)
+	}
+	return m
+}
+
+func matMul(a, b *Matrix) (*Matrix, error) {
+	if a.Cols != b.Rows {
+		return nil, fmt.Errorf("matMul: cannot multiply %dx%d by %dx%d", a.Rows, a.Cols, b.Rows, b.Cols)
+	}
+	out := NewMatrix(a.Rows, b.Cols)
+	for i := 0; i < a.Rows; i++ {
+		row := a.Data[i*a.Cols : (i+1)*a.Cols]
+		for k, aik := range row {
+			if aik == 0 {
+				continue
+			}
+			bRow := b.Data[k*b.Cols : (k+1)*b.Cols]
+			outRow := out.Data[i*out.Cols : (i+1)*out.Cols]
+			for j, bkj := range bRow {
+				outRow[j] += aik * bkj
+			}
+		}
+	}
+	return out, nil
+}
+
+// solveLinear solves a·x = b by Gauss-Jordan elimination with partial pivoting.
+// Neither input is modified.
+func solveLinear(a, b *Matrix) (*Matrix, error) {
+	n := a.Rows
+	work := NewMatrix(n, n)
+	copy(work.Data, a.Data)
+	x := NewMatrix(b.Rows, b.Cols)
+	copy(x.Data, b.Data)
+
+	for col := 0; col < n; col++ {
+		// Pick the row with the largest pivot to keep the elimination stable.
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(work.At(r, col)) > math.Abs(work.At(pivot, col)) {
+				pivot = r
+			}
+		}
+		if math.Abs(work.At(pivot, col)) < 1e-12 {
+			return nil, errSingularMatrix
+		}
+		swapRows(work, col, pivot)
+		swapRows(x, col, pivot)
+
+		p := work.At(col, col)
+		for j := 0; j < n; j++ {
+			work.Set(col, j, work.At(col, j)/p)
+		}
+		for j := 0; j < x.Cols; j++ {
+			x.Set(col, j, x.At(col, j)/p)
+		}
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			factor := work.At(r, col)
+			if factor == 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				work.Set(r, j, work.At(r, j)-factor*work.At(col, j))
+			}
+			for j := 0; j < x.Cols; j++ {
+				x.Set(r, j, x.At(r, j)-factor*x.At(col, j))
+			}
+		}
+	}
+	return x, nil
+}
+
+func swapRows(m *Matrix, i, j int) {
+	if i == j {
+		return
+	}
+	ri := m.Data[i*m.Cols : (i+1)*m.Cols]
+	rj := m.Data[j*m.Cols : (j+1)*m.Cols]
+	for k := range ri {
+		ri[k], rj[k] = rj[k], ri[k]
+	}
+}
@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Queue is a message broker adapter registered by the host, for example a thin
+// wrapper around a NATS or Kafka client. Implementations must be safe for
+// concurrent use and may invoke handlers from any goroutine.
+type Queue interface {
+	// Publish sends message to every subscriber of topic.
+	Publish(topic string, message interface{}) error
+	// Subscribe arranges for handler to be called with each message published to
+	// topic. A handler error signals that the message was not processed.
+	Subscribe(topic string, handler func(message interface{}) error) (Subscription, error)
+}
+
+// Subscription is an active Queue subscription.
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// RegisterQueue makes q available to silk programs under name. Programs reach it
+// through the following builtins, which are registered on first use of this method:
+//
+//	publish(queue, topic, message)     send a message
+//	subscribe(queue, topic, handler)   call handler, a function or function name, for every message; returns a subscription
+//	unsubscribe(subscription)          stop a subscription
+func (e *Executor) RegisterQueue(name string, q Queue) {
+	if e.queues == nil {
+		e.queues = make(map[string]Queue)
+		e.RegisterBuiltin("publish", e.publish, CapNet)
+		e.RegisterBuiltin("subscribe", e.subscribe, CapNet)
+		e.RegisterBuiltin("unsubscribe", unsubscribe)
+	}
+	e.queues[name] = q
+}
+
+func (e *Executor) publish(args []interface{}) (interface{}, error) {
+	if err := expectArgs("publish", args, 3); err != nil {
+		return nil, err
+	}
+	q, topic, err := e.queueArgs("publish", args)
+	if err != nil {
+		return nil, err
+	}
+	e.publishing.Add(1)
+	err = q.Publish(topic, args[2])
+	e.publishing.Add(-1)
+	if err != nil {
+		return nil, fmt.Errorf("publish: %w", err)
+	}
+	return nil, nil
+}
+
+func (e *Executor) subscribe(args []interface{}) (interface{}, error) {
+	if err := expectArgs("subscribe", args, 3); err != nil {
+		return nil, err
+	}
+	q, topic, err := e.queueArgs("subscribe", args)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := e.functionArg("subscribe", args[2])
+	if err != nil {
+		return nil, err
+	}
+	sub, err := q.Subscribe(topic, func(message interface{}) error {
+		// A queue may call the handler from a publish in the program, as
+		// MemoryQueue does, on a goroutine that may already hold a slot of the
+		// scheduler; waiting there for another could deadlock. Otherwise the
+		// handler takes a slot, as WebSocket handlers do.
+		if e.publishing.Load() > 0 {
+			_, err := e.callFunction(handler, []interface{}{message})
+			return err
+		}
+		var err error
+		e.scheduler.runCallback(func() {
+			_, err = e.callFunction(handler, []interface{}{message})
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("subscribe: %w", err)
+	}
+	return sub, nil
+}
+
+func unsubscribe(args []interface{}) (interface{}, error) {
+	if err := expectArgs("unsubscribe", args, 1); err != nil {
+		return nil, err
+	}
+	sub, ok := args[0].(Subscription)
+	if !ok {
+		return nil, fmt.Errorf("unsubscribe: expected a subscription, got %v", args[0])
+	}
+	return nil, sub.Unsubscribe()
+}
+
+// queueArgs resolves the queue and topic arguments shared by the queue builtins.
+func (e *Executor) queueArgs(name string, args []interface{}) (Queue, string, error) {
+	queueName, err := stringArg(name, args[0])
+	if err != nil {
+		return nil, "", err
+	}
+	q, ok := e.queues[queueName]
+	if !ok {
+		return nil, "", fmt.Errorf("%s: unknown queue: %s", name, queueName)
+	}
+	topic, err := stringArg(name, args[1])
+	if err != nil {
+		return nil, "", err
+	}
+	return q, topic, nil
+}
+
+// MemoryQueue is an in-process Queue. Publish delivers each message to the
+// current subscribers synchronously, in subscription order, and returns the
+// handlers' errors joined together.
+type MemoryQueue struct {
+	mu     sync.RWMutex
+	nextID int
+	topics map[string][]*memorySubscription
+}
+
+// NewMemoryQueue creates an in-process queue with no subscribers.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{topics: make(map[string][]*memorySubscription)}
+}
+
+type memorySubscription struct {
+	queue   *MemoryQueue
+	topic   string
+	id      int
+	handler func(message interface{}) error
+}
+
+// Publish delivers message to every subscriber of topic.
+func (q *MemoryQueue) Publish(topic string, message interface{}) error {
+	q.mu.RLock()
+	subs := append([]*memorySubscription(nil), q.topics[topic]...)
+	q.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.handler(message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Subscribe registers handler for messages published to topic.
+func (q *MemoryQueue) Subscribe(topic string, handler func(message interface{}) error) (Subscription, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	sub := &memorySubscription{queue: q, topic: topic, id: q.nextID, handler: handler}
+	q.topics[topic] = append(q.topics[topic], sub)
+	return sub, nil
+}
+
+// Unsubscribe removes the subscription from its queue.
+func (s *memorySubscription) Unsubscribe() error {
+	q := s.queue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	subs := q.topics[s.topic]
+	for i, sub := range subs {
+		if sub.id == s.id {
+			q.topics[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// String identifies the subscription when printed by a program.
+func (s *memorySubscription) String() string {
+	return fmt.Sprintf("subscription(%s#%d)", s.topic, s.id)
+}
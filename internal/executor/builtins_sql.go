@@ -0,0 +1,180 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RegisterDatabase makes db available to silk programs under name. Programs
+// reach it through the dbQuery and dbExec builtins, which are registered on
+// first use of this method.
+//
+//	dbQuery(name, sql[, params])  run a query; returns an array of row maps
+//	dbExec(name, sql[, params])   run a statement; returns a map with
+//	                              "rowsAffected" and "lastInsertId"
+//
+// Values are only ever passed to the driver as bound parameters; the SQL text
+// is never assembled from program values. A call is abandoned when the deadline
+// of an enclosing WithTimeout passes.
+func (e *Executor) RegisterDatabase(name string, db *sql.DB) {
+	if e.databases == nil {
+		e.databases = make(map[string]*sql.DB)
+		e.RegisterContextBuiltin("dbQuery", e.dbQuery, CapNet)
+		e.RegisterContextBuiltin("dbExec", e.dbExec, CapNet)
+	}
+	e.databases[name] = db
+}
+
+// SetMaxDBConns limits how many database calls this executor may have in flight
+// at once across all registered databases. Zero or a negative value removes the limit.
+func (e *Executor) SetMaxDBConns(n int) {
+	if n <= 0 {
+		e.dbSem = nil
+		return
+	}
+	e.dbSem = make(chan struct{}, n)
+}
+
+func (e *Executor) dbQuery(ctx context.Context, args []interface{}) (interface{}, error) {
+	db, query, params, err := e.dbArgs("dbQuery", args)
+	if err != nil {
+		return nil, err
+	}
+	release, err := e.acquireDBConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dbQuery: %w", err)
+	}
+	defer release()
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("dbQuery: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("dbQuery: %w", err)
+	}
+	result := []interface{}{}
+	for rows.Next() {
+		cells := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range cells {
+			ptrs[i] = &cells[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("dbQuery: %w", err)
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			row[col] = fromSQLValue(cells[i])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("dbQuery: %w", err)
+	}
+	return result, nil
+}
+
+func (e *Executor) dbExec(ctx context.Context, args []interface{}) (interface{}, error) {
+	db, query, params, err := e.dbArgs("dbExec", args)
+	if err != nil {
+		return nil, err
+	}
+	release, err := e.acquireDBConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("dbExec: %w", err)
+	}
+	defer release()
+
+	res, err := db.ExecContext(ctx, query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("dbExec: %w", err)
+	}
+	out := map[string]interface{}{}
+	// Not every driver supports both results; report the ones that are available.
+	if n, err := res.RowsAffected(); err == nil {
+		out["rowsAffected"] = float64(n)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		out["lastInsertId"] = float64(id)
+	}
+	return out, nil
+}
+
+// dbArgs resolves the database handle, SQL text, and bound parameters of a database builtin.
+func (e *Executor) dbArgs(name string, args []interface{}) (*sql.DB, string, []interface{}, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, "", nil, fmt.Errorf("%s expects 2 or 3 arguments, but got %d", name, len(args))
+	}
+	dbName, err := stringArg(name, args[0])
+	if err != nil {
+		return nil, "", nil, err
+	}
+	db, ok := e.databases[dbName]
+	if !ok {
+		return nil, "", nil, fmt.Errorf("%s: unknown database: %s", name, dbName)
+	}
+	query, err := stringArg(name, args[1])
+	if err != nil {
+		return nil, "", nil, err
+	}
+	var params []interface{}
+	if len(args) == 3 {
+		list, ok := args[2].([]interface{})
+		if !ok {
+			return nil, "", nil, fmt.Errorf("%s: params must be an array, got %v", name, args[2])
+		}
+		params = make([]interface{}, len(list))
+		for i, p := range list {
+			params[i] = toSQLValue(p)
+		}
+	}
+	return db, query, params, nil
+}
+
+// acquireDBConn blocks until a database slot is free or ctx is done, and
+// returns the slot's release function.
+func (e *Executor) acquireDBConn(ctx context.Context) (func(), error) {
+	sem := e.dbSem
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// toSQLValue converts a silk value into a driver parameter. Whole numbers are
+// passed as integers so they compare correctly against integer columns.
+func toSQLValue(v interface{}) interface{} {
+	if f, ok := v.(float64); ok && f == float64(int64(f)) {
+		return int64(f)
+	}
+	return v
+}
+
+// fromSQLValue converts a scanned column value into a silk value.
+func fromSQLValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
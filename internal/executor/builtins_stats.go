@@ -0,0 +1,187 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// RegisterStatsBuiltins registers descriptive-statistics builtins over numeric arrays:
+//
+//	min(a), max(a)         smallest and largest element, keeping its type
+//	mean(a)                arithmetic mean
+//	median(a)              middle value, averaging the two middle values for even lengths
+//	variance(a)            population variance
+//	stddev(a)              population standard deviation
+//	percentile(a, p)       p-th percentile (0-100) with linear interpolation
+//	histogram(a, bins)     map with "edges" (bins+1 boundaries) and "counts" per bin,
+//	                       for finite values and at most histogramMaxBins bins
+func (e *Executor) RegisterStatsBuiltins() {
+	e.RegisterBuiltin("min", func(args []interface{}) (interface{}, error) {
+		a, err := nonEmptyFloatArg("min", args)
+		if err != nil {
+			return nil, err
+		}
+		lo := 0
+		for i, f := range a {
+			if f < a[lo] {
+				lo = i
+			}
+		}
+		return element(args[0], a, lo), nil
+	})
+	e.RegisterBuiltin("max", func(args []interface{}) (interface{}, error) {
+		a, err := nonEmptyFloatArg("max", args)
+		if err != nil {
+			return nil, err
+		}
+		hi := 0
+		for i, f := range a {
+			if f > a[hi] {
+				hi = i
+			}
+		}
+		return element(args[0], a, hi), nil
+	})
+	e.RegisterBuiltin("mean", builtinMean)
+	e.RegisterBuiltin("median", func(args []interface{}) (interface{}, error) {
+		a, err := nonEmptyFloatArg("median", args)
+		if err != nil {
+			return nil, err
+		}
+		return percentile(sortedCopy(a), 50), nil
+	})
+	e.RegisterBuiltin("variance", func(args []interface{}) (interface{}, error) {
+		a, err := nonEmptyFloatArg("variance", args)
+		if err != nil {
+			return nil, err
+		}
+		return variance(a), nil
+	})
+	e.RegisterBuiltin("stddev", func(args []interface{}) (interface{}, error) {
+		a, err := nonEmptyFloatArg("stddev", args)
+		if err != nil {
+			return nil, err
+		}
+		return math.Sqrt(variance(a)), nil
+	})
+	e.RegisterBuiltin("percentile", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("percentile", args, 2); err != nil {
+			return nil, err
+		}
+		a, err := nonEmptyFloatArg("percentile", args[:1])
+		if err != nil {
+			return nil, err
+		}
+		p, ok := toFloat(args[1])
+		if !ok || !(p >= 0 && p <= 100) {
+			return nil, fmt.Errorf("percentile: p must be a number between 0 and 100, got %v", args[1])
+		}
+		return percentile(sortedCopy(a), p), nil
+	})
+	e.RegisterBuiltin("histogram", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("histogram", args, 2); err != nil {
+			return nil, err
+		}
+		a, err := nonEmptyFloatArg("histogram", args[:1])
+		if err != nil {
+			return nil, err
+		}
+		bins, err := intArg("histogram", args[1])
+		if err != nil {
+			return nil, err
+		}
+		if bins <= 0 || bins > histogramMaxBins {
+			return nil, fmt.Errorf("histogram: bin count must be between 1 and %d, got %d", histogramMaxBins, bins)
+		}
+		for _, f := range a {
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				return nil, fmt.Errorf("histogram: values must be finite, got %v", f)
+			}
+		}
+		edges, counts := histogram(a, bins)
+		return map[string]interface{}{"edges": edges, "counts": counts}, nil
+	})
+}
+
+// histogramMaxBins bounds the bins of a histogram, whose edges and counts are
+// allocated up front.
+const histogramMaxBins = 1 << 16
+
+// nonEmptyFloatArg extracts a numeric array argument that has at least one element.
+func nonEmptyFloatArg(name string, args []interface{}) ([]float64, error) {
+	a, err := floatArg(name, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 {
+		return nil, fmt.Errorf("%s: empty array", name)
+	}
+	return a, nil
+}
+
+// element returns element i of the array v, whose elements as numbers are a,
+// as it appears in v rather than widened.
+func element(v interface{}, a []float64, i int) interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list[i]
+	}
+	return a[i]
+}
+
+func sortedCopy(a []float64) []float64 {
+	out := make([]float64, len(a))
+	copy(out, a)
+	sort.Float64s(out)
+	return out
+}
+
+func variance(a []float64) float64 {
+	mean := sumFloats(a) / float64(len(a))
+	total := 0.0
+	for _, f := range a {
+		d := f - mean
+		total += d * d
+	}
+	return total / float64(len(a))
+}
+
+// percentile interpolates linearly between the closest ranks of a sorted array.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// histogram buckets values into equal-width bins spanning the data's range.
+// The last bin is closed so the maximum value is counted.
+func histogram(a []float64, bins int) ([]float64, []float64) {
+	lo, hi := a[0], a[0]
+	for _, f := range a {
+		lo = math.Min(lo, f)
+		hi = math.Max(hi, f)
+	}
+	width := (hi - lo) / float64(bins)
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = lo + float64(i)*width
+	}
+	edges[bins] = hi
+
+	counts := make([]float64, bins)
+	for _, f := range a {
+		i := bins - 1
+		if width > 0 {
+			i = int((f - lo) / width)
+			if i >= bins {
+				i = bins - 1
+			}
+		}
+		counts[i]++
+	}
+	return edges, counts
+}
@@ -0,0 +1,153 @@
+package executor
+
+import "fmt"
+
+// RegisterVectorBuiltins registers numeric array builtins that operate on whole
+// []float64 buffers in Go, avoiding per-element interpreter dispatch:
+//
+//	vecAdd(a, b)  elementwise sum of two equal-length arrays
+//	vecMul(a, b)  elementwise product of two equal-length arrays
+//	dot(a, b)     dot product of two equal-length arrays
+//	sum(a)        sum of all elements
+//	mean(a)       arithmetic mean of all elements
+//
+// Integer elements are widened to numbers, as in arithmetic mixing the two,
+// except that the sum of an array of integers is an integer.
+func (e *Executor) RegisterVectorBuiltins() {
+	e.RegisterBuiltin("vecAdd", func(args []interface{}) (interface{}, error) {
+		a, b, err := floatPair("vecAdd", args)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]float64, len(a))
+		for i := range a {
+			out[i] = a[i] + b[i]
+		}
+		return out, nil
+	})
+	e.RegisterBuiltin("vecMul", func(args []interface{}) (interface{}, error) {
+		a, b, err := floatPair("vecMul", args)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]float64, len(a))
+		for i := range a {
+			out[i] = a[i] * b[i]
+		}
+		return out, nil
+	})
+	e.RegisterBuiltin("dot", func(args []interface{}) (interface{}, error) {
+		a, b, err := floatPair("dot", args)
+		if err != nil {
+			return nil, err
+		}
+		total := 0.0
+		for i := range a {
+			total += a[i] * b[i]
+		}
+		return total, nil
+	})
+	e.RegisterBuiltin("sum", func(args []interface{}) (interface{}, error) {
+		if len(args) == 1 {
+			if total, ok, err := sumInts(args[0]); ok {
+				return total, err
+			}
+		}
+		a, err := floatArg("sum", args)
+		if err != nil {
+			return nil, err
+		}
+		return sumFloats(a), nil
+	})
+	e.RegisterBuiltin("mean", builtinMean)
+}
+
+// builtinMean implements mean(a), shared by the vector and statistics builtins.
+func builtinMean(args []interface{}) (interface{}, error) {
+	a, err := floatArg("mean", args)
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 0 {
+		return nil, fmt.Errorf("mean: empty array")
+	}
+	return sumFloats(a) / float64(len(a)), nil
+}
+
+// toFloats converts an array of numbers and integers to a []float64. Native
+// []float64 buffers are returned as-is without copying.
+func toFloats(v interface{}) ([]float64, bool) {
+	switch v := v.(type) {
+	case []float64:
+		return v, true
+	case []interface{}:
+		out := make([]float64, len(v))
+		for i, elem := range v {
+			f, ok := toFloat(elem)
+			if !ok {
+				return nil, false
+			}
+			out[i] = f
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// floatArg extracts the single numeric array argument of a builtin.
+func floatArg(name string, args []interface{}) ([]float64, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s expects 1 argument, but got %d", name, len(args))
+	}
+	a, ok := toFloats(args[0])
+	if !ok {
+		return nil, fmt.Errorf("%s: argument must be an array of numbers", name)
+	}
+	return a, nil
+}
+
+// floatPair extracts two numeric array arguments of equal length.
+func floatPair(name string, args []interface{}) ([]float64, []float64, error) {
+	if len(args) != 2 {
+		return nil, nil, fmt.Errorf("%s expects 2 arguments, but got %d", name, len(args))
+	}
+	a, ok1 := toFloats(args[0])
+	b, ok2 := toFloats(args[1])
+	if !ok1 || !ok2 {
+		return nil, nil, fmt.Errorf("%s: arguments must be arrays of numbers", name)
+	}
+	if len(a) != len(b) {
+		return nil, nil, fmt.Errorf("%s: array lengths differ (%d and %d)", name, len(a), len(b))
+	}
+	return a, b, nil
+}
+
+// sumInts sums an array of integers, reporting false if v is anything else.
+func sumInts(v interface{}) (int64, bool, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return 0, false, nil
+	}
+	var total int64
+	for _, elem := range list {
+		n, ok := elem.(int64)
+		if !ok {
+			return 0, false, nil
+		}
+		sum, err := intOperation("+", total, n)
+		if err != nil {
+			return 0, true, err
+		}
+		total = sum.(int64)
+	}
+	return total, true, nil
+}
+
+func sumFloats(a []float64) float64 {
+	total := 0.0
+	for _, f := range a {
+		total += f
+	}
+	return total
+}
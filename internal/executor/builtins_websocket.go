@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// webSocket is the value returned by wsOpen.
+type webSocket struct {
+	url  string
+	conn WebSocketConn
+
+	mu        sync.Mutex
+	listening bool          // Whether wsOnMessage has started a read loop.
+	done      chan struct{} // Closed when the read loop exits.
+	handling  bool          // Whether the read loop is running the message handler.
+	err       error         // First read or handler error seen by the read loop.
+}
+
+// String identifies the connection when printed by a program.
+func (ws *webSocket) String() string {
+	return fmt.Sprintf("websocket(%s)", ws.url)
+}
+
+// SetWebSocketDialer replaces the dialer used by wsOpen. Passing nil restores
+// DefaultWebSocketDialer.
+func (e *Executor) SetWebSocketDialer(d WebSocketDialer) {
+	e.wsDialer = d
+}
+
+// RegisterWebSocketBuiltins registers builtins for talking to WebSocket servers:
+//
+//	wsOpen(url)                 connect; returns a connection value
+//	wsSend(ws, message)         send a text message
+//	wsReceive(ws)               block for the next message; nil once the peer has closed
+//	wsOnMessage(ws, handler)    call handler, a function or function name, with every incoming message
+//	wsClose(ws)                 close the connection; returns the first handler error, if any
+//
+// Handlers run on the connection's read loop, one message at a time, and each
+// invocation occupies one of the executor's goroutine slots while it runs. A
+// handler error stops the loop and closes the connection.
+func (e *Executor) RegisterWebSocketBuiltins() {
+	e.RegisterBuiltin("wsOpen", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("wsOpen", args, 1); err != nil {
+			return nil, err
+		}
+		url, err := stringArg("wsOpen", args[0])
+		if err != nil {
+			return nil, err
+		}
+		dialer := e.wsDialer
+		if dialer == nil {
+			dialer = DefaultWebSocketDialer
+		}
+		conn, err := dialer.Dial(url)
+		if err != nil {
+			return nil, fmt.Errorf("wsOpen: %w", err)
+		}
+		return &webSocket{url: url, conn: conn}, nil
+	}, CapNet)
+	e.RegisterBuiltin("wsSend", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("wsSend", args, 2); err != nil {
+			return nil, err
+		}
+		ws, err := webSocketArg("wsSend", args[0])
+		if err != nil {
+			return nil, err
+		}
+		message, err := stringArg("wsSend", args[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := ws.conn.Send(message); err != nil {
+			return nil, fmt.Errorf("wsSend: %w", err)
+		}
+		return nil, nil
+	})
+	e.RegisterBuiltin("wsReceive", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("wsReceive", args, 1); err != nil {
+			return nil, err
+		}
+		ws, err := webSocketArg("wsReceive", args[0])
+		if err != nil {
+			return nil, err
+		}
+		ws.mu.Lock()
+		listening := ws.listening
+		ws.mu.Unlock()
+		if listening {
+			return nil, errors.New("wsReceive: connection already has a message handler")
+		}
+		message, err := ws.conn.Receive()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("wsReceive: %w", err)
+		}
+		return message, nil
+	})
+	e.RegisterBuiltin("wsOnMessage", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("wsOnMessage", args, 2); err != nil {
+			return nil, err
+		}
+		ws, err := webSocketArg("wsOnMessage", args[0])
+		if err != nil {
+			return nil, err
+		}
+		handler, err := e.functionArg("wsOnMessage", args[1])
+		if err != nil {
+			return nil, err
+		}
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		if ws.listening {
+			return nil, errors.New("wsOnMessage: connection already has a message handler")
+		}
+		ws.listening = true
+		ws.done = make(chan struct{})
+		go e.webSocketLoop(ws, handler)
+		return nil, nil
+	})
+	e.RegisterBuiltin("wsClose", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("wsClose", args, 1); err != nil {
+			return nil, err
+		}
+		ws, err := webSocketArg("wsClose", args[0])
+		if err != nil {
+			return nil, err
+		}
+		closeErr := ws.conn.Close()
+		// The read loop exits once it sees the connection closed. While the
+		// handler runs, wsClose may have been called from it, and waiting
+		// would deadlock the loop, so it is not waited for.
+		ws.mu.Lock()
+		done := ws.done
+		if ws.handling {
+			done = nil
+		}
+		ws.mu.Unlock()
+		if done != nil {
+			<-done
+		}
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		if ws.err != nil {
+			return nil, fmt.Errorf("wsClose: %w", ws.err)
+		}
+		if closeErr != nil && !errors.Is(closeErr, net.ErrClosed) {
+			return nil, fmt.Errorf("wsClose: %w", closeErr)
+		}
+		return nil, nil
+	})
+}
+
+// webSocketLoop delivers incoming messages to handler until the connection closes.
+func (e *Executor) webSocketLoop(ws *webSocket, handler *Function) {
+	defer close(ws.done)
+	for {
+		message, err := ws.conn.Receive()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				ws.setErr(err)
+			}
+			return
+		}
+		var handlerErr error
+		ws.setHandling(true)
+		e.scheduler.runCallback(func() {
+			_, handlerErr = e.callFunction(handler, []interface{}{message})
+		})
+		ws.setHandling(false)
+		if handlerErr != nil {
+			ws.setErr(handlerErr)
+			ws.conn.Close()
+			return
+		}
+	}
+}
+
+func (ws *webSocket) setHandling(handling bool) {
+	ws.mu.Lock()
+	ws.handling = handling
+	ws.mu.Unlock()
+}
+
+func (ws *webSocket) setErr(err error) {
+	ws.mu.Lock()
+	if ws.err == nil {
+		ws.err = err
+	}
+	ws.mu.Unlock()
+}
+
+func webSocketArg(name string, v interface{}) (*webSocket, error) {
+	ws, ok := v.(*webSocket)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a websocket, got %v", name, v)
+	}
+	return ws, nil
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"silk/internal/models"
+)
+
+// Cache stores the results of Cached nodes. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, if present and not expired.
+	Get(key string) (interface{}, bool)
+	// Set stores value under key. A zero ttl means the entry does not expire.
+	Set(key string, value interface{}, ttl time.Duration)
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// MemoryCache is an in-process Cache with per-entry expiry.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewMemoryCache creates an empty in-memory cache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]cacheEntry)}
+}
+
+// Get returns the value stored under key, evicting it if it has expired.
+func (c *MemoryCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Set stores value under key for the given ttl.
+func (c *MemoryCache) Set(key string, value interface{}, ttl time.Duration) {
+	entry := cacheEntry{value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// SetCache replaces the backend used by Cached nodes.
+func (e *Executor) SetCache(cache Cache) {
+	e.cache = cache
+}
+
+// handleCached returns the cached result for the node's key, executing the body on a miss.
+// Keys are scoped to the node and include the type of the key value, so that
+// two Cached nodes, or the keys 1 and "1", do not share results.
+func (e *Executor) handleCached(n *models.Cached, env *Environment) (interface{}, error) {
+	keyVal, err := e.eval(n.Key, env)
+	if err != nil {
+		return nil, err
+	}
+	key := e.cacheScope(n) + ":" + typeName(keyVal) + ":" + fmt.Sprint(keyVal)
+	if val, ok := e.cache.Get(key); ok {
+		return val, nil
+	}
+
+	// The body declares in env, but is set apart from it so that a return in
+	// it is not taken for a tail call.
+	scope := newEnvironment(env)
+	scope.transparent = true
+	scope.boundary = true
+	var result interface{}
+	for _, stmt := range n.Body {
+		if isComment(stmt) {
+			continue
+		}
+		result, err = e.eval(stmt, scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+	e.cache.Set(key, result, n.TTL)
+	return result, nil
+}
+
+// cacheScope returns the prefix of the cache keys of n: a digest of the node,
+// so that the same program loaded again, or run by another process sharing
+// the backend, finds its results. A node that cannot be encoded is told apart
+// by its address instead.
+func (e *Executor) cacheScope(n *models.Cached) string {
+	if scope, ok := e.cacheScopes.Load(n); ok {
+		return scope.(string)
+	}
+	scope := fmt.Sprintf("%p", n)
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(n); err == nil {
+		sum := sha256.Sum256(buf.Bytes())
+		scope = hex.EncodeToString(sum[:8])
+	}
+	e.cacheScopes.Store(n, scope)
+	return scope
+}
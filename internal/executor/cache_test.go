@@ -0,0 +1,31 @@
+package executor
+
+import (
+	"testing"
+
+	"silk/internal/models"
+)
+
+func TestCachedKeys(t *testing.T) {
+	e := NewExecutor()
+	cached := func(key, body models.Node) models.Node {
+		return &models.Cached{Key: key, Body: []models.Node{body}}
+	}
+	first := cached(num(1), str("first"))
+	val, err := e.Execute(program(&models.ArrayLiteral{Elements: []models.Node{
+		first,
+		cached(num(1), str("second")),  // Another node with the same key.
+		cached(str("1"), str("third")), // A key of another type with the same text.
+		first,                          // A hit.
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := val.([]interface{})
+	want := []interface{}{"first", "second", "third", "first"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("node %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package executor
+
+import "silk/internal/utils"
+
+// Capability names a class of side effect a builtin may perform.
+type Capability string
+
+const (
+	CapNet  Capability = "net"  // Network access, including databases, queues, and RPC.
+	CapFS   Capability = "fs"   // Reading or writing the local filesystem.
+	CapExec Capability = "exec" // Starting processes.
+	CapEnv  Capability = "env"  // Reading the process environment.
+	CapAll  Capability = "*"    // Matches every capability in a Policy.
+)
+
+// Policy restricts which capabilities builtins may use. A capability is granted
+// when Allow lists it (or CapAll) and Deny does not; Deny takes precedence.
+// Builtins that declare no capabilities are always permitted.
+type Policy struct {
+	Allow []Capability
+	Deny  []Capability
+}
+
+// Permits reports whether the policy grants c.
+func (p *Policy) Permits(c Capability) bool {
+	return !hasCapability(p.Deny, c) && hasCapability(p.Allow, c)
+}
+
+func hasCapability(caps []Capability, c Capability) bool {
+	for _, candidate := range caps {
+		if candidate == c || candidate == CapAll {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPolicy restricts the builtins that programs run by this executor may call.
+// A nil policy, the default, permits every builtin.
+func (e *Executor) SetPolicy(p *Policy) {
+	e.policy = p
+}
+
+// authorize checks the capabilities declared by a builtin against the policy.
+func (e *Executor) authorize(name string) error {
+	if e.policy == nil {
+		return nil
+	}
+	for _, c := range e.capabilities[name] {
+		if !e.policy.Permits(c) {
+			return &utils.PermissionError{Builtin: name, Capability: string(c)}
+		}
+	}
+	return nil
+}
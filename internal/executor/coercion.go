@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CoercionMode controls whether operators convert between numbers and strings.
+type CoercionMode int
+
+const (
+	// CoercionStrict performs no conversions: arithmetic requires numbers and
+	// ordering comparisons require two numbers or two strings. It is the default.
+	CoercionStrict CoercionMode = iota
+
+	// CoercionLoose converts between numbers and strings:
+	//   - "+" with a string operand concatenates, writing the other operand as text;
+	//   - other arithmetic and bitwise operators convert numeric strings, such as
+	//     "42" or " 1.5 ", to numbers;
+	//   - comparing a number with a numeric string compares them as numbers, and
+	//     with any other string compares them as text.
+	CoercionLoose
+)
+
+// SetCoercion selects how operators treat operands of mixed types. Eval always
+// uses CoercionStrict.
+func (e *Executor) SetCoercion(mode CoercionMode) {
+	e.coercion = mode
+}
+
+// binary applies an arithmetic or bitwise operator, either overloaded by the
+// left operand or under the coercion mode. Operator methods are called from
+// site.
+func (e *Executor) binary(site callSite, operator string, left, right interface{}) (interface{}, error) {
+	if val, ok, err := e.overloadedBinary(site, operator, left, right); ok {
+		return val, err
+	}
+	if e.coercion == CoercionLoose {
+		_, leftStr := left.(string)
+		_, rightStr := right.(string)
+		if operator == "+" && (leftStr || rightStr) {
+			return fmt.Sprint(left) + fmt.Sprint(right), nil
+		}
+		left, right = toNumber(left), toNumber(right)
+	}
+	return applyBinary(operator, left, right)
+}
+
+// compare applies a comparison operator, either overloaded by an operand or
+// under the coercion mode. Operator methods are called from site.
+func (e *Executor) compare(site callSite, operator string, left, right interface{}) (interface{}, error) {
+	if val, ok, err := e.overloadedCompare(site, operator, left, right); ok {
+		return val, err
+	}
+	if e.coercion == CoercionLoose {
+		if num, ok := toFloat(left); ok {
+			if str, ok := right.(string); ok {
+				left, right = comparands(num, str)
+			}
+		} else if num, ok := toFloat(right); ok {
+			if str, ok := left.(string); ok {
+				right, left = comparands(num, str)
+			}
+		}
+	}
+	return compareValues(operator, left, right)
+}
+
+// comparands converts a number and a string to a pair of the same type: two
+// numbers if the string is numeric, and two strings otherwise.
+func comparands(num float64, str string) (interface{}, interface{}) {
+	if f, ok := parseNumber(str); ok {
+		return num, f
+	}
+	return fmt.Sprint(num), str
+}
+
+// toNumber converts a numeric string to a number, returning any other value unchanged.
+func toNumber(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if f, ok := parseNumber(s); ok {
+			return f
+		}
+	}
+	return v
+}
+
+// parseNumber parses a string holding a decimal number, ignoring surrounding space.
+func parseNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f, err == nil
+}
@@ -0,0 +1,249 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"silk/internal/models"
+)
+
+// Tuple holds the values returned together by a multi-value return statement.
+type Tuple []interface{}
+
+// String renders the tuple for output.
+func (t Tuple) String() string {
+	parts := make([]string, len(t))
+	for i, v := range t {
+		parts[i] = fmt.Sprint(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// indexValue returns the element at index of a list, or the value stored under
+// the key index of a map. Missing map keys yield nil.
+func indexValue(object, index interface{}) (interface{}, error) {
+	switch list := object.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be a string, got %v", index)
+		}
+		return list[key], nil
+	case []interface{}:
+		i, err := listIndex(index, len(list))
+		if err != nil {
+			return nil, err
+		}
+		return list[i], nil
+	case []float64:
+		i, err := listIndex(index, len(list))
+		if err != nil {
+			return nil, err
+		}
+		return list[i], nil
+	case Tuple:
+		i, err := listIndex(index, len(list))
+		if err != nil {
+			return nil, err
+		}
+		return list[i], nil
+	default:
+		return nil, fmt.Errorf("cannot index %v", object)
+	}
+}
+
+// evalSlice evaluates a slice expression, using eval for its operands.
+func evalSlice(n *models.SliceExpression, eval func(models.Node) (interface{}, error)) (interface{}, error) {
+	object, err := eval(n.Object)
+	if err != nil {
+		return nil, err
+	}
+	var start, end interface{}
+	if n.Start != nil {
+		if start, err = eval(n.Start); err != nil {
+			return nil, err
+		}
+	}
+	if n.End != nil {
+		if end, err = eval(n.End); err != nil {
+			return nil, err
+		}
+	}
+	return sliceValue(object, start, end)
+}
+
+// sliceValue copies the elements of a list or tuple, or the characters of a
+// string, from start up to but not including end. A nil bound defaults to the
+// corresponding end of the sequence.
+func sliceValue(object, start, end interface{}) (interface{}, error) {
+	var n int
+	switch object.(type) {
+	case []interface{}, []float64, Tuple, string:
+		n, _ = length(object)
+	default:
+		return nil, fmt.Errorf("cannot slice %v", object)
+	}
+	lo, err := sliceBound(start, 0, n)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := sliceBound(end, n, n)
+	if err != nil {
+		return nil, err
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("slice start %d is after end %d", lo, hi)
+	}
+
+	switch v := object.(type) {
+	case []interface{}:
+		return append([]interface{}{}, v[lo:hi]...), nil
+	case []float64:
+		return append([]float64{}, v[lo:hi]...), nil
+	case Tuple:
+		return append(Tuple{}, v[lo:hi]...), nil
+	default:
+		return string([]rune(object.(string))[lo:hi]), nil
+	}
+}
+
+// sliceBound resolves a slice bound against a sequence of length n, counting
+// negative bounds back from the end.
+func sliceBound(bound interface{}, def, n int) (int, error) {
+	if bound == nil {
+		return def, nil
+	}
+	b, ok := toInteger(bound)
+	if !ok {
+		return 0, fmt.Errorf("slice index must be an integer, got %v", bound)
+	}
+	if b < 0 {
+		b += int64(n)
+	}
+	if b < 0 || b > int64(n) {
+		return 0, fmt.Errorf("slice index %v out of range for length %d", bound, n)
+	}
+	return int(b), nil
+}
+
+// setIndex replaces the element at index of a list in place, or stores val
+// under the key index of a map. Lists cannot grow this way.
+func setIndex(object, index, val interface{}) error {
+	switch list := object.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return fmt.Errorf("map key must be a string, got %v", index)
+		}
+		list[key] = val
+		return nil
+	case []interface{}:
+		i, err := listIndex(index, len(list))
+		if err != nil {
+			return err
+		}
+		list[i] = val
+		return nil
+	case []float64:
+		i, err := listIndex(index, len(list))
+		if err != nil {
+			return err
+		}
+		f, ok := val.(float64)
+		if !ok {
+			return fmt.Errorf("cannot store %v in a numeric list", val)
+		}
+		list[i] = f
+		return nil
+	default:
+		return fmt.Errorf("cannot assign to an index of %v", object)
+	}
+}
+
+// listIndex validates that index is an integer within a list of length n.
+func listIndex(index interface{}, n int) (int, error) {
+	i, ok := toInteger(index)
+	if !ok {
+		return 0, fmt.Errorf("list index must be an integer, got %v", index)
+	}
+	if i < 0 || i >= int64(n) {
+		return 0, fmt.Errorf("index %v out of range for list of length %d", index, n)
+	}
+	return int(i), nil
+}
+
+// evalElements evaluates call arguments or list elements in order, using eval
+// for each node and expand to split the value of a Spread into its elements.
+func evalElements(nodes []models.Node, eval func(models.Node) (interface{}, error), expand func(interface{}) ([]interface{}, error)) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		spread, ok := node.(*models.Spread)
+		if !ok {
+			val, err := eval(node)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, val)
+			continue
+		}
+		val, err := eval(spread.Value)
+		if err != nil {
+			return nil, err
+		}
+		elems, err := expand(val)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, elems...)
+	}
+	return values, nil
+}
+
+// spreadList returns the elements of a list or tuple being spread.
+func spreadList(v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case Tuple:
+		return v, nil
+	case []interface{}, []float64:
+		return listArg("spread", v)
+	default:
+		return nil, fmt.Errorf("cannot spread %v", v)
+	}
+}
+
+// listArg converts a builtin argument to a list, copying numeric buffers.
+func listArg(name string, v interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, nil
+	case []float64:
+		list := make([]interface{}, len(v))
+		for i, f := range v {
+			list[i] = f
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("%s: expected a list, got %v", name, v)
+	}
+}
+
+// length returns the number of elements in a list or map, or characters in a string.
+func length(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(v), true
+	case []interface{}:
+		return len(v), true
+	case []float64:
+		return len(v), true
+	case Tuple:
+		return len(v), true
+	case *Range:
+		return v.count(), true
+	case string:
+		return utf8.RuneCountInString(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"silk/internal/models"
+	"silk/internal/utils"
+)
+
+// Control-flow signals travel up the Go call stack as errors until the
+// construct they target handles them. If one escapes its construct, its
+// message describes the misuse.
+var (
+	errBreak    = errors.New("break statement outside of a loop")
+	errContinue = errors.New("continue statement outside of a loop")
+)
+
+// returnSignal carries the value of a return statement up to the function call
+// it ends, through any enclosing conditionals and loops.
+type returnSignal struct {
+	value interface{}
+}
+
+func (r *returnSignal) Error() string {
+	return "return statement outside of a function"
+}
+
+// tailCall replaces a return statement of the form "return f(args)" when f is
+// the function already executing, so that callFunction can rerun the body with
+// the new arguments instead of nesting another call.
+type tailCall struct {
+	args []interface{}
+}
+
+func (t *tailCall) Error() string {
+	return "tail call outside of a function"
+}
+
+// handleAssert evaluates an assert statement. A comparison condition has its
+// operands evaluated once and reported in the error if it fails.
+func (e *Executor) handleAssert(n *models.Assert, env *Environment) error {
+	failure := &utils.AssertionError{}
+	var ok bool
+	if cmp, isCmp := n.Condition.(*models.ComparisonExpression); isCmp {
+		left, err := e.eval(cmp.Left, env)
+		if err != nil {
+			return err
+		}
+		right, err := e.eval(cmp.Right, env)
+		if err != nil {
+			return err
+		}
+		result, err := e.compare(env.site(), cmp.Operator, left, right)
+		if err != nil {
+			return err
+		}
+		ok = isTruthy(result)
+		failure.Operator, failure.Left, failure.Right = cmp.Operator, left, right
+	} else {
+		cond, err := e.eval(n.Condition, env)
+		if err != nil {
+			return err
+		}
+		ok = isTruthy(cond)
+	}
+	if ok {
+		return nil
+	}
+
+	if n.Message != nil {
+		msg, err := e.eval(n.Message, env)
+		if err != nil {
+			return err
+		}
+		failure.Message = fmt.Sprint(msg)
+	}
+	return failure
+}
+
+// runLoopBody executes one iteration of a loop body. It reports whether a
+// break statement ended the loop; a continue statement ends only the iteration.
+func (e *Executor) runLoopBody(body []models.Node, env *Environment) (bool, error) {
+	for _, stmt := range body {
+		_, err := e.eval(stmt, env)
+		switch err {
+		case nil:
+		case errBreak:
+			return true, nil
+		case errContinue:
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// loopSignalError keeps break and continue signals from crossing a function
+// boundary, where they would otherwise end a loop in the caller.
+func loopSignalError(err error) error {
+	if err == errBreak || err == errContinue {
+		return errors.New(err.Error())
+	}
+	return err
+}
+
+// branchSignalError keeps every control signal from leaving a branch of a
+// parallel construct. Wrapped in the construct's error, a return would
+// otherwise still be found by errors.As and end the enclosing function with
+// the branch's value, hiding the failures of the other branches.
+func branchSignalError(err error) error {
+	switch err.(type) {
+	case *returnSignal, *tailCall:
+		return errors.New(err.Error())
+	}
+	return loopSignalError(err)
+}
@@ -0,0 +1,351 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, for amounts such as money that float64
+// cannot represent. Addition, subtraction, multiplication, and % are exact.
+// Division that does not terminate is rounded half to even after
+// decimalDivisionPlaces digits. Arithmetic on a decimal and a number or big
+// integer converts the other operand to a decimal first; a number is taken at
+// its shortest decimal representation, so 0.1 becomes exactly 0.1.
+type Decimal struct {
+	coef  *big.Int // Value times 10 to the power of scale.
+	scale int      // Number of digits after the decimal point.
+}
+
+// decimalDivisionPlaces is the number of digits after the decimal point kept
+// by a division whose result does not terminate.
+const decimalDivisionPlaces = 16
+
+// Rounding modes accepted by the round builtin.
+const (
+	RoundHalfEven = "half-even" // To nearest, ties to the even neighbour.
+	RoundHalfUp   = "half-up"   // To nearest, ties away from zero.
+	RoundHalfDown = "half-down" // To nearest, ties toward zero.
+	RoundUp       = "up"        // Away from zero.
+	RoundDown     = "down"      // Toward zero.
+	RoundCeiling  = "ceiling"   // Toward positive infinity.
+	RoundFloor    = "floor"     // Toward negative infinity.
+)
+
+var roundingModes = map[string]bool{
+	RoundHalfEven: true, RoundHalfUp: true, RoundHalfDown: true,
+	RoundUp: true, RoundDown: true, RoundCeiling: true, RoundFloor: true,
+}
+
+// ParseDecimal parses decimal text such as "19.99" or "-0.005".
+func ParseDecimal(s string) (*Decimal, error) {
+	text := strings.TrimSpace(s)
+	sign := ""
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		sign, text = text[:1], text[1:]
+	}
+	digits, frac, point := strings.Cut(text, ".")
+	// Either side of the point may be empty, as in ".5", but not both, and a
+	// point must be followed by digits.
+	if digits == "" && frac == "" || point && frac == "" || strings.ContainsAny(text, "+-") {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	coef, ok := new(big.Int).SetString(sign+"0"+digits+frac, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return &Decimal{coef: coef, scale: len(frac)}, nil
+}
+
+// String renders the decimal with all of its digits after the point, so 2.50
+// stays "2.50".
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.coef).String()
+	sign := ""
+	if d.coef.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= d.scale {
+		digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+	}
+	point := len(digits) - d.scale
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// GobEncode encodes the decimal as its text, so that decimals can be sent to
+// remote workers.
+func (d *Decimal) GobEncode() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// GobDecode decodes a decimal encoded by GobEncode.
+func (d *Decimal) GobDecode(data []byte) error {
+	parsed, err := ParseDecimal(string(data))
+	if err != nil {
+		return err
+	}
+	*d = *parsed
+	return nil
+}
+
+// Float64 returns the number nearest to the decimal.
+func (d *Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// toDecimal converts a decimal, number, integer, or big integer to a decimal.
+func toDecimal(v interface{}) (*Decimal, bool) {
+	switch v := v.(type) {
+	case *Decimal:
+		return v, true
+	case *big.Int:
+		return &Decimal{coef: v, scale: 0}, true
+	case int64:
+		return &Decimal{coef: big.NewInt(v), scale: 0}, true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+		d, err := ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+		return d, err == nil
+	default:
+		return nil, false
+	}
+}
+
+// rescale returns the coefficient of d at a larger scale.
+func (d *Decimal) rescale(scale int) *big.Int {
+	if scale == d.scale {
+		return d.coef
+	}
+	return new(big.Int).Mul(d.coef, pow10(scale-d.scale))
+}
+
+// align returns the coefficients of a and b at their common scale.
+func align(a, b *Decimal) (x, y *big.Int, scale int) {
+	scale = max(a.scale, b.scale)
+	return a.rescale(scale), b.rescale(scale), scale
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Cmp compares d with other, returning -1, 0, or +1.
+func (d *Decimal) Cmp(other *Decimal) int {
+	x, y, _ := align(d, other)
+	return x.Cmp(y)
+}
+
+// Round rounds d to places digits after the decimal point using mode. The
+// result has exactly places digits after the point.
+func (d *Decimal) Round(places int, mode string) (*Decimal, error) {
+	if places < 0 {
+		return nil, fmt.Errorf("decimal places must not be negative, got %d", places)
+	}
+	if !roundingModes[mode] {
+		return nil, fmt.Errorf("unknown rounding mode %q", mode)
+	}
+	if places >= d.scale {
+		return &Decimal{coef: d.rescale(places), scale: places}, nil
+	}
+	divisor := pow10(d.scale - places)
+	q, r := new(big.Int).QuoRem(d.coef, divisor, new(big.Int))
+	if r.Sign() != 0 {
+		up, err := roundsAway(q, r, divisor, d.coef.Sign(), mode)
+		if err != nil {
+			return nil, err
+		}
+		if up {
+			q.Add(q, big.NewInt(int64(d.coef.Sign())))
+		}
+	}
+	return &Decimal{coef: q, scale: places}, nil
+}
+
+// roundsAway reports whether a truncated quotient q, with nonzero remainder r
+// from dividing by divisor, must move one step away from zero under mode.
+// sign is the sign of the exact result.
+func roundsAway(q, r, divisor *big.Int, sign int, mode string) (bool, error) {
+	half := new(big.Int).Abs(r)
+	half.Lsh(half, 1)
+	tie := half.Cmp(divisor)
+	switch mode {
+	case RoundHalfEven:
+		return tie > 0 || tie == 0 && q.Bit(0) == 1, nil
+	case RoundHalfUp:
+		return tie >= 0, nil
+	case RoundHalfDown:
+		return tie > 0, nil
+	case RoundUp:
+		return true, nil
+	case RoundDown:
+		return false, nil
+	case RoundCeiling:
+		return sign > 0, nil
+	case RoundFloor:
+		return sign < 0, nil
+	default:
+		return false, fmt.Errorf("unknown rounding mode %q", mode)
+	}
+}
+
+// quo divides a by b, exactly if the quotient terminates within
+// decimalDivisionPlaces digits and rounded half to even otherwise.
+func quo(a, b *Decimal) (*Decimal, error) {
+	if b.coef.Sign() == 0 {
+		return nil, errors.New("division by zero")
+	}
+	// Scale the dividend so the quotient has decimalDivisionPlaces digits.
+	scale := max(a.scale, b.scale, decimalDivisionPlaces)
+	num := new(big.Int).Mul(a.coef, pow10(scale+b.scale-a.scale))
+	q, r := new(big.Int).QuoRem(num, b.coef, new(big.Int))
+	if r.Sign() != 0 {
+		sign := a.coef.Sign() * b.coef.Sign()
+		up, _ := roundsAway(q, r, new(big.Int).Abs(b.coef), sign, RoundHalfEven)
+		if up {
+			q.Add(q, big.NewInt(int64(sign)))
+		}
+		return &Decimal{coef: q, scale: scale}, nil
+	}
+	// Drop the trailing zeros of an exact quotient, keeping at least the
+	// scale of the operands.
+	d := &Decimal{coef: q, scale: scale}
+	ten, m := big.NewInt(10), new(big.Int)
+	for d.scale > max(a.scale, b.scale) {
+		div, mod := new(big.Int).QuoRem(d.coef, ten, m)
+		if mod.Sign() != 0 {
+			break
+		}
+		d = &Decimal{coef: div, scale: d.scale - 1}
+	}
+	return d, nil
+}
+
+// decimalBinary applies an arithmetic operator to decimals, reporting false if
+// neither operand is one.
+func decimalBinary(operator string, left, right interface{}) (interface{}, bool, error) {
+	_, leftDec := left.(*Decimal)
+	_, rightDec := right.(*Decimal)
+	if !leftDec && !rightDec {
+		return nil, false, nil
+	}
+	l, lok := toDecimal(left)
+	r, rok := toDecimal(right)
+	if !lok || !rok {
+		return nil, true, fmt.Errorf("operator %s is not defined for %s and %s", operator, typeName(left), typeName(right))
+	}
+	switch operator {
+	case "+", "-", "%":
+		x, y, scale := align(l, r)
+		z := new(big.Int)
+		switch operator {
+		case "+":
+			z.Add(x, y)
+		case "-":
+			z.Sub(x, y)
+		default:
+			if y.Sign() == 0 {
+				return nil, true, errors.New("modulo by zero")
+			}
+			z.Rem(x, y)
+		}
+		return &Decimal{coef: z, scale: scale}, true, nil
+	case "*":
+		return &Decimal{coef: new(big.Int).Mul(l.coef, r.coef), scale: l.scale + r.scale}, true, nil
+	case "/":
+		d, err := quo(l, r)
+		return d, true, err
+	case "**":
+		n, ok := r.integer()
+		if !ok || n < 0 {
+			return nil, true, fmt.Errorf("decimal exponent must be a whole number that is not negative, got %v", r)
+		}
+		return &Decimal{coef: new(big.Int).Exp(l.coef, big.NewInt(n), nil), scale: l.scale * int(n)}, true, nil
+	default:
+		return nil, true, fmt.Errorf("operator %s is not defined for decimals", operator)
+	}
+}
+
+// integer returns d as an int64 if it is a whole number that fits.
+func (d *Decimal) integer() (int64, bool) {
+	q, r := new(big.Int).QuoRem(d.coef, pow10(d.scale), new(big.Int))
+	if r.Sign() != 0 || !q.IsInt64() {
+		return 0, false
+	}
+	return q.Int64(), true
+}
+
+// decimalOrder compares a decimal with a decimal, number, or big integer,
+// returning -1, 0, or +1. It reports false if neither operand is a decimal.
+func decimalOrder(left, right interface{}) (int, bool) {
+	_, leftDec := left.(*Decimal)
+	_, rightDec := right.(*Decimal)
+	if !leftDec && !rightDec {
+		return 0, false
+	}
+	l, lok := toDecimal(left)
+	r, rok := toDecimal(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	return l.Cmp(r), true
+}
+
+// registerDecimalBuiltins registers builtins for exact decimals:
+//
+//	decimal(value)               decimal from decimal text, a number, or a big integer
+//	round(value, places[, mode]) value rounded to places digits after the point; mode
+//	                             is "half-even" (the default), "half-up", "half-down",
+//	                             "up", "down", "ceiling", or "floor"
+//
+// round returns a number when given a number, and a decimal otherwise.
+func (e *Executor) registerDecimalBuiltins() {
+	e.RegisterBuiltin("decimal", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("decimal", args, 1); err != nil {
+			return nil, err
+		}
+		if s, ok := args[0].(string); ok {
+			return ParseDecimal(s)
+		}
+		d, ok := toDecimal(args[0])
+		if !ok {
+			return nil, fmt.Errorf("decimal: expected text, a number, or a big integer, got %v", args[0])
+		}
+		return d, nil
+	})
+	e.RegisterBuiltin("round", func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return nil, fmt.Errorf("round expects 2 or 3 arguments, but got %d", len(args))
+		}
+		places, err := intArg("round", args[1])
+		if err != nil {
+			return nil, err
+		}
+		mode := RoundHalfEven
+		if len(args) == 3 {
+			if mode, err = stringArg("round", args[2]); err != nil {
+				return nil, err
+			}
+		}
+		d, ok := toDecimal(args[0])
+		if !ok {
+			return nil, fmt.Errorf("round: expected a decimal or number, got %v", args[0])
+		}
+		rounded, err := d.Round(places, mode)
+		if err != nil {
+			return nil, fmt.Errorf("round: %w", err)
+		}
+		if _, ok := args[0].(float64); ok {
+			return rounded.Float64(), nil
+		}
+		return rounded, nil
+	})
+}
@@ -0,0 +1,81 @@
+package executor
+
+import "testing"
+
+func decimal(t *testing.T, s string) *Decimal {
+	t.Helper()
+	d, err := ParseDecimal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestParseDecimal(t *testing.T) {
+	for s, want := range map[string]string{
+		"19.99": "19.99", "-0.005": "-0.005", "+2.50": "2.50", ".5": "0.5", "-.5": "-0.5", " 7 ": "7", "007": "7",
+	} {
+		if d, err := ParseDecimal(s); err != nil || d.String() != want {
+			t.Errorf("ParseDecimal(%q) = %v, %v; want %s", s, d, err, want)
+		}
+	}
+	for _, s := range []string{"", " ", "-", "+", ".", "-.", "1.", "1.-2", "--1", "+-1", "1e3", "1_000", "abc"} {
+		if d, err := ParseDecimal(s); err == nil {
+			t.Errorf("ParseDecimal(%q) = %v, want an error", s, d)
+		}
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	values := []string{"2.5", "-2.5", "3.5", "2.51", "-2.49", "2.0"}
+	want := map[string][]string{
+		RoundHalfEven: {"2", "-2", "4", "3", "-2", "2"},
+		RoundHalfUp:   {"3", "-3", "4", "3", "-2", "2"},
+		RoundHalfDown: {"2", "-2", "3", "3", "-2", "2"},
+		RoundUp:       {"3", "-3", "4", "3", "-3", "2"},
+		RoundDown:     {"2", "-2", "3", "2", "-2", "2"},
+		RoundCeiling:  {"3", "-2", "4", "3", "-2", "2"},
+		RoundFloor:    {"2", "-3", "3", "2", "-3", "2"},
+	}
+	for mode, results := range want {
+		for i, v := range values {
+			got, err := decimal(t, v).Round(0, mode)
+			if err != nil || got.String() != results[i] {
+				t.Errorf("round(%s, 0, %s) = %v, %v; want %s", v, mode, got, err, results[i])
+			}
+		}
+	}
+	if got, err := decimal(t, "1.005").Round(2, RoundHalfUp); err != nil || got.String() != "1.01" {
+		t.Errorf("round(1.005, 2, half-up) = %v, %v; want 1.01", got, err)
+	}
+	if got, err := decimal(t, "1.5").Round(3, RoundDown); err != nil || got.String() != "1.500" {
+		t.Errorf("round(1.5, 3, down) = %v, %v; want 1.500", got, err)
+	}
+	if _, err := decimal(t, "1.5").Round(0, "sideways"); err == nil {
+		t.Error("an unknown rounding mode was accepted")
+	}
+}
+
+func TestDecimalDivision(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"1", "4", "0.25"},
+		{"10.00", "4", "2.50"},
+		// Quotients that do not terminate are rounded half to even after
+		// decimalDivisionPlaces digits.
+		{"1", "3", "0.3333333333333333"},
+		{"2", "3", "0.6666666666666667"},
+		{"-2", "3", "-0.6666666666666667"},
+		{"1", "6", "0.1666666666666667"},
+		{"1", "20000000000000000", "0.0000000000000000"},
+		{"3", "20000000000000000", "0.0000000000000002"},
+	}
+	for _, tt := range tests {
+		got, err := quo(decimal(t, tt.a), decimal(t, tt.b))
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s / %s = %v, %v; want %s", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	if _, err := quo(decimal(t, "1"), decimal(t, "0.00")); err == nil {
+		t.Error("division by zero succeeded")
+	}
+}
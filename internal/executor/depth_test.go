@@ -0,0 +1,69 @@
+package executor
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"silk/internal/models"
+)
+
+// countdown declares down(n), which nests n calls, not in tail position,
+// before returning n.
+func countdown() models.Node {
+	return function("down", []string{"n"},
+		&models.IfStatement{
+			Condition:  &models.ComparisonExpression{Left: ref("n"), Operator: "<=", Right: num(0)},
+			Consequent: ret(num(0)),
+		},
+		call("sleep", num(0)),
+		ret(binop(num(1), "+", call("down", binop(ref("n"), "-", num(1))))),
+	)
+}
+
+func TestCallDepthPerRun(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxCallDepth(150)
+	if _, err := e.Execute(program(countdown())); err != nil {
+		t.Fatal(err)
+	}
+	// Each run recurses to 100 calls; together they exceed the limit, but
+	// every run is within it.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = e.Execute(program(call("down", num(100))))
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("run %d: %v", i, err)
+		}
+	}
+}
+
+func TestCallDepthExceeded(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxCallDepth(50)
+	_, err := e.Execute(program(countdown(), call("down", num(100))))
+	if err == nil || !strings.Contains(err.Error(), "maximum recursion depth exceeded") {
+		t.Errorf("err = %v, want a recursion depth error", err)
+	}
+}
+
+func TestCallDepthThroughBuiltins(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxCallDepth(50)
+	// deep(n) calls itself through map, which must count toward the limit.
+	deep := function("deep", []string{"n"},
+		ret(call("map", &models.ArrayLiteral{Elements: []models.Node{ref("n")}}, ref("deep"))),
+	)
+	_, err := e.Execute(program(deep, call("deep", num(1))))
+	if err == nil || !strings.Contains(err.Error(), "maximum recursion depth exceeded") {
+		t.Errorf("err = %v, want a recursion depth error", err)
+	}
+}
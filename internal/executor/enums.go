@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Enum is the value bound to the name of an enum declaration. Its members are
+// read with member expressions, as in "Color.Red".
+type Enum struct {
+	Name    string
+	Members []*EnumMember
+}
+
+// EnumMember is one symbolic constant of an enum. Members are equal only to
+// themselves.
+type EnumMember struct {
+	Enum    string // Name of the enum the member belongs to.
+	Name    string
+	Ordinal int // Position of the member in its declaration, from zero.
+}
+
+// newEnum creates the members of an enum declaration, rejecting duplicates.
+func newEnum(n *models.EnumDeclaration) (*Enum, error) {
+	en := &Enum{Name: n.Name, Members: make([]*EnumMember, len(n.Members))}
+	for i, name := range n.Members {
+		if _, ok := en.member(name); ok {
+			return nil, fmt.Errorf("enum %s declares member %s more than once", n.Name, name)
+		}
+		en.Members[i] = &EnumMember{Enum: n.Name, Name: name, Ordinal: i}
+	}
+	return en, nil
+}
+
+// String renders the enum for output.
+func (en *Enum) String() string {
+	return fmt.Sprintf("<enum %s>", en.Name)
+}
+
+// String renders the member qualified by its enum's name.
+func (m *EnumMember) String() string {
+	return m.Enum + "." + m.Name
+}
+
+// member returns the member called name.
+func (en *Enum) member(name string) (*EnumMember, bool) {
+	for _, m := range en.Members {
+		if m != nil && m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// contains reports whether v is a member of the enum.
+func (en *Enum) contains(v interface{}) bool {
+	m, ok := v.(*EnumMember)
+	return ok && m.Enum == en.Name && m.Ordinal < len(en.Members) && *en.Members[m.Ordinal] == *m
+}
+
+// enumAnnotation returns the enum named by a type annotation, if the name is
+// bound to an enum in env.
+func enumAnnotation(t string, env *Environment) *Enum {
+	if t == "" {
+		return nil
+	}
+	val, _ := env.Lookup(t)
+	en, _ := val.(*Enum)
+	return en
+}
+
+// checkEnum verifies that val may be stored in the variable name, restricted to
+// members of en. A nil en means the variable is unrestricted.
+func checkEnum(en *Enum, name string, val interface{}) error {
+	if en == nil || val == nil || en.contains(val) {
+		return nil
+	}
+	return fmt.Errorf("cannot assign %v to %s: not a member of enum %s", val, name, en.Name)
+}
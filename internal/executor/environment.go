@@ -0,0 +1,207 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// Environment represents a single scope of variable bindings. Scopes form a
+// chain: a name not bound in a scope is resolved in its parent.
+//
+// The statements of a parallel block share the scopes enclosing the block, so
+// each scope guards its bindings with a lock. A single read or write of a
+// variable is atomic, but a read followed by a write, as in "x += 1", is not.
+type Environment struct {
+	mu        sync.RWMutex // Guards variables, constants, and enums.
+	variables map[string]interface{}
+	constants map[string]*models.ConstDeclaration // Declarations of the names in this scope that are constant.
+	enums     map[string]*Enum                    // Enum types of the variables in this scope declared with one.
+	parent    *Environment
+	function  *Function       // Function whose call created this scope, if any.
+	generator *generatorState // Generator running the call, if the function is a generator.
+	module    *Module         // Module whose top-level scope this is, if any.
+	cancel    *cancelScope    // Innermost WithTimeout running code in this scope, if any.
+	depth     int             // User function calls in progress in the chain of calls running code in this scope.
+
+	// Whether declarations go to the parent scope, as they do from the scope
+	// of an Execute call to the top-level scope.
+	transparent bool
+
+	// Whether a construct that uses the outcome of the code in this scope, such
+	// as Cached or Retry, runs it here, so that a return in it is not a tail
+	// call.
+	boundary bool
+}
+
+// newEnvironment creates an empty scope nested in parent, which may be nil.
+func newEnvironment(parent *Environment) *Environment {
+	env := &Environment{variables: make(map[string]interface{}), parent: parent}
+	if parent != nil {
+		env.cancel = parent.cancel
+		env.depth = parent.depth
+	}
+	return env
+}
+
+// callSite describes the code making a call: the cancel scope it runs under
+// and the user function calls already in progress in its chain of calls. The
+// zero value starts a new chain, as for a message handler.
+type callSite struct {
+	cancel *cancelScope
+	depth  int
+}
+
+// site returns the call site for calls made by code running in env.
+func (env *Environment) site() callSite {
+	return callSite{cancel: env.cancel, depth: env.depth}
+}
+
+type callSiteKey struct{}
+
+// withCallSite attaches site to the context passed to a builtin, so that the
+// functions the builtin calls back continue the caller's chain of calls.
+func withCallSite(ctx context.Context, site callSite) context.Context {
+	return context.WithValue(ctx, callSiteKey{}, site)
+}
+
+// callSiteOf returns the call site attached to ctx by withCallSite.
+func callSiteOf(ctx context.Context) callSite {
+	site, _ := ctx.Value(callSiteKey{}).(callSite)
+	return site
+}
+
+// Lookup returns the value bound to name in the nearest scope that defines it.
+func (env *Environment) Lookup(name string) (interface{}, bool) {
+	for scope := env; scope != nil; scope = scope.parent {
+		scope.mu.RLock()
+		val, ok := scope.variables[name]
+		scope.mu.RUnlock()
+		if ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// frame returns the scope created by the innermost function call executing in
+// env, or nil at the top level.
+func (env *Environment) frame() *Environment {
+	for scope := env; scope != nil; scope = scope.parent {
+		if scope.function != nil {
+			return scope
+		}
+	}
+	return nil
+}
+
+// declarations returns the scope that declarations in env bind names in.
+func (env *Environment) declarations() *Environment {
+	for env.transparent {
+		env = env.parent
+	}
+	return env
+}
+
+// define binds name in this scope, shadowing any binding in an outer scope.
+func (env *Environment) define(name string, val interface{}) {
+	env = env.declarations()
+	env.mu.Lock()
+	env.variables[name] = val
+	env.mu.Unlock()
+}
+
+// defineConst binds name in this scope and marks it immutable. A name can be
+// declared constant only once per scope.
+func (env *Environment) defineConst(decl *models.ConstDeclaration, val interface{}) error {
+	return env.bindConst(decl, val, false)
+}
+
+// importConst binds a constant exported by a module, as defineConst does,
+// except that importing the same constant again is harmless.
+func (env *Environment) importConst(decl *models.ConstDeclaration, val interface{}) error {
+	return env.bindConst(decl, val, true)
+}
+
+// bindConst implements defineConst and importConst.
+func (env *Environment) bindConst(decl *models.ConstDeclaration, val interface{}, reimport bool) error {
+	name := decl.Variable.Name
+	env = env.declarations()
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if prev, ok := env.constants[name]; ok {
+		if reimport && prev == decl {
+			return nil
+		}
+		return fmt.Errorf("constant %s is already declared%s", name, declaredAt(prev))
+	}
+	if env.constants == nil {
+		env.constants = make(map[string]*models.ConstDeclaration)
+	}
+	env.constants[name] = decl
+	env.variables[name] = val
+	return nil
+}
+
+// declare binds name in this scope, as define does, unless this scope already
+// declares it as a constant.
+func (env *Environment) declare(name string, val interface{}) error {
+	env = env.declarations()
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if decl, ok := env.constants[name]; ok {
+		return fmt.Errorf("constant %s is already declared%s", name, declaredAt(decl))
+	}
+	env.variables[name] = val
+	return nil
+}
+
+// restrict limits the variable name in this scope to members of en.
+func (env *Environment) restrict(name string, en *Enum) {
+	env = env.declarations()
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if env.enums == nil {
+		env.enums = make(map[string]*Enum)
+	}
+	env.enums[name] = en
+}
+
+// assign updates name in the nearest scope that defines it, or defines it in
+// this scope if no enclosing scope does. Constants cannot be assigned.
+func (env *Environment) assign(name string, val interface{}) error {
+	for scope := env; scope != nil; scope = scope.parent {
+		if ok, err := scope.update(name, val); ok {
+			return err
+		}
+	}
+	env.define(name, val)
+	return nil
+}
+
+// update sets name to val if this scope defines it, reporting whether it does.
+func (env *Environment) update(name string, val interface{}) (bool, error) {
+	env.mu.Lock()
+	defer env.mu.Unlock()
+	if _, ok := env.variables[name]; !ok {
+		return false, nil
+	}
+	if decl, ok := env.constants[name]; ok {
+		return true, fmt.Errorf("cannot assign to constant %s%s", name, declaredAt(decl))
+	}
+	if err := checkEnum(env.enums[name], name, val); err != nil {
+		return true, err
+	}
+	env.variables[name] = val
+	return true, nil
+}
+
+// declaredAt describes where a constant was declared, if its position is known.
+func declaredAt(decl *models.ConstDeclaration) string {
+	if !decl.Pos.IsValid() {
+		return ""
+	}
+	return " declared at " + decl.Pos.String()
+}
@@ -0,0 +1,168 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Eval evaluates a single expression against host-supplied variables, without
+// creating an Executor. It supports literals, template strings, variables,
+// indexing, slicing, field access, and arithmetic, comparison, and logical expressions. Statements and function
+// calls are rejected, so an expression can never reach user functions or
+// builtins.
+//
+// Eval is safe for concurrent use as long as vars is not modified while it runs.
+func Eval(expr models.Node, vars map[string]interface{}) (interface{}, error) {
+	switch n := expr.(type) {
+	case *models.Number:
+		return n.Value, nil
+
+	case *models.Duration:
+		return n.Value, nil
+
+	case *models.Integer:
+		return n.Value, nil
+
+	case *models.Regex:
+		return compileRegex(n.Pattern, n.Flags)
+
+	case *models.MatchesExpression:
+		return evalMatches(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		})
+
+	case *models.BigInt:
+		return parseBigInt(n.Value)
+
+	case *models.Decimal:
+		return ParseDecimal(n.Value)
+
+	case *models.String:
+		return n.Value, nil
+
+	case *models.Boolean:
+		return n.Value, nil
+
+	case *models.Null:
+		return nil, nil
+
+	case *models.Variable:
+		val, ok := vars[n.Name]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable: %s", n.Name)
+		}
+		return val, nil
+
+	case *models.ArrayLiteral:
+		list, err := evalElements(n.Elements, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		}, spreadList)
+		if err != nil {
+			return nil, err
+		}
+		return list, nil
+
+	case *models.MapLiteral:
+		m := make(map[string]interface{}, len(n.Entries))
+		for _, entry := range n.Entries {
+			key, err := Eval(entry.Key, vars)
+			if err != nil {
+				return nil, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %v", key)
+			}
+			if m[keyStr], err = Eval(entry.Value, vars); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case *models.IndexExpression:
+		object, err := Eval(n.Object, vars)
+		if err != nil {
+			return nil, err
+		}
+		if object == nil && n.Optional {
+			return nil, nil
+		}
+		index, err := Eval(n.Index, vars)
+		if err != nil {
+			return nil, err
+		}
+		return indexValue(object, index)
+
+	case *models.MemberExpression:
+		object, err := Eval(n.Object, vars)
+		if err != nil {
+			return nil, err
+		}
+		if object == nil && n.Optional {
+			return nil, nil
+		}
+		return memberValue(object, n.Property)
+
+	case *models.SliceExpression:
+		return evalSlice(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		})
+
+	case *models.Range:
+		return evalRange(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		})
+
+	case *models.BinaryExpression:
+		left, err := Eval(n.Left, vars)
+		if err != nil {
+			return nil, err
+		}
+		right, err := Eval(n.Right, vars)
+		if err != nil {
+			return nil, err
+		}
+		return applyBinary(n.Operator, left, right)
+
+	case *models.UnaryExpression:
+		operand, err := Eval(n.Operand, vars)
+		if err != nil {
+			return nil, err
+		}
+		return handleUnary(n.Operator, operand)
+
+	case *models.TemplateString:
+		return evalTemplate(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		})
+
+	case *models.LogicalExpression:
+		return evalLogical(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		})
+
+	case *models.ComparisonExpression:
+		left, err := Eval(n.Left, vars)
+		if err != nil {
+			return nil, err
+		}
+		right, err := Eval(n.Right, vars)
+		if err != nil {
+			return nil, err
+		}
+		return compareValues(n.Operator, left, right)
+
+	case *models.ComparisonChain:
+		return evalChain(n, func(node models.Node) (interface{}, error) {
+			return Eval(node, vars)
+		}, compareValues)
+
+	case nil:
+		return nil, errors.New("eval: missing expression")
+
+	default:
+		return nil, fmt.Errorf("eval: unsupported expression: %T", n)
+	}
+}
@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"silk/internal/models"
+)
+
+func TestConcurrentExecute(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(4)
+	if _, err := e.Execute(program(
+		function("scale", []string{"x", "k"}, ret(binop(ref("x"), "*", ref("k")))),
+		assign("base", num(100)),
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run declares a function and a struct type of its own, calls the
+	// shared function from parallel branches, and assigns a global.
+	const runs = 8
+	var wg sync.WaitGroup
+	errs := make([]error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn, typ, out := fmt.Sprint("f", i), fmt.Sprint("T", i), fmt.Sprint("out", i)
+			_, errs[i] = e.Execute(program(
+				function(fn, []string{"x"}, ret(call("scale", ref("x"), num(int64(i))))),
+				&models.StructDeclaration{Name: typ, Fields: []string{"v"}},
+				assign(out, &models.IndexExpression{Index: num(1), Object: &models.ParallelBlock{Body: []models.Node{
+					call(fn, num(1)),
+					call(fn, ref("base")),
+					&models.StructLiteral{Name: typ, Fields: []models.FieldValue{{Name: "v", Value: num(int64(i))}}},
+				}}}),
+			))
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		val, err := e.EnvValue(fmt.Sprint("out", i))
+		if err != nil || val != int64(100*i) {
+			t.Errorf("out%d = %v, %v; want %d", i, val, err, 100*i)
+		}
+	}
+}
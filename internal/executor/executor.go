@@ -1,54 +1,166 @@
 package executor
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"silk/internal/models"
+	"silk/internal/utils"
 )
 
-// Environment represents a single scope of variable bindings.
-type Environment struct {
-	variables  map[string]interface{}
-	isReusable bool
-}
+// BuiltinFunc is the signature of functions implemented by the host and callable from silk programs.
+type BuiltinFunc func(args []interface{}) (interface{}, error)
+
+// ContextBuiltinFunc is the signature of builtins that take a context. The
+// context is cancelled when the deadline of an enclosing WithTimeout passes or
+// when Stop is called, so that a builtin waiting on I/O can give up.
+type ContextBuiltinFunc func(ctx context.Context, args []interface{}) (interface{}, error)
 
 // Executor is responsible for executing AST nodes and managing environments and functions.
 type Executor struct {
-	envStack      []Environment                                            // Stack of environments to handle variable scoping.
-	functions     map[string]*models.FunctionDeclaration                   // Map of user-defined functions.
-	builtins      map[string]func(args []interface{}) (interface{}, error) // Map of built-in functions.
-	builtinCache  map[string]func(args []interface{}) (interface{}, error) // Cache for frequently used built-in functions.
-	envPool       []Environment                                            // Pool of reusable environments.
-	maxGoroutines int                                                      // Maximum number of concurrent goroutines.
-	sem           chan struct{}                                            // Semaphore to control goroutine concurrency.
+	globals          *Environment                                    // Top-level scope, shared by every Execute call.
+	functions        map[string]*models.FunctionDeclaration          // Map of user-defined functions.
+	structs          map[string]*models.StructDeclaration            // Struct types declared by programs.
+	methods          map[string]map[string]*models.MethodDeclaration // Methods of each struct type, by name.
+	declsMu          sync.RWMutex                                    // Guards functions, structs, and methods.
+	builtins         map[string]BuiltinFunc                          // Map of built-in functions.
+	maxGoroutines    int                                             // Initial limit on concurrent goroutines; see SetMaxGoroutines.
+	scheduler        *scheduler                                      // Work-stealing scheduler for parallel branches.
+	progress         ProgressFunc                                    // Optional hook notified of loop and parallel progress.
+	cache            Cache                                           // Backend for results of Cached nodes.
+	idempotency      IdempotencyStore                                // Record of completed builtin calls made with an idempotency key.
+	inflight         map[string]chan struct{}                        // Keyed builtin calls in progress, closed when each ends; see claimIdempotent.
+	inflightMu       sync.Mutex                                      // Guards inflight.
+	dispatcher       Dispatcher                                      // Optional remote executor for parallel branches.
+	databases        map[string]*sql.DB                              // Database handles registered by the host.
+	dbSem            chan struct{}                                   // Optional limit on in-flight database calls.
+	queues           map[string]Queue                                // Message queues registered by the host.
+	publishing       atomic.Int64                                    // Calls to publish in progress; see subscribe.
+	wsDialer         WebSocketDialer                                 // Dialer for wsOpen; nil uses DefaultWebSocketDialer.
+	grpcMethods      map[string]GRPCInvoker                          // gRPC methods registered by the host, by full method name.
+	metrics          *Metrics                                        // Optional collector for executor statistics.
+	stdout           io.Writer                                       // Destination for program output.
+	stderr           io.Writer                                       // Destination for diagnostics.
+	outputMu         sync.Mutex                                      // Serializes writes to stdout and stderr.
+	locale           *Locale                                         // Locale for the formatting builtins; nil means en-US.
+	capabilities     map[string][]Capability                         // Capabilities declared by each builtin.
+	policy           *Policy                                         // Optional restriction on builtin capabilities.
+	coercion         CoercionMode                                    // Conversions applied to operands of operators.
+	strictAssign     bool                                            // Whether assignment requires a declared variable.
+	sharedContainers bool                                            // Whether element assignment copies lists and maps; see SetSharedContainers.
+	maxCallDepth     int64                                           // Limit on nested user function calls; zero means no limit.
+	generators       sync.Map                                        // Whether each function declaration is a generator.
+	cacheScopes      sync.Map                                        // Prefix of the cache keys of each Cached node; see cacheScope.
+	regexes          sync.Map                                        // Compiled regex of each Regex node; see regexLiteral.
+	loader           ModuleLoader                                    // Source of the programs named by import statements.
+	modules          map[string]*Module                              // Modules imported so far, by path.
+	modulesMu        sync.Mutex                                      // Guards modules.
+	runs             map[*cancelScope]bool                           // Cancel scopes of the Execute calls in progress; guarded by runMu.
+	runMu            sync.Mutex                                      // Guards runs.
+	ctxBuiltins      map[string]ContextBuiltinFunc                   // Builtins that take a context, by name.
+	rateLimits       map[string]*rateLimiter                         // Limits on calls to functions, by name.
+	replay           *replayState                                    // Record or Replay in effect, if any.
+	gates            map[string]*callGate                            // Throttled and debounced functions, by name.
 }
 
 // NewExecutor creates a new Executor with an initial environment.
 func NewExecutor() *Executor {
 	maxGoroutines := runtime.NumCPU() // Set the limit for the number of concurrent goroutines to the number of logical processors.
-	return &Executor{
-		envStack:      []Environment{{variables: make(map[string]interface{}), isReusable: false}},
+	e := &Executor{
+		globals:       newEnvironment(nil),
 		functions:     make(map[string]*models.FunctionDeclaration),
-		builtins:      make(map[string]func(args []interface{}) (interface{}, error)),
-		builtinCache:  make(map[string]func(args []interface{}) (interface{}, error)),
-		envPool:       []Environment{},
+		structs:       make(map[string]*models.StructDeclaration),
+		methods:       make(map[string]map[string]*models.MethodDeclaration),
+		builtins:      make(map[string]BuiltinFunc),
 		maxGoroutines: maxGoroutines,
-		sem:           make(chan struct{}, maxGoroutines),
+		scheduler:     newScheduler(maxGoroutines),
+		cache:         NewMemoryCache(),
+		idempotency:   NewMemoryIdempotencyStore(),
+		inflight:      make(map[string]chan struct{}),
+		stdout:        os.Stdout,
+		stderr:        os.Stderr,
+		maxCallDepth:  DefaultMaxCallDepth,
+		modules:       make(map[string]*Module),
+		runs:          make(map[*cancelScope]bool),
 	}
+	e.registerStandardBuiltins()
+	e.registerCollectionBuiltins()
+	e.registerIteratorBuiltins()
+	e.registerParallelBuiltins()
+	e.registerFutureBuiltins()
+	e.registerLockBuiltins()
+	e.registerTimeBuiltins()
+	e.registerBigIntBuiltins()
+	e.registerDecimalBuiltins()
+	e.registerIntegerBuiltins()
+	e.registerRegexBuiltins()
+	return e
+}
+
+// SetMaxGoroutines changes how many goroutines may run parallel branches at
+// once, counting the goroutine that waits on each parallel construct. It may
+// be called while programs run, for example to shed load: a raised limit
+// takes effect at once, and a lowered one as running branches finish. Values
+// below one count as one. NewExecutor starts with the number of CPUs.
+func (e *Executor) SetMaxGoroutines(n int) {
+	e.scheduler.setMaxGoroutines(n)
 }
 
-// Execute executes a given AST node and returns the result or an error.
+// Execute executes a given AST node in the top-level scope and returns the result or an error.
+//
+// Execute may be called from several goroutines at once. The calls share the
+// top-level scope and the functions and struct types that programs declare,
+// each of which is guarded, but every call evaluates in environments of its
+// own below that scope. Builtins and the executor's settings must be
+// registered before the calls start, except where a setter says otherwise.
 func (e *Executor) Execute(node models.Node) (interface{}, error) {
+	run, end := e.begin()
+	defer end()
+	if e.metrics != nil {
+		e.metrics.programsRunning.Add(1)
+		defer e.metrics.programsRunning.Add(-1)
+	}
+	// The scope of the call declares in the top-level scope, but carries the
+	// cancellation of this call alone.
+	env := newEnvironment(e.globals)
+	env.transparent = true
+	env.cancel = run
+	val, err := e.eval(node, env)
+	if err != nil && e.metrics != nil {
+		e.metrics.observeError(err)
+	}
+	return val, err
+}
+
+// eval executes a node in the scope env.
+func (e *Executor) eval(node models.Node, env *Environment) (interface{}, error) {
+	if env != nil && env.cancel.cancelled() {
+		if env.cancel.interrupted() {
+			return nil, ErrInterrupted
+		}
+		return nil, errCancelled
+	}
 	switch n := node.(type) {
 
 	case *models.Program:
 		// Execute each statement in the program sequentially.
 		var result interface{}
 		for _, stmt := range n.Body {
-			res, err := e.Execute(stmt)
+			if isComment(stmt) {
+				continue
+			}
+			res, err := e.eval(stmt, env)
 			if err != nil {
 				return nil, err
 			}
@@ -60,21 +172,106 @@ func (e *Executor) Execute(node models.Node) (interface{}, error) {
 		// Return the numeric value.
 		return n.Value, nil
 
+	case *models.Duration:
+		return n.Value, nil
+
+	case *models.Integer:
+		return n.Value, nil
+
+	case *models.Regex:
+		return e.regexLiteral(n)
+
+	case *models.MatchesExpression:
+		return evalMatches(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		})
+
+	case *models.BigInt:
+		return parseBigInt(n.Value)
+
+	case *models.Decimal:
+		return ParseDecimal(n.Value)
+
 	case *models.Variable:
-		// Retrieve the value of a variable from the current environment.
-		val, ok := e.currentEnv().variables[n.Name]
-		if !ok {
-			return nil, fmt.Errorf("undefined variable: %s", n.Name)
+		// Resolve the variable in the current scope or an enclosing one, falling
+		// back to a function of that name so functions can be used as values.
+		if val, ok := env.Lookup(n.Name); ok {
+			return val, nil
 		}
-		return val, nil
+		if fn, ok := e.lookupFunction(n.Name); ok {
+			return fn, nil
+		}
+		return nil, fmt.Errorf("undefined variable: %s", n.Name)
 
 	case *models.Assignment:
-		// Evaluate the value and assign it to the variable in the current environment.
-		val, err := e.Execute(n.Value)
+		// Evaluate the value and assign it in the scope that defines the variable.
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if err := e.assignVariable(env, n.Variable.Name, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	case *models.VariableDeclaration:
+		// Declare the variable in the current scope, null unless initialized.
+		var val interface{}
+		if n.Value != nil {
+			var err error
+			if val, err = e.eval(n.Value, env); err != nil {
+				return nil, err
+			}
+		}
+		en := enumAnnotation(n.Type, env)
+		if err := checkEnum(en, n.Variable.Name, val); err != nil {
+			return nil, err
+		}
+		if err := env.declare(n.Variable.Name, val); err != nil {
+			return nil, err
+		}
+		if en != nil {
+			env.restrict(n.Variable.Name, en)
+		}
+		return val, nil
+
+	case *models.ConstDeclaration:
+		// Bind an immutable name in the current scope.
+		val, err := e.eval(n.Value, env)
 		if err != nil {
 			return nil, err
 		}
-		e.currentEnv().variables[n.Variable.Name] = val
+		if err := checkEnum(enumAnnotation(n.Type, env), n.Variable.Name, val); err != nil {
+			return nil, err
+		}
+		if err := env.defineConst(n, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	case *models.MultiAssignment:
+		// Unpack a tuple or list into the variables, one element each.
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		var values []interface{}
+		switch v := val.(type) {
+		case Tuple:
+			values = v
+		case []interface{}:
+			values = v
+		default:
+			return nil, fmt.Errorf("cannot unpack %v into %d variables", val, len(n.Variables))
+		}
+		if len(values) != len(n.Variables) {
+			return nil, fmt.Errorf("cannot unpack %d values into %d variables", len(values), len(n.Variables))
+		}
+		for i, v := range n.Variables {
+			if err := e.assignVariable(env, v.Name, values[i]); err != nil {
+				return nil, err
+			}
+		}
 		return val, nil
 
 	case *models.BinaryExpression:
@@ -84,38 +281,103 @@ func (e *Executor) Execute(node models.Node) (interface{}, error) {
 		}
 
 		// Evaluate both sides of the binary expression and perform the operation.
-		left, err := e.Execute(n.Left)
+		left, err := e.eval(n.Left, env)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(n.Right, env)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.binary(env.site(), n.Operator, left, right)
+
+	case *models.CompoundAssignment:
+		// Combine the variable's current value with the operand and store the result.
+		operator := strings.TrimSuffix(n.Operator, "=")
+		if operator == n.Operator || !e.isValidOperator(operator) {
+			return nil, fmt.Errorf("unknown assignment operator: %s", n.Operator)
+		}
+		current, ok := env.Lookup(n.Variable.Name)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable: %s", n.Variable.Name)
+		}
+		operand, err := e.eval(n.Value, env)
 		if err != nil {
 			return nil, err
 		}
-		right, err := e.Execute(n.Right)
+		val, err := e.binary(env.site(), operator, current, operand)
 		if err != nil {
 			return nil, err
 		}
+		if err := env.assign(n.Variable.Name, val); err != nil {
+			return nil, err
+		}
+		return val, nil
 
-		// Check if both operands are numbers before performing the operation.
-		leftNum, ok1 := left.(float64)
-		rightNum, ok2 := right.(float64)
-		if !ok1 || !ok2 {
-			return nil, errors.New("operands must be numbers")
+	case *models.IncDecStatement:
+		// Step the variable by one in place and return its new value.
+		var delta float64
+		switch n.Operator {
+		case "++":
+			delta = 1
+		case "--":
+			delta = -1
+		default:
+			return nil, fmt.Errorf("unknown increment operator: %s", n.Operator)
+		}
+		current, ok := env.Lookup(n.Variable.Name)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable: %s", n.Variable.Name)
+		}
+		var next interface{}
+		switch num := current.(type) {
+		case float64:
+			next = num + delta
+		case int64:
+			sum, err := intOperation("+", num, int64(delta))
+			if err != nil {
+				return nil, err
+			}
+			next = sum
+		default:
+			return nil, fmt.Errorf("cannot apply %s to non-number %s", n.Operator, n.Variable.Name)
 		}
+		if err := env.assign(n.Variable.Name, next); err != nil {
+			return nil, err
+		}
+		return next, nil
+
+	case *models.UnaryExpression:
+		// Evaluate the operand and apply the prefix operator.
+		operand, err := e.eval(n.Operand, env)
+		if err != nil {
+			return nil, err
+		}
+		return handleUnary(n.Operator, operand)
+
+	case *models.TemplateString:
+		// Evaluate each part and join their text.
+		return evalTemplate(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		})
 
-		return e.handleBinaryOperation(n.Operator, leftNum, rightNum)
+	case *models.LogicalExpression:
+		// Combine conditions, skipping the right operand when the left decides the result.
+		return evalLogical(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		})
 
 	case *models.IfStatement:
 		// Evaluate the condition and execute the appropriate branch.
-		condition, err := e.Execute(n.Condition)
+		condition, err := e.eval(n.Condition, env)
 		if err != nil {
 			return nil, err
 		}
-		condBool, ok := condition.(bool)
-		if !ok {
-			return nil, errors.New("condition must evaluate to a boolean")
-		}
-		if condBool {
-			return e.Execute(n.Consequent)
+		if isTruthy(condition) {
+			return e.eval(n.Consequent, env)
 		} else if n.Alternate != nil {
-			return e.Execute(n.Alternate)
+			return e.eval(n.Alternate, env)
 		}
 		return nil, nil
 
@@ -123,113 +385,404 @@ func (e *Executor) Execute(node models.Node) (interface{}, error) {
 		// Return the string value.
 		return n.Value, nil
 
+	case *models.Boolean:
+		// Return the boolean value.
+		return n.Value, nil
+
+	case *models.Null:
+		// nil is the single value representing "no value".
+		return nil, nil
+
+	case *models.Comment:
+		return nil, nil
+
+	case *models.ArrayLiteral:
+		// Evaluate the elements in order into a new list.
+		list, err := e.evalElements(n.Elements, env)
+		if err != nil {
+			return nil, err
+		}
+		return list, nil
+
+	case *models.Spread:
+		return nil, errors.New("spread is only allowed in call arguments and list literals")
+
+	case *models.MapLiteral:
+		// Evaluate the entries in order into a new map.
+		m := make(map[string]interface{}, len(n.Entries))
+		for _, entry := range n.Entries {
+			key, err := e.eval(entry.Key, env)
+			if err != nil {
+				return nil, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %v", key)
+			}
+			val, err := e.eval(entry.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			m[keyStr] = val
+		}
+		return m, nil
+
+	case *models.IndexExpression:
+		// Evaluate the collection and the index, then look up the element.
+		object, err := e.eval(n.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		if object == nil && n.Optional {
+			return nil, nil
+		}
+		index, err := e.eval(n.Index, env)
+		if err != nil {
+			return nil, err
+		}
+		return indexValue(object, index)
+
+	case *models.Range:
+		// Evaluate the bounds and step; the numbers are produced when iterated.
+		return evalRange(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		})
+
+	case *models.SliceExpression:
+		// Evaluate the collection and whichever bounds are given, then copy the range.
+		return evalSlice(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		})
+
+	case *models.IndexAssignment:
+		// Evaluate the target collection, index, and value, then store the value in place.
+		if e.sharedTarget(n.Object) {
+			index, err := e.eval(n.Index, env)
+			if err != nil {
+				return nil, err
+			}
+			val, err := e.eval(n.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.assignShared(n.Object, pathStep{key: index}, val, env); err != nil {
+				return nil, err
+			}
+			return val, nil
+		}
+		object, err := e.eval(n.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		index, err := e.eval(n.Index, env)
+		if err != nil {
+			return nil, err
+		}
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if err := setIndex(object, index, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	case *models.StructDeclaration:
+		// Register a struct type for later literals.
+		return nil, e.declareStruct(n)
+
+	case *models.ImportStatement:
+		// Load a module and bind its exports in the current scope.
+		return nil, e.handleImport(n, env)
+
+	case *models.EnumDeclaration:
+		// Bind the enum's name to its set of members.
+		en, err := newEnum(n)
+		if err != nil {
+			return nil, err
+		}
+		return nil, env.declare(n.Name, en)
+
+	case *models.MethodDeclaration:
+		// Attach a method to a declared struct type.
+		return nil, e.declareMethod(n)
+
+	case *models.StructLiteral:
+		// Create a struct instance from its field initializers.
+		return e.newStruct(n, env)
+
+	case *models.MemberExpression:
+		// Read a field or bound method of a struct, or a key of a map.
+		object, err := e.eval(n.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		if object == nil && n.Optional {
+			return nil, nil
+		}
+		if method, ok := e.boundMethod(object, n.Property); ok {
+			return method, nil
+		}
+		return memberValue(object, n.Property)
+
+	case *models.MemberAssignment:
+		// Evaluate the target and value, then store the value in place.
+		if e.sharedTarget(n.Object) {
+			val, err := e.eval(n.Value, env)
+			if err != nil {
+				return nil, err
+			}
+			if err := e.assignShared(n.Object, pathStep{key: n.Property, member: true}, val, env); err != nil {
+				return nil, err
+			}
+			return val, nil
+		}
+		object, err := e.eval(n.Object, env)
+		if err != nil {
+			return nil, err
+		}
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if err := setMember(object, n.Property, val); err != nil {
+			return nil, err
+		}
+		return val, nil
+
+	case *models.MatchExpression:
+		// Evaluate the first arm whose pattern and guard match the value.
+		return e.handleMatch(n, env)
+
 	case *models.ComparisonExpression:
 		// Evaluate both sides of the comparison and perform the comparison operation.
-		left, err := e.Execute(n.Left)
+		left, err := e.eval(n.Left, env)
 		if err != nil {
 			return nil, err
 		}
-		right, err := e.Execute(n.Right)
+		right, err := e.eval(n.Right, env)
 		if err != nil {
 			return nil, err
 		}
 
-		// Check if both operands are numbers before performing the comparison.
-		leftNum, ok1 := left.(float64)
-		rightNum, ok2 := right.(float64)
-		if !ok1 || !ok2 {
-			return nil, errors.New("operands must be numbers")
-		}
+		return e.compare(env.site(), n.Operator, left, right)
 
-		return e.handleComparison(n.Operator, leftNum, rightNum)
+	case *models.ComparisonChain:
+		// Compare each operand with the next until a comparison fails.
+		return evalChain(n, func(node models.Node) (interface{}, error) {
+			return e.eval(node, env)
+		}, func(operator string, left, right interface{}) (interface{}, error) {
+			return e.compare(env.site(), operator, left, right)
+		})
 
 	case *models.ParallelBlock:
-		// Execute each statement in parallel using goroutines, with a limit on concurrency.
-		var wg sync.WaitGroup
-		errors := []error{}
+		// Execute each statement in parallel on the scheduler, which limits
+		// concurrency, collecting the value of each in order. Each statement
+		// runs in a scope of its own, so its declarations are not seen by the
+		// others. The first failure cancels the statements still running.
+		cancel := e.newCancelScope(env.cancel, 0)
+		if n.Priority != 0 {
+			cancel.priority = n.Priority
+		}
+		group := e.scheduler.group()
+		group.limit = n.MaxConcurrency
+		group.priority = cancel.priority
+		boundQueue(group, n.QueueSize, n.Overflow)
+		sched := e.beginSchedule(n, len(n.Body))
+		if sched.serial() {
+			group.limit = 1
+		}
+		limiter := newRateLimiter(n.RateLimit)
+		var errs []*utils.BranchError
+		results := make([]interface{}, len(n.Body))
 		var mu sync.Mutex
-		for _, childNode := range n.Body {
-			e.sem <- struct{}{} // Acquire a slot
-			wg.Add(1)
-			go func(node models.Node) {
-				defer wg.Done()
-				defer func() { <-e.sem }() // Release the slot
-				_, err := e.Execute(node)
-				if err != nil {
-					mu.Lock()
-					errors = append(errors, err)
-					mu.Unlock()
+		completed := 0
+		overflowed := false
+		for _, i := range sched.order(len(n.Body)) {
+			i, node := i, n.Body[i]
+			accepted := group.Go(func() {
+				var val interface{}
+				err := e.throttle(limiter, cancel)
+				switch {
+				case err != nil:
+				case e.dispatcher != nil:
+					val, err = e.executeRemote(node, env)
+				default:
+					val, err = protect(node, func() (interface{}, error) {
+						scope := newEnvironment(env)
+						scope.cancel = cancel
+						return e.eval(node, scope)
+					})
 				}
-			}(childNode)
+				// Each goroutine writes only its own element.
+				results[i] = val
+				mu.Lock()
+				if err != nil && !errors.Is(err, errCancelled) {
+					errs = append(errs, &utils.BranchError{Index: i, Err: branchSignalError(err)})
+					cancel.cancel()
+				}
+				completed++
+				e.reportProgress(ProgressParallel, n, completed, len(n.Body), false)
+				mu.Unlock()
+				sched.done(i)
+			})
+			if !accepted && n.Overflow == models.OverflowError {
+				overflowed = true
+				cancel.cancel()
+				break
+			}
 		}
-		wg.Wait()
-		if len(errors) > 0 {
-			return nil, fmt.Errorf("multiple errors occurred: %v", errors)
+		group.Wait()
+		sched.end(len(n.Body))
+		e.reportProgress(ProgressParallel, n, completed, len(n.Body), true)
+		switch {
+		case env.cancel.interrupted():
+			return nil, ErrInterrupted
+		case env.cancel.cancelled():
+			return nil, errCancelled
+		case overflowed:
+			return nil, queueFull(n.QueueSize)
+		case len(errs) > 0:
+			return nil, parallelError(errs)
 		}
-		return nil, nil
+		return results, nil
 
 	case *models.FunctionDeclaration:
-		// Register a user-defined function.
-		e.functions[n.Name] = n
+		// Register a top-level function. A declaration nested in a function body
+		// defines a closure over the enclosing scope instead.
+		if env.declarations() != e.globals {
+			env.define(n.Name, &Function{Name: n.Name, decl: n, env: env})
+			return nil, nil
+		}
+		e.RegisterFunction(n.Name, n)
 		return nil, nil
 
+	case *models.FunctionLiteral:
+		// Create a closure over the current scope.
+		decl := &models.FunctionDeclaration{
+			Parameters: n.Parameters,
+			Defaults:   n.Defaults,
+			ParamTypes: n.ParamTypes,
+			ReturnType: n.ReturnType,
+			Body:       n.Body,
+			Pos:        n.Pos,
+		}
+		return &Function{decl: decl, env: env}, nil
+
 	case *models.FunctionCall:
 		// Handle a function call, either built-in or user-defined.
-		return e.handleFunctionCall(n)
+		return e.handleFunctionCall(n, env)
 
 	case *models.ForLoop:
 		// Handle a for loop, including initialization, condition check, and post iteration.
-		return e.handleForLoop(n)
+		return e.handleForLoop(n, env)
 
 	case *models.WhileLoop:
 		// Handle a while loop, executing while the condition is true.
-		return e.handleWhileLoop(n)
+		return e.handleWhileLoop(n, env)
 
-	default:
-		return nil, fmt.Errorf("unknown node type: %T", n)
-	}
-}
+	case *models.ForEachLoop:
+		// Handle a loop over the elements of a collection.
+		return e.handleForEachLoop(n, env)
 
-// currentEnv returns the current environment from the top of the stack.
-func (e *Executor) currentEnv() *Environment {
-	return &e.envStack[len(e.envStack)-1]
-}
+	case *models.ParallelForLoop:
+		// Run the iterations of a loop concurrently.
+		return e.handleParallelForLoop(n, env)
 
-// pushEnv adds a new environment to the stack, reusing one from the pool if available.
-func (e *Executor) pushEnv() {
-	var newEnv Environment
-	if len(e.envPool) > 0 {
-		newEnv = e.envPool[len(e.envPool)-1]
-		e.envPool = e.envPool[:len(e.envPool)-1]
-		newEnv.variables = make(map[string]interface{}) // Reset the environment variables.
-	} else {
-		newEnv = Environment{variables: make(map[string]interface{}), isReusable: true}
-	}
-	e.envStack = append(e.envStack, newEnv)
-}
+	case *models.ReturnStatement:
+		// Evaluate the returned value and signal the enclosing function call to end.
+		if n.Values != nil {
+			tuple := make(Tuple, len(n.Values))
+			for i, value := range n.Values {
+				val, err := e.eval(value, env)
+				if err != nil {
+					return nil, err
+				}
+				tuple[i] = val
+			}
+			return nil, &returnSignal{value: tuple}
+		}
+		if n.Value == nil {
+			return nil, &returnSignal{}
+		}
+		if call, ok := n.Value.(*models.FunctionCall); ok && e.isSelfCall(call, env) {
+			args, err := e.evalElements(call.Args, env)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &tailCall{args: args}
+		}
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &returnSignal{value: val}
 
-// popEnv removes the top environment from the stack and adds it back to the pool if reusable.
-func (e *Executor) popEnv() {
-	env := e.envStack[len(e.envStack)-1]
-	e.envStack = e.envStack[:len(e.envStack)-1]
-	if env.isReusable {
-		e.envPool = append(e.envPool, env)
-	}
-}
+	case *models.YieldStatement:
+		// Hand a value to the generator's caller and wait to be resumed.
+		frame := env.frame()
+		if frame == nil || frame.generator == nil {
+			return nil, errors.New("yield statement outside of a generator")
+		}
+		val, err := e.eval(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		return nil, frame.generator.yield(val)
+
+	case *models.Assert:
+		// Halt with an assertion error if the condition does not hold.
+		return nil, e.handleAssert(n, env)
+
+	case *models.Break:
+		// Signal the innermost enclosing loop to stop.
+		return nil, errBreak
+
+	case *models.Continue:
+		// Signal the innermost enclosing loop to start its next iteration.
+		return nil, errContinue
 
-// Env returns the environment stack.
-func (e *Executor) Env() []Environment {
-	return e.envStack
+	case *models.Cached:
+		// Reuse a previously computed result for the same key, if still fresh.
+		return e.handleCached(n, env)
+
+	case *models.AsyncCall:
+		return e.handleAsyncCall(n, env)
+
+	case *models.Await:
+		return e.handleAwait(n, env)
+
+	case *models.Spawn:
+		return e.handleSpawn(n, env)
+
+	case *models.WithTimeout:
+		return e.handleWithTimeout(n, env)
+
+	case *models.Retry:
+		return e.handleRetry(n, env)
+
+	case *models.Pipeline:
+		return e.handlePipeline(n, env)
+
+	case *models.TaskGraph:
+		return e.handleTaskGraph(n, env)
+
+	case *models.Race:
+		return e.handleRace(n, env)
+
+	default:
+		return nil, fmt.Errorf("unknown node type: %T", n)
+	}
 }
 
-// CurrentEnv returns the current environment from the top of the stack.
-func (e *Executor) CurrentEnv() Environment {
-	return *e.currentEnv()
+// Env returns the top-level environment, in which Execute runs programs.
+func (e *Executor) Env() *Environment {
+	return e.globals
 }
 
-// EnvValue retrieves the value of a variable from the current environment.
+// EnvValue retrieves the value of a variable from the top-level environment.
 func (e *Executor) EnvValue(name string) (interface{}, error) {
-	val, ok := e.currentEnv().variables[name]
+	val, ok := e.globals.Lookup(name)
 	if !ok {
 		return nil, fmt.Errorf("undefined variable: %s", name)
 	}
@@ -237,17 +790,70 @@ func (e *Executor) EnvValue(name string) (interface{}, error) {
 }
 
 func (e *Executor) RegisterFunction(name string, function *models.FunctionDeclaration) {
+	e.declsMu.Lock()
+	defer e.declsMu.Unlock()
 	if e.functions == nil {
 		e.functions = make(map[string]*models.FunctionDeclaration)
 	}
 	e.functions[name] = function
 }
 
-func (e *Executor) RegisterBuiltin(name string, function func(args []interface{}) (interface{}, error)) {
+// RegisterBuiltin makes a host function callable from silk programs. The name
+// may be qualified by a namespace, as in "math.sqrt". caps lists the
+// capabilities the function needs; calls are refused with a
+// utils.PermissionError when the executor's policy does not grant them all.
+func (e *Executor) RegisterBuiltin(name string, function BuiltinFunc, caps ...Capability) {
 	if e.builtins == nil {
-		e.builtins = make(map[string]func(args []interface{}) (interface{}, error))
+		e.builtins = make(map[string]BuiltinFunc)
 	}
 	e.builtins[name] = function
+	delete(e.ctxBuiltins, name)
+	if len(caps) == 0 {
+		delete(e.capabilities, name)
+		return
+	}
+	if e.capabilities == nil {
+		e.capabilities = make(map[string][]Capability)
+	}
+	e.capabilities[name] = caps
+}
+
+// RegisterContextBuiltin is like RegisterBuiltin for a function that takes a
+// context carrying the cancellation of the code that calls it. Go code calling
+// the function recorded by RegisterBuiltin passes the background context.
+func (e *Executor) RegisterContextBuiltin(name string, function ContextBuiltinFunc, caps ...Capability) {
+	e.RegisterBuiltin(name, func(args []interface{}) (interface{}, error) {
+		return function(context.Background(), args)
+	}, caps...)
+	if e.ctxBuiltins == nil {
+		e.ctxBuiltins = make(map[string]ContextBuiltinFunc)
+	}
+	e.ctxBuiltins[name] = function
+}
+
+// RegisterNamespace registers each of builtins under its name qualified by
+// namespace, so that "sqrt" in the namespace "math" is called as "math.sqrt".
+// Namespaces may themselves be qualified, as in "myteam.orders". caps applies
+// to every function in the namespace. Nothing is registered if the namespace
+// is not a dotted sequence of identifiers, or if any of the qualified names is
+// already taken by a builtin or user-defined function.
+func (e *Executor) RegisterNamespace(namespace string, builtins map[string]BuiltinFunc, caps ...Capability) error {
+	if !validQualifiedName(namespace) {
+		return fmt.Errorf("invalid namespace %q", namespace)
+	}
+	for name := range builtins {
+		qualified := namespace + "." + name
+		if !validQualifiedName(qualified) {
+			return fmt.Errorf("invalid function name %q in namespace %s", name, namespace)
+		}
+		if _, ok := e.lookupFunction(qualified); ok {
+			return fmt.Errorf("function %s is already registered", qualified)
+		}
+	}
+	for name, builtin := range builtins {
+		e.RegisterBuiltin(namespace+"."+name, builtin, caps...)
+	}
+	return nil
 }
 
 func (e *Executor) add(a, b interface{}) (interface{}, error) {
@@ -294,77 +900,221 @@ func (e *Executor) divide(a, b interface{}) (interface{}, error) {
 }
 
 // handleFunctionCall executes a function call, supporting both built-in and user-defined functions.
-func (e *Executor) handleFunctionCall(n *models.FunctionCall) (interface{}, error) {
-	// Check if it's cached in the built-in function cache.
-	if cachedBuiltin, ok := e.builtinCache[n.Name]; ok {
-		args := []interface{}{}
-		for _, argNode := range n.Args {
-			argVal, err := e.Execute(argNode)
-			if err != nil {
-				return nil, err
-			}
-			args = append(args, argVal)
+func (e *Executor) handleFunctionCall(n *models.FunctionCall, env *Environment) (interface{}, error) {
+	// Calls through an expression, such as a returned closure, need the value first.
+	if n.Callee != nil {
+		if name, ok := namespacedCallee(n.Callee, env); ok {
+			return e.callNamed(name, n, env)
 		}
-		return cachedBuiltin(args)
+		callee, err := e.eval(n.Callee, env)
+		if err != nil {
+			return nil, err
+		}
+		// A call through an optional member, as in "a?.f()", yields nil when the member does.
+		if member, ok := n.Callee.(*models.MemberExpression); ok && callee == nil && member.Optional {
+			return nil, nil
+		}
+		fn, ok := callee.(*Function)
+		if !ok {
+			return nil, fmt.Errorf("cannot call non-function value %v", callee)
+		}
+		return e.callValue(n, fn, env)
 	}
 
-	// Check if it's a built-in function.
-	if builtin, ok := e.builtins[n.Name]; ok {
-		// Cache the built-in function for future calls.
-		e.builtinCache[n.Name] = builtin
-		args := []interface{}{}
-		for _, argNode := range n.Args {
-			argVal, err := e.Execute(argNode)
-			if err != nil {
-				return nil, err
-			}
-			args = append(args, argVal)
+	// A variable holding a function value shadows functions of the same name.
+	if val, ok := env.Lookup(n.Name); ok {
+		if fn, ok := val.(*Function); ok {
+			return e.callValue(n, fn, env)
 		}
-		return builtin(args)
+	}
+	return e.callNamed(n.Name, n, env)
+}
+
+// callNamed calls the builtin or top-level user-defined function registered
+// as name, which may be qualified by a namespace.
+func (e *Executor) callNamed(name string, n *models.FunctionCall, env *Environment) (interface{}, error) {
+	// Check if it's a built-in function.
+	if builtin, ok := e.builtins[name]; ok {
+		return e.callBuiltin(name, n, builtin, env)
 	}
 
 	// Handle user-defined function.
-	function, ok := e.functions[n.Name]
+	function, ok := e.function(name)
 	if !ok {
-		return nil, fmt.Errorf("undefined function: %s", n.Name)
+		return nil, fmt.Errorf("undefined function: %s", name)
 	}
+	return e.callValue(n, &Function{Name: name, decl: function, env: e.globals}, env)
+}
 
-	// Check if the number of arguments matches the number of parameters.
-	if len(n.Args) != len(function.Parameters) {
-		return nil, fmt.Errorf("function %s expects %d arguments, but got %d", n.Name, len(function.Parameters), len(n.Args))
+// callValue evaluates the arguments of a call in env and invokes fn with them.
+func (e *Executor) callValue(n *models.FunctionCall, fn *Function, env *Environment) (interface{}, error) {
+	if fn.builtin != nil {
+		return e.callBuiltin(fn.Name, n, fn.builtin, env)
+	}
+	if n.IdempotencyKey != nil {
+		return nil, fmt.Errorf("idempotency keys are only supported on builtin calls, not %s", fn.displayName())
 	}
 
-	// Create a new environment for the function call.
-	e.pushEnv()
-	defer e.popEnv()
-	for i, param := range function.Parameters {
-		argVal, err := e.Execute(n.Args[i])
+	// Evaluate the arguments in the caller's environment.
+	args, err := e.evalElements(n.Args, env)
+	if err != nil {
+		return nil, err
+	}
+	return e.callFunctionIn(env.site(), fn, args)
+}
+
+// callBuiltin evaluates the call's arguments and invokes the built-in function
+// registered as name, skipping the call if its idempotency key has already been
+// recorded, and waiting for any call with the same key that is in progress.
+func (e *Executor) callBuiltin(name string, n *models.FunctionCall, builtin BuiltinFunc, env *Environment) (interface{}, error) {
+	if err := e.authorize(name); err != nil {
+		return nil, err
+	}
+
+	var key string
+	if n.IdempotencyKey != nil {
+		keyVal, err := e.eval(n.IdempotencyKey, env)
 		if err != nil {
 			return nil, err
 		}
-		e.currentEnv().variables[param.Name] = argVal
+		key = idempotencyKey(name, keyVal)
+		result, ok, err := e.claimIdempotent(key, env.cancel)
+		if ok || err != nil {
+			return result, err
+		}
+		defer e.releaseIdempotent(key)
+	}
+
+	args, err := e.evalElements(n.Args, env)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.runBuiltin(env.site(), name, builtin, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.IdempotencyKey != nil {
+		if err := e.idempotency.Record(key, result); err != nil {
+			return nil, fmt.Errorf("recording idempotent call to %s: %w", name, err)
+		}
 	}
+	return result, nil
+}
 
-	// Execute the function body.
+// runBuiltin calls a built-in function for code at site, subject to any
+// throttle or debounce set for it.
+func (e *Executor) runBuiltin(site callSite, name string, builtin BuiltinFunc, args []interface{}) (interface{}, error) {
+	if g := e.gates[name]; g != nil {
+		return g.call(e, name, site, args, func(site callSite, args []interface{}) (interface{}, error) {
+			return e.invokeBuiltin(site, name, builtin, args)
+		})
+	}
+	return e.invokeBuiltin(site, name, builtin, args)
+}
+
+// invokeBuiltin makes a call let through by runBuiltin, marking any error the
+// builtin returns as a builtin failure and recording the call's latency when
+// metrics are enabled.
+func (e *Executor) invokeBuiltin(site callSite, name string, builtin BuiltinFunc, args []interface{}) (interface{}, error) {
+	if err := e.throttle(e.rateLimits[name], site.cancel); err != nil {
+		return nil, err
+	}
+	start := time.Now()
 	var result interface{}
-	// Instead of using retStmt, let's directly check the type and break if necessary
-	for _, stmt := range function.Body {
-		res, err := e.Execute(stmt)
+	var err error
+	recorded := e.recorded(name)
+	switch function, ok := e.ctxBuiltins[name]; {
+	case recorded && e.replay.replaying:
+		result, err = e.replay.replayCall(name, args)
+	case ok:
+		result, err = function(withCallSite(e.context(site.cancel), site), args)
+	default:
+		result, err = builtin(args)
+	}
+	if recorded && !e.replay.replaying {
+		e.replay.recordCall(name, args, result, err)
+	}
+	switch {
+	case err == nil:
+	case site.cancel.interrupted():
+		// The builtin most likely gave up because Stop cancelled its context.
+		result, err = nil, ErrInterrupted
+	default:
+		err = &utils.BuiltinError{Name: name, Err: err}
+	}
+	if e.metrics != nil {
+		e.metrics.observeBuiltin(name, time.Since(start), err)
+	}
+	return result, err
+}
+
+// blockValue runs the statements of body in env and returns the value of the
+// last one.
+func (e *Executor) blockValue(body []models.Node, env *Environment) (interface{}, error) {
+	var result interface{}
+	for _, stmt := range body {
+		if isComment(stmt) {
+			continue
+		}
+		res, err := e.eval(stmt, env)
 		if err != nil {
 			return nil, err
 		}
-		if _, ok := stmt.(*models.ReturnStatement); ok {
-			result = res
-			break
-		}
 		result = res
 	}
-
 	return result, nil
 }
 
+// isComment reports whether node is a comment, which does not count as the
+// last statement of a block when taking the block's value.
+func isComment(node models.Node) bool {
+	_, ok := node.(*models.Comment)
+	return ok
+}
+
+// isTruthy reports whether a value counts as true in a condition. false, nil,
+// zero, and the empty string are false; every other value is true.
+func isTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case int64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// applyBinary performs an arithmetic or bitwise operation on two evaluated operands.
+func applyBinary(operator string, left, right interface{}) (interface{}, error) {
+	if val, ok, err := temporalBinary(operator, left, right); ok {
+		return val, err
+	}
+	if val, ok, err := decimalBinary(operator, left, right); ok {
+		return val, err
+	}
+	if val, ok, err := bigBinary(operator, left, right); ok {
+		return val, err
+	}
+	if val, ok, err := intBinary(operator, left, right); ok {
+		return val, err
+	}
+	leftNum, rightNum, ok := numberOperands(left, right)
+	if !ok {
+		return nil, errors.New("operands must be numbers")
+	}
+	return handleBinaryOperation(operator, leftNum, rightNum)
+}
+
 // handleBinaryOperation performs arithmetic operations on two operands.
-func (e *Executor) handleBinaryOperation(operator string, left, right float64) (interface{}, error) {
+func handleBinaryOperation(operator string, left, right float64) (interface{}, error) {
 	switch operator {
 	case "+":
 		return left + right, nil
@@ -377,93 +1127,438 @@ func (e *Executor) handleBinaryOperation(operator string, left, right float64) (
 			return nil, errors.New("division by zero")
 		}
 		return left / right, nil
+	case "%":
+		if right == 0 {
+			return nil, errors.New("modulo by zero")
+		}
+		return math.Mod(left, right), nil
+	case "**":
+		return math.Pow(left, right), nil
+	case "&", "|", "^", "<<", ">>":
+		return handleBitwise(operator, left, right)
 	default:
 		return nil, fmt.Errorf("unknown operator: %s", operator)
 	}
 }
 
+// handleBitwise performs bitwise operations on two whole-number operands,
+// treating them as 64-bit signed integers.
+func handleBitwise(operator string, left, right float64) (interface{}, error) {
+	a, ok1 := toInt64(left)
+	b, ok2 := toInt64(right)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("operands of %s must be integers", operator)
+	}
+	switch operator {
+	case "&":
+		return float64(a & b), nil
+	case "|":
+		return float64(a | b), nil
+	case "^":
+		return float64(a ^ b), nil
+	}
+	if b < 0 || b > 63 {
+		return nil, fmt.Errorf("shift count out of range: %d", b)
+	}
+	if operator == "<<" {
+		return float64(a << b), nil
+	}
+	return float64(a >> b), nil
+}
+
+// toInt64 converts a whole number within the int64 range to an int64.
+func toInt64(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// handleUnary applies a prefix operator to an evaluated operand.
+func handleUnary(operator string, operand interface{}) (interface{}, error) {
+	switch operator {
+	case "-":
+		if i, ok := operand.(int64); ok {
+			if i == math.MinInt64 {
+				return nil, errIntegerOverflow
+			}
+			return -i, nil
+		}
+		num, ok := operand.(float64)
+		if !ok {
+			return nil, errors.New("operand of unary - must be a number")
+		}
+		return -num, nil
+	case "!":
+		return !isTruthy(operand), nil
+	default:
+		return nil, fmt.Errorf("unknown unary operator: %s", operator)
+	}
+}
+
+// evalLogical evaluates a logical expression, using eval for its operands.
+func evalLogical(n *models.LogicalExpression, eval func(models.Node) (interface{}, error)) (interface{}, error) {
+	if n.Operator == "!" {
+		operand, err := eval(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		return !isTruthy(operand), nil
+	}
+	if n.Operator != "&&" && n.Operator != "||" {
+		return nil, fmt.Errorf("unknown logical operator: %s", n.Operator)
+	}
+
+	left, err := eval(n.Left)
+	if err != nil {
+		return nil, err
+	}
+	if isTruthy(left) == (n.Operator == "||") {
+		return n.Operator == "||", nil
+	}
+	right, err := eval(n.Right)
+	if err != nil {
+		return nil, err
+	}
+	return isTruthy(right), nil
+}
+
+// evalTemplate evaluates a template string, using eval for its parts.
+func evalTemplate(n *models.TemplateString, eval func(models.Node) (interface{}, error)) (interface{}, error) {
+	var sb strings.Builder
+	for _, part := range n.Parts {
+		val, err := eval(part)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprint(&sb, val)
+	}
+	return sb.String(), nil
+}
+
+// evalChain evaluates a comparison chain, using eval for its operands and
+// compare for each comparison.
+func evalChain(n *models.ComparisonChain, eval func(models.Node) (interface{}, error), compare func(string, interface{}, interface{}) (interface{}, error)) (interface{}, error) {
+	if len(n.Operands) < 2 || len(n.Operators) != len(n.Operands)-1 {
+		return nil, fmt.Errorf("comparison chain has %d operands but %d operators", len(n.Operands), len(n.Operators))
+	}
+	left, err := eval(n.Operands[0])
+	if err != nil {
+		return nil, err
+	}
+	for i, operator := range n.Operators {
+		right, err := eval(n.Operands[i+1])
+		if err != nil {
+			return nil, err
+		}
+		result, err := compare(operator, left, right)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(result) {
+			return false, nil
+		}
+		left = right
+	}
+	return true, nil
+}
+
+// compareValues compares two evaluated operands. Equality applies to values of
+// any type, including nil; ordering comparisons require two numbers, two
+// strings, which are ordered lexicographically by byte, two times, or two
+// durations. Numbers, integers, big integers, and decimals may be compared with
+// each other.
+func compareValues(operator string, left, right interface{}) (interface{}, error) {
+	switch operator {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	}
+	if leftStr, ok := left.(string); ok {
+		if rightStr, ok := right.(string); ok {
+			return handleComparison(operator, float64(strings.Compare(leftStr, rightStr)), 0)
+		}
+	}
+	if order, ok := temporalOrder(left, right); ok {
+		return handleComparison(operator, float64(order), 0)
+	}
+	if order, ok := decimalOrder(left, right); ok {
+		return handleComparison(operator, float64(order), 0)
+	}
+	if order, ok := bigOrder(left, right); ok {
+		return handleComparison(operator, float64(order), 0)
+	}
+	if order, ok := intOrder(left, right); ok {
+		return handleComparison(operator, float64(order), 0)
+	}
+	leftNum, ok1 := left.(float64)
+	rightNum, ok2 := right.(float64)
+	if !ok1 || !ok2 {
+		return nil, errors.New("operands must be numbers or strings")
+	}
+	return handleComparison(operator, leftNum, rightNum)
+}
+
+// valuesEqual reports whether two values are equal. Values of different types
+// are never equal, so nil equals only nil, except that numbers, integers, big
+// integers, and decimals with the same value are equal.
+func valuesEqual(a, b interface{}) bool {
+	if order, ok := decimalOrder(a, b); ok {
+		return order == 0
+	}
+	if order, ok := bigOrder(a, b); ok {
+		return order == 0
+	}
+	if order, ok := intOrder(a, b); ok {
+		return order == 0
+	}
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case float64, string, bool, time.Duration:
+		return a == b
+	case time.Time:
+		t, ok := b.(time.Time)
+		return ok && a.Equal(t)
+	case *Function:
+		return a == b
+	case *Regex:
+		r, ok := b.(*Regex)
+		return ok && a.String() == r.String()
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
 // handleComparison performs comparison operations on two operands.
-func (e *Executor) handleComparison(operator string, left, right float64) (interface{}, error) {
+func handleComparison(operator string, left, right float64) (interface{}, error) {
 	switch operator {
 	case ">":
 		return left > right, nil
 	case "<":
 		return left < right, nil
+	case ">=":
+		return left >= right, nil
+	case "<=":
+		return left <= right, nil
 	case "==":
 		return left == right, nil
+	case "!=":
+		return left != right, nil
 	default:
 		return nil, fmt.Errorf("unknown comparison operator: %s", operator)
 	}
 }
 
 // handleForLoop executes a for loop, managing initialization, condition, and post-iteration.
-func (e *Executor) handleForLoop(n *models.ForLoop) (interface{}, error) {
+func (e *Executor) handleForLoop(n *models.ForLoop, env *Environment) (interface{}, error) {
 	// Execute the initialization part of the loop.
-	_, err := e.Execute(n.Initialization)
+	_, err := e.eval(n.Initialization, env)
 	if err != nil {
 		return nil, err
 	}
 
 	// Loop while the condition is true.
+	iterations := 0
 	for {
-		condition, err := e.Execute(n.Condition)
+		condition, err := e.eval(n.Condition, env)
 		if err != nil {
 			return nil, err
 		}
-		condBool, ok := condition.(bool)
-		if !ok {
-			return nil, errors.New("condition must evaluate to a boolean")
-		}
-		if !condBool {
+		if !isTruthy(condition) {
 			break
 		}
 
-		// Execute the loop body.
-		for _, stmt := range n.Body {
-			_, err := e.Execute(stmt)
-			if err != nil {
-				return nil, err
-			}
+		// Execute the loop body, stopping early on a break statement.
+		done, err := e.runLoopBody(n.Body, env)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
 		}
 
 		// Execute the post iteration statement.
-		_, err = e.Execute(n.Post)
+		_, err = e.eval(n.Post, env)
 		if err != nil {
 			return nil, err
 		}
+		iterations++
+		e.reportProgress(ProgressLoop, n, iterations, -1, false)
 	}
+	e.reportProgress(ProgressLoop, n, iterations, -1, true)
 	return nil, nil
 }
 
 // handleWhileLoop executes a while loop, continuing as long as the condition is true.
-func (e *Executor) handleWhileLoop(n *models.WhileLoop) (interface{}, error) {
+func (e *Executor) handleWhileLoop(n *models.WhileLoop, env *Environment) (interface{}, error) {
+	iterations := 0
 	for {
 		// Evaluate the condition.
-		condition, err := e.Execute(n.Condition)
+		condition, err := e.eval(n.Condition, env)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(condition) {
+			break
+		}
+
+		// Execute the loop body, stopping early on a break statement.
+		done, err := e.runLoopBody(n.Body, env)
 		if err != nil {
 			return nil, err
 		}
-		condBool, ok := condition.(bool)
+		if done {
+			break
+		}
+		iterations++
+		e.reportProgress(ProgressLoop, n, iterations, -1, false)
+	}
+	e.reportProgress(ProgressLoop, n, iterations, -1, true)
+	return nil, nil
+}
+
+// handleForEachLoop executes a loop body once per element of a collection.
+func (e *Executor) handleForEachLoop(n *models.ForEachLoop, env *Environment) (interface{}, error) {
+	collection, err := e.eval(n.Collection, env)
+	if err != nil {
+		return nil, err
+	}
+	if it, ok := e.iterator(collection); ok {
+		return e.forEachIterator(n, it, env)
+	}
+	keys, lookup, err := loopElements(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	iterations := 0
+	for i, key := range keys {
+		val, ok := lookup(i)
 		if !ok {
-			return nil, errors.New("condition must evaluate to a boolean")
+			continue
 		}
-		if !condBool {
+		if err := bindLoopVariables(n, env, key, val); err != nil {
+			return nil, err
+		}
+
+		// Execute the loop body, stopping early on a break statement.
+		done, err := e.runLoopBody(n.Body, env)
+		if err != nil {
+			return nil, err
+		}
+		if done {
 			break
 		}
+		iterations++
+		e.reportProgress(ProgressLoop, n, iterations, len(keys), false)
+	}
+	e.reportProgress(ProgressLoop, n, iterations, len(keys), true)
+	return nil, nil
+}
 
-		// Execute the loop body.
-		for _, stmt := range n.Body {
-			_, err := e.Execute(stmt)
-			if err != nil {
-				return nil, err
-			}
+// loopElements returns the keys of a list, map, or string in the order a
+// ForEach loop visits them, and a function returning the element at the i-th
+// key. The function reports false for a map key that has since been deleted.
+func loopElements(collection interface{}) ([]interface{}, func(i int) (interface{}, bool), error) {
+	switch c := collection.(type) {
+	case []interface{}:
+		return indexKeys(len(c)), func(i int) (interface{}, bool) { return c[i], true }, nil
+	case []float64:
+		return indexKeys(len(c)), func(i int) (interface{}, bool) { return c[i], true }, nil
+	case map[string]interface{}:
+		// Visit a snapshot of the keys, skipping any the body deletes.
+		names := sortedKeys(c)
+		return stringList(names), func(i int) (interface{}, bool) {
+			val, ok := c[names[i]]
+			return val, ok
+		}, nil
+	case string:
+		chars := []rune(c)
+		return indexKeys(len(chars)), func(i int) (interface{}, bool) { return string(chars[i]), true }, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot iterate over %v", collection)
+	}
+}
+
+// forEachIterator runs a ForEach loop over the values of an iterator, binding
+// the key variable to a count from zero.
+func (e *Executor) forEachIterator(n *models.ForEachLoop, it Iterator, env *Environment) (interface{}, error) {
+	iterations := 0
+	for {
+		val, ok, err := it.Next()
+		if err != nil {
+			return nil, err
 		}
+		if !ok {
+			break
+		}
+		if err := bindLoopVariables(n, env, float64(iterations), val); err != nil {
+			return nil, err
+		}
+
+		done, err := e.runLoopBody(n.Body, env)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
+		}
+		iterations++
+		e.reportProgress(ProgressLoop, n, iterations, -1, false)
 	}
+	e.reportProgress(ProgressLoop, n, iterations, -1, true)
 	return nil, nil
 }
 
+// SetStrictAssignments controls whether assigning to a variable that no
+// enclosing scope declares is an error. By default such an assignment creates
+// the variable in the current scope. Variables are declared by let and const
+// declarations, function parameters, ForEach loops, and the host.
+func (e *Executor) SetStrictAssignments(strict bool) {
+	e.strictAssign = strict
+}
+
+// assignVariable assigns name in env, enforcing strict assignment mode.
+func (e *Executor) assignVariable(env *Environment, name string, val interface{}) error {
+	if e.strictAssign {
+		if _, ok := env.Lookup(name); !ok {
+			return fmt.Errorf("assignment to undeclared variable %s", name)
+		}
+	}
+	return env.assign(name, val)
+}
+
+// bindLoopVariables assigns the key and value of one ForEach iteration.
+func bindLoopVariables(n *models.ForEachLoop, env *Environment, key, val interface{}) error {
+	if n.Key != nil {
+		if err := env.assign(n.Key.Name, key); err != nil {
+			return err
+		}
+	}
+	if n.Value != nil {
+		return env.assign(n.Value.Name, val)
+	}
+	return nil
+}
+
+// indexKeys returns the list indices 0 through n-1 as silk numbers.
+func indexKeys(n int) []interface{} {
+	keys := make([]interface{}, n)
+	for i := range keys {
+		keys[i] = float64(i)
+	}
+	return keys
+}
+
 // isValidOperator checks if the given operator is a valid arithmetic operator.
 // It returns true if the operator is valid, and false otherwise.
 func (e *Executor) isValidOperator(operator string) bool {
-	return operator == "+" || operator == "-" || operator == "*" || operator == "/"
+	switch operator {
+	case "+", "-", "*", "/", "%", "**", "&", "|", "^", "<<", ">>":
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,292 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"silk/internal/models"
+)
+
+// DefaultMaxCallDepth is the number of nested user function calls an executor
+// allows before failing with a recursion depth error.
+const DefaultMaxCallDepth = 10000
+
+// Function is a callable silk value: a user-defined function, a closure created
+// by a function literal or nested declaration, or a builtin.
+type Function struct {
+	Name    string                      // Declared name, or empty for function literals.
+	decl    *models.FunctionDeclaration // Parameters and body of a user-defined function.
+	env     *Environment                // Scope the function was created in.
+	builtin BuiltinFunc                 // Implementation of a builtin; nil for user-defined functions.
+}
+
+// String renders the function for output.
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<function>"
+	}
+	return fmt.Sprintf("<function %s>", f.Name)
+}
+
+// SetMaxCallDepth limits how deeply user functions may nest calls before the
+// call fails with "maximum recursion depth exceeded" instead of exhausting the
+// Go stack. The limit applies to each chain of calls: the statements of a
+// parallel construct or task, and the functions a builtin such as map calls
+// back, continue the count of the code that started them, while separate
+// Execute calls and message handlers each count from zero. Zero or a negative
+// value removes the limit.
+func (e *Executor) SetMaxCallDepth(n int) {
+	e.maxCallDepth = int64(max(n, 0))
+}
+
+// lookupFunction returns the builtin or top-level user-defined function named name.
+func (e *Executor) lookupFunction(name string) (*Function, bool) {
+	if builtin, ok := e.builtins[name]; ok {
+		return &Function{Name: name, builtin: builtin}, true
+	}
+	if decl, ok := e.function(name); ok {
+		return &Function{Name: name, decl: decl, env: e.globals}, true
+	}
+	return nil, false
+}
+
+// function returns the top-level user-defined function named name. Programs
+// may declare functions while others run, so the map is read under declsMu.
+func (e *Executor) function(name string) (*models.FunctionDeclaration, bool) {
+	e.declsMu.RLock()
+	defer e.declsMu.RUnlock()
+	decl, ok := e.functions[name]
+	return decl, ok
+}
+
+// namespacedCallee reports whether callee spells the qualified name of a
+// function, as in "math.sqrt(x)", and returns the name. A variable in scope
+// named like the namespace takes precedence, so method calls and calls of
+// functions stored in maps are unaffected.
+func namespacedCallee(callee models.Node, env *Environment) (string, bool) {
+	if _, ok := callee.(*models.MemberExpression); !ok {
+		return "", false
+	}
+	name, ok := qualifiedName(callee)
+	if !ok {
+		return "", false
+	}
+	root, _, _ := strings.Cut(name, ".")
+	if _, bound := env.Lookup(root); bound {
+		return "", false
+	}
+	return name, true
+}
+
+// qualifiedName returns the dotted name spelled by a variable or a chain of
+// member expressions on one.
+func qualifiedName(node models.Node) (string, bool) {
+	switch n := node.(type) {
+	case *models.Variable:
+		return n.Name, true
+	case *models.MemberExpression:
+		prefix, ok := qualifiedName(n.Object)
+		if !ok {
+			return "", false
+		}
+		return prefix + "." + n.Property, true
+	default:
+		return "", false
+	}
+}
+
+// validQualifiedName reports whether name is a sequence of identifiers
+// separated by dots.
+func validQualifiedName(name string) bool {
+	for _, part := range strings.Split(name, ".") {
+		if part == "" {
+			return false
+		}
+		for i, r := range part {
+			if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// functionArg converts a builtin argument to a function. The argument may be a
+// function value or the name of a builtin or top-level user-defined function,
+// which lets builtins call back into silk functions supplied by the program.
+func (e *Executor) functionArg(name string, v interface{}) (*Function, error) {
+	switch v := v.(type) {
+	case *Function:
+		return v, nil
+	case string:
+		fn, ok := e.lookupFunction(v)
+		if !ok {
+			return nil, fmt.Errorf("%s: undefined function: %s", name, v)
+		}
+		return fn, nil
+	default:
+		return nil, fmt.Errorf("%s: expected a function, got %v", name, v)
+	}
+}
+
+// callFunction invokes a function value with already evaluated arguments,
+// starting a new chain of calls.
+func (e *Executor) callFunction(fn *Function, args []interface{}) (interface{}, error) {
+	return e.callFunctionIn(callSite{}, fn, args)
+}
+
+// callFunctionCtx invokes a function value for a builtin given ctx, so that
+// the call continues the chain of calls of the builtin's caller and is
+// cancelled with it.
+func (e *Executor) callFunctionCtx(ctx context.Context, fn *Function, args []interface{}) (interface{}, error) {
+	return e.callFunctionIn(callSiteOf(ctx), fn, args)
+}
+
+// callFunctionIn invokes a function value for a caller at site, so that the
+// call is cancelled with the caller and counts toward its call depth.
+func (e *Executor) callFunctionIn(site callSite, fn *Function, args []interface{}) (interface{}, error) {
+	if fn.builtin != nil {
+		if err := e.authorize(fn.Name); err != nil {
+			return nil, err
+		}
+		return e.runBuiltin(site, fn.Name, fn.builtin, args)
+	}
+	if g := e.gates[fn.Name]; g != nil {
+		return g.call(e, fn.Name, site, args, func(site callSite, args []interface{}) (interface{}, error) {
+			return e.runFunction(site, fn, args)
+		})
+	}
+	return e.runFunction(site, fn, args)
+}
+
+// runFunction runs the body of a user-defined function.
+func (e *Executor) runFunction(site callSite, fn *Function, args []interface{}) (interface{}, error) {
+	if err := e.throttle(e.rateLimits[fn.Name], site.cancel); err != nil {
+		return nil, err
+	}
+	function := fn.decl
+	if e.isGenerator(function) {
+		env, err := e.bindArguments(fn, args, site)
+		if err != nil {
+			return nil, err
+		}
+		return e.newGenerator(fn, env), nil
+	}
+
+	if e.maxCallDepth > 0 && int64(site.depth) >= e.maxCallDepth {
+		return nil, fmt.Errorf("maximum recursion depth exceeded (%d calls) in function %s", e.maxCallDepth, fn.displayName())
+	}
+
+call:
+	for {
+		env, err := e.bindArguments(fn, args, site)
+		if err != nil {
+			return nil, err
+		}
+
+		// Execute the function body until it returns, however deeply nested the
+		// return statement is. A self call in tail position starts the body over
+		// with the new arguments rather than growing the call stack.
+		var result interface{}
+		for _, stmt := range function.Body {
+			if isComment(stmt) {
+				continue
+			}
+			res, err := e.eval(stmt, env)
+			var ret *returnSignal
+			var tail *tailCall
+			switch {
+			case errors.As(err, &ret):
+				return ret.value, nil
+			case errors.As(err, &tail):
+				args = tail.args
+				continue call
+			case err != nil:
+				return nil, loopSignalError(err)
+			}
+			result = res
+		}
+		return result, nil
+	}
+}
+
+// bindArguments creates the scope for a call to a user-defined function made
+// at site, nested in the scope the function was created in rather than the
+// caller's. Omitted arguments take their defaults.
+func (e *Executor) bindArguments(fn *Function, args []interface{}, site callSite) (*Environment, error) {
+	if err := fn.checkArity(len(args)); err != nil {
+		return nil, err
+	}
+	env := newEnvironment(fn.env)
+	env.function = fn
+	env.cancel = site.cancel
+	env.depth = site.depth + 1
+	for i, param := range fn.decl.Parameters {
+		if i < len(args) {
+			env.define(param.Name, args[i])
+			continue
+		}
+		val, err := e.eval(fn.decl.Defaults[i], env)
+		if err != nil {
+			return nil, err
+		}
+		env.define(param.Name, val)
+	}
+	return env, nil
+}
+
+// isSelfCall reports whether call invokes the function whose body is executing
+// in env, resolving the name the same way handleFunctionCall does. A call made
+// under a construct that must see its outcome, such as a WithTimeout, a
+// parallel branch, Cached, or Retry, is not one, since starting the body over
+// would leave the construct.
+func (e *Executor) isSelfCall(call *models.FunctionCall, env *Environment) bool {
+	frame := env.frame()
+	if frame == nil || frame.generator != nil || call.Callee != nil || call.IdempotencyKey != nil {
+		return false
+	}
+	for scope := env; scope != frame; scope = scope.parent {
+		if scope.boundary || scope.cancel != frame.cancel {
+			return false
+		}
+	}
+	current := frame.function
+	if val, ok := env.Lookup(call.Name); ok {
+		if fn, ok := val.(*Function); ok {
+			return fn.decl == current.decl && fn.env == current.env
+		}
+	}
+	if _, ok := e.builtins[call.Name]; ok {
+		return false
+	}
+	decl, _ := e.function(call.Name)
+	return current.env == e.globals && decl == current.decl
+}
+
+// checkArity verifies that a user-defined function accepts n arguments, given
+// that trailing parameters with defaults may be omitted.
+func (f *Function) checkArity(n int) error {
+	params := len(f.decl.Parameters)
+	required := params
+	for required > 0 && required <= len(f.decl.Defaults) && f.decl.Defaults[required-1] != nil {
+		required--
+	}
+	if n > params || n < required {
+		if required == params {
+			return fmt.Errorf("function %s expects %d arguments, but got %d", f.displayName(), params, n)
+		}
+		return fmt.Errorf("function %s expects %d to %d arguments, but got %d", f.displayName(), required, params, n)
+	}
+	return nil
+}
+
+// displayName names the function in error messages.
+func (f *Function) displayName() string {
+	if f.Name == "" {
+		return "<anonymous>"
+	}
+	return f.Name
+}
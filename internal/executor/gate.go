@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// callGate throttles or debounces the calls to a function.
+type callGate struct {
+	interval time.Duration
+	debounce bool
+	mu       sync.Mutex
+	last     time.Time   // When a throttled call last ran.
+	result   interface{} // Result of the last throttled call to run.
+	calls    int         // Debounced calls made so far.
+}
+
+// SetThrottle lets programs call the builtin or user-defined function named
+// name at most once every interval, such as once a second for emitMetrics.
+// A call made sooner after the last one that ran is skipped and evaluates to
+// that call's result. A zero interval removes the throttle, as it does a
+// debounce set by SetDebounce.
+func (e *Executor) SetThrottle(name string, interval time.Duration) {
+	e.setGate(name, interval, false)
+}
+
+// SetDebounce delays calls to the builtin or user-defined function named name
+// until no call to it has been made for wait. Each call evaluates to null at
+// once, and only the last of a burst of calls runs, with its own arguments,
+// as a callback such as a message handler does. Its error, if any, is written
+// to Stderr, as by RenderError. A zero wait removes the debounce, as it does a
+// throttle set by SetThrottle.
+func (e *Executor) SetDebounce(name string, wait time.Duration) {
+	e.setGate(name, wait, true)
+}
+
+func (e *Executor) setGate(name string, interval time.Duration, debounce bool) {
+	if interval <= 0 {
+		delete(e.gates, name)
+		return
+	}
+	if e.gates == nil {
+		e.gates = make(map[string]*callGate)
+	}
+	e.gates[name] = &callGate{interval: interval, debounce: debounce}
+}
+
+// call passes a call to the function named name made at site through g,
+// calling run with the caller's site if the call is to run now.
+func (g *callGate) call(e *Executor, name string, site callSite, args []interface{}, run func(callSite, []interface{}) (interface{}, error)) (interface{}, error) {
+	if g.debounce {
+		g.mu.Lock()
+		g.calls++
+		call := g.calls
+		g.mu.Unlock()
+		time.AfterFunc(g.interval, func() {
+			g.mu.Lock()
+			latest := g.calls == call
+			g.mu.Unlock()
+			if !latest {
+				return
+			}
+			// The caller may be long gone, so the call starts a chain of its own.
+			e.scheduler.runCallback(func() {
+				if _, err := run(callSite{}, args); err != nil {
+					e.RenderError(fmt.Errorf("debounced call to %s: %w", name, err))
+				}
+			})
+		})
+		return nil, nil
+	}
+
+	g.mu.Lock()
+	now := time.Now()
+	if !g.last.IsZero() && now.Sub(g.last) < g.interval {
+		result := g.result
+		g.mu.Unlock()
+		return result, nil
+	}
+	g.last = now
+	g.mu.Unlock()
+	result, err := run(site, args)
+	if err == nil {
+		g.mu.Lock()
+		g.result = result
+		g.mu.Unlock()
+	}
+	return result, err
+}
@@ -0,0 +1,177 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// errGeneratorClosed unwinds the body of a generator that was closed or
+// abandoned while suspended at a yield statement.
+var errGeneratorClosed = errors.New("generator closed")
+
+// Generator is the value returned by calling a generator function. Its body
+// runs on its own goroutine, one step per call to Next, so values are produced
+// only as the caller consumes them.
+type Generator struct {
+	*generatorState
+}
+
+// generatorState is the part of a generator shared with the goroutine running
+// its body. The goroutine never refers to the Generator itself, so an
+// abandoned generator can be garbage collected, which closes it.
+type generatorState struct {
+	e    *Executor
+	fn   *Function
+	env  *Environment
+	mu   sync.Mutex // Serializes calls to Next.
+	done bool
+
+	started  bool
+	resume   chan struct{}      // Caller to body: produce the next value.
+	steps    chan generatorStep // Body to caller: a value, or the end of the body.
+	stop     chan struct{}      // Closed when the generator is closed.
+	stopOnce sync.Once
+}
+
+// generatorStep is one value produced by a generator, or its completion.
+type generatorStep struct {
+	value interface{}
+	done  bool
+	err   error
+}
+
+// newGenerator creates a suspended generator that will run fn's body in env.
+func (e *Executor) newGenerator(fn *Function, env *Environment) *Generator {
+	state := &generatorState{
+		e:      e,
+		fn:     fn,
+		env:    env,
+		resume: make(chan struct{}),
+		steps:  make(chan generatorStep),
+		stop:   make(chan struct{}),
+	}
+	env.generator = state
+	g := &Generator{state}
+	runtime.SetFinalizer(g, func(g *Generator) { g.Close() })
+	return g
+}
+
+// String renders the generator for output.
+func (g *Generator) String() string {
+	if g.fn.Name == "" {
+		return "<generator>"
+	}
+	return fmt.Sprintf("<generator %s>", g.fn.Name)
+}
+
+// Next resumes the generator until it yields a value, which it returns with
+// ok set. Once the body has finished, Next returns ok false, along with the
+// error that ended the body the first time it is reported.
+func (g *Generator) Next() (val interface{}, ok bool, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done {
+		return nil, false, nil
+	}
+
+	if !g.started {
+		g.started = true
+		go g.run()
+	} else {
+		select {
+		case g.resume <- struct{}{}:
+		case <-g.stop:
+		}
+	}
+
+	select {
+	case step := <-g.steps:
+		if step.done {
+			g.done = true
+			return nil, false, step.err
+		}
+		return step.value, true, nil
+	case <-g.stop:
+		g.done = true
+		return nil, false, nil
+	}
+}
+
+// Close stops the generator. A body suspended at a yield statement is unwound
+// without running the rest of it.
+func (g *Generator) Close() {
+	g.stopOnce.Do(func() { close(g.stop) })
+}
+
+// run executes the generator body, reporting its end on the steps channel.
+func (s *generatorState) run() {
+	err := s.body()
+	if err == errGeneratorClosed {
+		return
+	}
+	select {
+	case s.steps <- generatorStep{done: true, err: err}:
+	case <-s.stop:
+	}
+}
+
+// body executes the statements of the generator function. A return statement
+// ends the generator; its value is discarded.
+func (s *generatorState) body() error {
+	if s.e.maxCallDepth > 0 && int64(s.env.depth) > s.e.maxCallDepth {
+		return fmt.Errorf("maximum recursion depth exceeded (%d calls) in function %s", s.e.maxCallDepth, s.fn.displayName())
+	}
+	for _, stmt := range s.fn.decl.Body {
+		_, err := s.e.eval(stmt, s.env)
+		var ret *returnSignal
+		if errors.As(err, &ret) {
+			return nil
+		}
+		if err != nil {
+			return loopSignalError(err)
+		}
+	}
+	return nil
+}
+
+// yield hands val to the caller of Next and blocks until the generator is
+// resumed. It returns errGeneratorClosed if the generator is closed instead.
+func (s *generatorState) yield(val interface{}) error {
+	select {
+	case s.steps <- generatorStep{value: val}:
+	case <-s.stop:
+		return errGeneratorClosed
+	}
+	select {
+	case <-s.resume:
+		return nil
+	case <-s.stop:
+		return errGeneratorClosed
+	}
+}
+
+// isGenerator reports whether the body of decl contains a yield statement,
+// not counting those in nested functions.
+func (e *Executor) isGenerator(decl *models.FunctionDeclaration) bool {
+	if known, ok := e.generators.Load(decl); ok {
+		return known.(bool)
+	}
+	found := false
+	for _, stmt := range decl.Body {
+		models.Walk(stmt, func(node models.Node) bool {
+			switch node.(type) {
+			case *models.YieldStatement:
+				found = true
+			case *models.FunctionDeclaration, *models.FunctionLiteral, *models.MethodDeclaration:
+				return false
+			}
+			return !found
+		})
+	}
+	e.generators.Store(decl, found)
+	return found
+}
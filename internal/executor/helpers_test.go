@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Helpers for building the ASTs that tests run.
+
+func num(v int64) models.Node { return &models.Integer{Value: v} }
+
+func str(v string) models.Node { return &models.String{Value: v} }
+
+func ref(name string) *models.Variable { return &models.Variable{Name: name} }
+
+func list(elems ...models.Node) *models.ArrayLiteral { return &models.ArrayLiteral{Elements: elems} }
+
+func assign(name string, val models.Node) models.Node {
+	return &models.Assignment{Variable: ref(name), Value: val}
+}
+
+func call(name string, args ...models.Node) *models.FunctionCall {
+	return &models.FunctionCall{Name: name, Args: args}
+}
+
+func ret(val models.Node) models.Node { return &models.ReturnStatement{Value: val} }
+
+func binop(left models.Node, op string, right models.Node) models.Node {
+	return &models.BinaryExpression{Left: left, Operator: op, Right: right}
+}
+
+func function(name string, params []string, body ...models.Node) *models.FunctionDeclaration {
+	vars := make([]*models.Variable, len(params))
+	for i, p := range params {
+		vars[i] = ref(p)
+	}
+	return &models.FunctionDeclaration{Name: name, Parameters: vars, Body: body}
+}
+
+func program(body ...models.Node) *models.Program { return &models.Program{Body: body} }
+
+// keys returns n distinct strings, as a list literal.
+func keys(n int) *models.ArrayLiteral {
+	list := &models.ArrayLiteral{}
+	for i := 0; i < n; i++ {
+		list.Elements = append(list.Elements, str(fmt.Sprint("k", i)))
+	}
+	return list
+}
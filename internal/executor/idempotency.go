@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"fmt"
+	"sync"
+)
+
+// IdempotencyStore records the results of builtin calls made with an idempotency key.
+// Hosts that resume workflows from checkpoints should supply a durable implementation
+// shared across runs. Implementations must be safe for concurrent use.
+type IdempotencyStore interface {
+	// Lookup returns the recorded result for key, if the call has already completed.
+	Lookup(key string) (interface{}, bool)
+	// Record stores the result of a completed call under key.
+	Record(key string, result interface{}) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that lives for the lifetime of the process.
+type MemoryIdempotencyStore struct {
+	mu      sync.RWMutex
+	results map[string]interface{}
+}
+
+// NewMemoryIdempotencyStore creates an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{results: make(map[string]interface{})}
+}
+
+// Lookup returns the recorded result for key.
+func (s *MemoryIdempotencyStore) Lookup(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result, ok := s.results[key]
+	return result, ok
+}
+
+// Record stores the result of a completed call under key.
+func (s *MemoryIdempotencyStore) Record(key string, result interface{}) error {
+	s.mu.Lock()
+	s.results[key] = result
+	s.mu.Unlock()
+	return nil
+}
+
+// SetIdempotencyStore replaces the store used to deduplicate keyed builtin calls.
+func (e *Executor) SetIdempotencyStore(store IdempotencyStore) {
+	e.idempotency = store
+}
+
+// idempotencyKey scopes a key value to the builtin it was attached to, so the same
+// key may be reused across different builtins without collisions. The key's type
+// is part of it, so that 1 and "1" are different keys.
+func idempotencyKey(builtin string, key interface{}) string {
+	return builtin + ":" + typeName(key) + ":" + fmt.Sprint(key)
+}
+
+// claimIdempotent returns the recorded result of the call made with key, if
+// there is one. Otherwise it reserves key for the caller, which must make the
+// call and then release key with releaseIdempotent. While key is reserved,
+// other callers wait for the call to end instead of making it again; if it
+// fails, the next of them makes it.
+func (e *Executor) claimIdempotent(key string, cancel *cancelScope) (interface{}, bool, error) {
+	for {
+		if result, ok := e.idempotency.Lookup(key); ok {
+			return result, true, nil
+		}
+		e.inflightMu.Lock()
+		wait, busy := e.inflight[key]
+		if !busy {
+			e.inflight[key] = make(chan struct{})
+		}
+		e.inflightMu.Unlock()
+		if !busy {
+			return nil, false, nil
+		}
+		select {
+		case <-wait:
+		case <-e.context(cancel).Done():
+			if cancel.interrupted() {
+				return nil, false, ErrInterrupted
+			}
+			return nil, false, errCancelled
+		}
+	}
+}
+
+// releaseIdempotent ends the reservation of key made by claimIdempotent, waking
+// the callers waiting for it.
+func (e *Executor) releaseIdempotent(key string) {
+	e.inflightMu.Lock()
+	close(e.inflight[key])
+	delete(e.inflight, key)
+	e.inflightMu.Unlock()
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"silk/internal/models"
+)
+
+// keyed calls the builtin "charge" with the idempotency key k.
+func keyed(k models.Node) models.Node {
+	c := call("charge")
+	c.IdempotencyKey = k
+	return c
+}
+
+func countingExecutor(calls *atomic.Int64) *Executor {
+	e := NewExecutor()
+	e.RegisterBuiltin("charge", func(args []interface{}) (interface{}, error) {
+		n := calls.Add(1)
+		// Long enough for concurrent calls to overlap.
+		time.Sleep(5 * time.Millisecond)
+		return n, nil
+	})
+	return e
+}
+
+func TestIdempotencyKeyConcurrentCalls(t *testing.T) {
+	var calls atomic.Int64
+	e := countingExecutor(&calls)
+	e.SetMaxGoroutines(4)
+	k := str("order-1")
+	val, err := e.Execute(program(&models.ParallelBlock{Body: []models.Node{keyed(k), keyed(k), keyed(k), keyed(k)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("charge ran %d times, want 1", n)
+	}
+	for i, v := range val.([]interface{}) {
+		if v != int64(1) {
+			t.Errorf("branch %d = %v, want the first call's result", i, v)
+		}
+	}
+}
+
+func TestIdempotencyKeyType(t *testing.T) {
+	var calls atomic.Int64
+	e := countingExecutor(&calls)
+	if _, err := e.Execute(program(keyed(num(1)), keyed(str("1")), keyed(num(1)))); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("charge ran %d times, want 2", n)
+	}
+}
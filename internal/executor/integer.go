@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Integers are int64 values, created by Integer literals and the int builtin.
+// Arithmetic on two integers gives an integer: / truncates toward zero, %
+// takes the sign of the dividend, and a result outside the int64 range is an
+// error rather than being rounded. An integer combined with a number gives a
+// number, with a big integer a big integer, and with a decimal a decimal.
+
+var errIntegerOverflow = errors.New("integer overflow")
+
+// toInteger returns v as an int64 if it is an integer or a whole number within
+// the int64 range.
+func toInteger(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return toInt64(v)
+	default:
+		return 0, false
+	}
+}
+
+// numberOperands converts the operands of an arithmetic operator to numbers,
+// widening integers. It reports false unless both are numbers or integers.
+func numberOperands(left, right interface{}) (l, r float64, ok bool) {
+	l, lok := toFloat(left)
+	r, rok := toFloat(right)
+	return l, r, lok && rok
+}
+
+// toFloat returns a number or integer as a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// intBinary applies an arithmetic or bitwise operator to two integers,
+// reporting false unless both operands are integers.
+func intBinary(operator string, left, right interface{}) (interface{}, bool, error) {
+	l, lok := left.(int64)
+	r, rok := right.(int64)
+	if !lok || !rok {
+		return nil, false, nil
+	}
+	val, err := intOperation(operator, l, r)
+	return val, true, err
+}
+
+func intOperation(operator string, l, r int64) (interface{}, error) {
+	switch operator {
+	case "+":
+		z := l + r
+		if (z > l) != (r > 0) {
+			return nil, errIntegerOverflow
+		}
+		return z, nil
+	case "-":
+		z := l - r
+		if (z < l) != (r > 0) {
+			return nil, errIntegerOverflow
+		}
+		return z, nil
+	case "*":
+		z, err := intMul(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return z, nil
+	case "/":
+		if r == 0 {
+			return nil, errors.New("division by zero")
+		}
+		if l == math.MinInt64 && r == -1 {
+			return nil, errIntegerOverflow
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, errors.New("modulo by zero")
+		}
+		if r == -1 {
+			return int64(0), nil
+		}
+		return l % r, nil
+	case "**":
+		if r < 0 {
+			return math.Pow(float64(l), float64(r)), nil
+		}
+		z, err := intPow(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return z, nil
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "^":
+		return l ^ r, nil
+	case "<<", ">>":
+		if r < 0 || r > 63 {
+			return nil, fmt.Errorf("shift count out of range: %d", r)
+		}
+		if operator == "<<" {
+			return l << r, nil
+		}
+		return l >> r, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", operator)
+	}
+}
+
+func intMul(l, r int64) (int64, error) {
+	if l == 0 || r == 0 {
+		return 0, nil
+	}
+	z := l * r
+	if z/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+		return 0, errIntegerOverflow
+	}
+	return z, nil
+}
+
+// intPow raises base to an exponent that is not negative, by squaring.
+func intPow(base, exp int64) (int64, error) {
+	result := int64(1)
+	var err error
+	for {
+		if exp&1 == 1 {
+			if result, err = intMul(result, base); err != nil {
+				return 0, err
+			}
+		}
+		exp >>= 1
+		if exp == 0 {
+			return result, nil
+		}
+		if base, err = intMul(base, base); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// intOrder compares an integer with an integer or a number, returning -1, 0,
+// or +1. It reports false if neither operand is an integer. The comparison is
+// exact, even for integers that no number represents.
+func intOrder(left, right interface{}) (int, bool) {
+	l, lok := left.(int64)
+	r, rok := right.(int64)
+	switch {
+	case lok && rok:
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		default:
+			return 0, true
+		}
+	case lok:
+		if f, ok := right.(float64); ok && !math.IsNaN(f) {
+			return new(big.Float).SetInt64(l).Cmp(big.NewFloat(f)), true
+		}
+	case rok:
+		if f, ok := left.(float64); ok && !math.IsNaN(f) {
+			return big.NewFloat(f).Cmp(new(big.Float).SetInt64(r)), true
+		}
+	}
+	return 0, false
+}
+
+// registerIntegerBuiltins registers the conversion to integers:
+//
+//	int(value)                integer from a number or decimal, truncated toward
+//	                          zero, from a big integer that fits, or from text
+//
+// number converts an integer back to a number.
+func (e *Executor) registerIntegerBuiltins() {
+	e.RegisterBuiltin("int", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("int", args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case int64:
+			return v, nil
+		case float64:
+			n, ok := toInt64(math.Trunc(v))
+			if !ok {
+				return nil, fmt.Errorf("int: %v is out of range", v)
+			}
+			return n, nil
+		case *big.Int:
+			if !v.IsInt64() {
+				return nil, fmt.Errorf("int: %v is out of range", v)
+			}
+			return v.Int64(), nil
+		case *Decimal:
+			q := new(big.Int).Quo(v.coef, pow10(v.scale))
+			if !q.IsInt64() {
+				return nil, fmt.Errorf("int: %v is out of range", v)
+			}
+			return q.Int64(), nil
+		case string:
+			n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int: invalid integer %q", v)
+			}
+			return n, nil
+		default:
+			return nil, fmt.Errorf("int: expected a number, big integer, decimal, or text, got %v", v)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package executor
+
+import "errors"
+
+// ErrInterrupted is the error of an Execute call that Stop aborted.
+var ErrInterrupted = errors.New("execution interrupted")
+
+// Stop aborts the Execute calls in progress. Each stops before evaluating its
+// next node, as do the branches of its parallel constructs and its background
+// tasks, and returns ErrInterrupted. Builtins registered with
+// RegisterContextBuiltin see their context cancelled; other builtins already
+// running are not cut short. Stop has no effect on Execute calls that start
+// later, even while the calls it aborted are still unwinding.
+func (e *Executor) Stop() {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	for run := range e.runs {
+		run.stopped.Store(true)
+		run.cancel()
+	}
+}
+
+// begin creates the cancel scope of an Execute call, through which Stop aborts
+// it, and returns the function that records the end of the call.
+func (e *Executor) begin() (*cancelScope, func()) {
+	run := e.newCancelScope(nil, 0)
+	e.runMu.Lock()
+	e.runs[run] = true
+	e.runMu.Unlock()
+	return run, func() {
+		e.runMu.Lock()
+		delete(e.runs, run)
+		e.runMu.Unlock()
+		run.stop()
+	}
+}
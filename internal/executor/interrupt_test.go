@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStopInterruptsOnlyRunsInProgress(t *testing.T) {
+	e := NewExecutor()
+	entered, release := make(chan struct{}), make(chan struct{})
+	// hold ignores cancellation, so a stopped run stays inside it until
+	// released.
+	e.RegisterBuiltin("hold", func(args []interface{}) (interface{}, error) {
+		close(entered)
+		<-release
+		return nil, nil
+	})
+
+	stopped := make(chan error)
+	go func() {
+		_, err := e.Execute(program(call("hold"), num(1)))
+		stopped <- err
+	}()
+	<-entered
+	e.Stop()
+
+	val, err := e.Execute(program(num(2)))
+	if err != nil || val != int64(2) {
+		t.Errorf("Execute after Stop = %v, %v; want 2", val, err)
+	}
+	close(release)
+	if err := <-stopped; !errors.Is(err, ErrInterrupted) {
+		t.Errorf("stopped run returned %v, want ErrInterrupted", err)
+	}
+}
+
+func TestExecuteDeclaresAtTopLevel(t *testing.T) {
+	e := NewExecutor()
+	if _, err := e.Execute(program(assign("x", num(1)), function("f", nil, ret(ref("x"))))); err != nil {
+		t.Fatal(err)
+	}
+	val, err := e.Execute(program(call("f")))
+	if err != nil || val != int64(1) {
+		t.Errorf("f() = %v, %v; want 1", val, err)
+	}
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Iterator is a sequence whose values are produced one at a time. ForEach loops
+// and the higher-order builtins consume any Iterator, so a builtin can stream
+// its results by returning one. Generators are iterators, ranges are iterated
+// from the start each time, and so is any struct whose type has a next method
+// returning a (value, ok) tuple.
+type Iterator interface {
+	// Next returns the next value with ok set, or ok false once the sequence
+	// is exhausted.
+	Next() (val interface{}, ok bool, err error)
+}
+
+// methodIterator adapts a struct with a next method to Iterator.
+type methodIterator struct {
+	e    *Executor
+	next *Function
+}
+
+func (it *methodIterator) Next() (interface{}, bool, error) {
+	res, err := it.e.callFunction(it.next, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	tuple, ok := res.(Tuple)
+	if !ok || len(tuple) != 2 {
+		return nil, false, fmt.Errorf("%s must return a (value, ok) tuple, got %v", it.next.displayName(), res)
+	}
+	return tuple[0], isTruthy(tuple[1]), nil
+}
+
+// iterator returns v as an Iterator if it implements the protocol.
+func (e *Executor) iterator(v interface{}) (Iterator, bool) {
+	switch v := v.(type) {
+	case Iterator:
+		return v, true
+	case *Range:
+		return &rangeIterator{r: v}, true
+	case *Struct:
+		next, ok := e.boundMethod(v, "next")
+		if !ok {
+			return nil, false
+		}
+		return &methodIterator{e: e, next: next}, true
+	default:
+		return nil, false
+	}
+}
+
+// sequenceArg converts a builtin argument to a list, draining it if it is an iterator.
+func (e *Executor) sequenceArg(name string, v interface{}) ([]interface{}, error) {
+	it, ok := e.iterator(v)
+	if !ok {
+		return listArg(name, v)
+	}
+	var list []interface{}
+	for {
+		val, ok, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return list, nil
+		}
+		list = append(list, val)
+	}
+}
+
+// evalElements evaluates call arguments or list elements in env. A Spread may
+// expand a list, a tuple, or any iterator.
+func (e *Executor) evalElements(nodes []models.Node, env *Environment) ([]interface{}, error) {
+	return evalElements(nodes, func(node models.Node) (interface{}, error) {
+		return e.eval(node, env)
+	}, func(v interface{}) ([]interface{}, error) {
+		if _, ok := e.iterator(v); ok {
+			return e.sequenceArg("spread", v)
+		}
+		return spreadList(v)
+	})
+}
+
+// registerIteratorBuiltins registers builtins for consuming iterators:
+//
+//	next(iterator)              the tuple (value, true) for the next value of the
+//	                            iterator, or (null, false) once it is exhausted
+//	collect(iterator)           list of the remaining values of the iterator
+func (e *Executor) registerIteratorBuiltins() {
+	e.RegisterBuiltin("next", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("next", args, 1); err != nil {
+			return nil, err
+		}
+		it, ok := e.iterator(args[0])
+		if !ok {
+			return nil, fmt.Errorf("next: expected an iterator, got %v", args[0])
+		}
+		val, ok, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		return Tuple{val, ok}, nil
+	})
+	e.RegisterBuiltin("collect", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("collect", args, 1); err != nil {
+			return nil, err
+		}
+		list, err := e.sequenceArg("collect", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			list = []interface{}{}
+		}
+		return list, nil
+	})
+}
@@ -0,0 +1,292 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Mutex is a lock for parallel code to guard shared state with, the value of
+// the mutex builtin.
+type Mutex struct {
+	ch chan struct{} // Holds a token while the mutex is locked.
+}
+
+func (m *Mutex) String() string {
+	return "<mutex>"
+}
+
+// lock blocks until m is unlocked or ctx is done.
+func (m *Mutex) lock(ctx context.Context) error {
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Mutex) unlock() error {
+	select {
+	case <-m.ch:
+		return nil
+	default:
+		return errors.New("unlock of an unlocked mutex")
+	}
+}
+
+// semaphoreMaxPermits bounds the permits of a semaphore, whose tokens are
+// buffered in a channel of that capacity.
+const semaphoreMaxPermits = 1 << 20
+
+// Semaphore holds a fixed number of permits, the value of the semaphore
+// builtin. Parallel code takes a permit before using a shared resource, such
+// as a service that allows only a few connections, so that no more than that
+// many statements use it at once, whatever the executor's goroutine limit.
+type Semaphore struct {
+	ch chan struct{} // Holds a token for each permit acquired.
+}
+
+func (s *Semaphore) String() string {
+	return fmt.Sprintf("<semaphore %d/%d>", len(s.ch), cap(s.ch))
+}
+
+// acquire blocks until a permit is free or ctx is done.
+func (s *Semaphore) acquire(ctx context.Context) error {
+	select {
+	case s.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Semaphore) release() error {
+	select {
+	case <-s.ch:
+		return nil
+	default:
+		return errors.New("release of a semaphore with no permits acquired")
+	}
+}
+
+// Atomic is a variable whose operations are atomic, the value of the atomic
+// builtin. Unlike an assignment such as "total += x", atomicAdd reads and
+// writes the value as one step, so parallel statements can accumulate into it.
+type Atomic struct {
+	mu  sync.Mutex
+	val interface{}
+}
+
+func (a *Atomic) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return fmt.Sprintf("<atomic %v>", a.val)
+}
+
+// registerLockBuiltins registers builtins for sharing state between parallel
+// statements, tasks, and loop iterations:
+//
+//	mutex()                       a new, unlocked mutex
+//	lock(mutex)                   lock mutex, waiting until it is unlocked
+//	unlock(mutex)                 unlock mutex
+//	withLock(mutex, fn)           result of fn() called with mutex locked
+//	semaphore(n)                  a new semaphore with n permits, all free
+//	acquire(semaphore)            take a permit, waiting until one is free
+//	release(semaphore)            give back a permit
+//	withPermit(semaphore, fn)     result of fn() called holding a permit
+//	atomic(value)                 a new atomic variable holding value
+//	atomicGet(atomic)             value of atomic
+//	atomicSet(atomic, value)      store value in atomic; returns value
+//	atomicAdd(atomic, delta)      add delta to atomic with +; returns the sum
+//	compareAndSwap(atomic, old, new)
+//	                              store new in atomic if it holds old; returns
+//	                              whether it did
+func (e *Executor) registerLockBuiltins() {
+	e.RegisterBuiltin("mutex", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("mutex", args, 0); err != nil {
+			return nil, err
+		}
+		return &Mutex{ch: make(chan struct{}, 1)}, nil
+	})
+	e.RegisterContextBuiltin("lock", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("lock", args, 1); err != nil {
+			return nil, err
+		}
+		m, err := mutexArg("lock", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, m.lock(ctx)
+	})
+	e.RegisterBuiltin("unlock", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("unlock", args, 1); err != nil {
+			return nil, err
+		}
+		m, err := mutexArg("unlock", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, m.unlock()
+	})
+	e.RegisterContextBuiltin("withLock", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("withLock", args, 2); err != nil {
+			return nil, err
+		}
+		m, err := mutexArg("withLock", args[0])
+		if err != nil {
+			return nil, err
+		}
+		fn, err := e.functionArg("withLock", args[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := m.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer m.unlock()
+		return e.callFunctionCtx(ctx, fn, nil)
+	})
+
+	e.RegisterBuiltin("semaphore", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("semaphore", args, 1); err != nil {
+			return nil, err
+		}
+		n, err := intArg("semaphore", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 || n > semaphoreMaxPermits {
+			return nil, fmt.Errorf("semaphore: expected between 1 and %d permits, got %d", semaphoreMaxPermits, n)
+		}
+		return &Semaphore{ch: make(chan struct{}, n)}, nil
+	})
+	e.RegisterContextBuiltin("acquire", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("acquire", args, 1); err != nil {
+			return nil, err
+		}
+		s, err := semaphoreArg("acquire", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.acquire(ctx)
+	})
+	e.RegisterBuiltin("release", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("release", args, 1); err != nil {
+			return nil, err
+		}
+		s, err := semaphoreArg("release", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.release()
+	})
+	e.RegisterContextBuiltin("withPermit", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("withPermit", args, 2); err != nil {
+			return nil, err
+		}
+		s, err := semaphoreArg("withPermit", args[0])
+		if err != nil {
+			return nil, err
+		}
+		fn, err := e.functionArg("withPermit", args[1])
+		if err != nil {
+			return nil, err
+		}
+		if err := s.acquire(ctx); err != nil {
+			return nil, err
+		}
+		defer s.release()
+		return e.callFunctionCtx(ctx, fn, nil)
+	})
+
+	e.RegisterBuiltin("atomic", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("atomic", args, 1); err != nil {
+			return nil, err
+		}
+		return &Atomic{val: args[0]}, nil
+	})
+	e.RegisterBuiltin("atomicGet", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("atomicGet", args, 1); err != nil {
+			return nil, err
+		}
+		a, err := atomicArg("atomicGet", args[0])
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.val, nil
+	})
+	e.RegisterBuiltin("atomicSet", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("atomicSet", args, 2); err != nil {
+			return nil, err
+		}
+		a, err := atomicArg("atomicSet", args[0])
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.val = args[1]
+		return a.val, nil
+	})
+	e.RegisterBuiltin("atomicAdd", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("atomicAdd", args, 2); err != nil {
+			return nil, err
+		}
+		a, err := atomicArg("atomicAdd", args[0])
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		sum, err := e.binary(callSite{}, "+", a.val, args[1])
+		if err != nil {
+			return nil, err
+		}
+		a.val = sum
+		return sum, nil
+	})
+	e.RegisterBuiltin("compareAndSwap", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("compareAndSwap", args, 3); err != nil {
+			return nil, err
+		}
+		a, err := atomicArg("compareAndSwap", args[0])
+		if err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if !valuesEqual(a.val, args[1]) {
+			return false, nil
+		}
+		a.val = args[2]
+		return true, nil
+	})
+}
+
+func mutexArg(name string, v interface{}) (*Mutex, error) {
+	m, ok := v.(*Mutex)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a mutex, got %v", name, v)
+	}
+	return m, nil
+}
+
+func semaphoreArg(name string, v interface{}) (*Semaphore, error) {
+	s, ok := v.(*Semaphore)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a semaphore, got %v", name, v)
+	}
+	return s, nil
+}
+
+func atomicArg(name string, v interface{}) (*Atomic, error) {
+	a, ok := v.(*Atomic)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected an atomic, got %v", name, v)
+	}
+	return a, nil
+}
@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"silk/internal/models"
+)
+
+// handleMatch evaluates the body of the first arm of n that matches its value.
+// Each arm binds its pattern variables in a scope of its own.
+func (e *Executor) handleMatch(n *models.MatchExpression, env *Environment) (interface{}, error) {
+	val, err := e.eval(n.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	for _, arm := range n.Arms {
+		scope := newEnvironment(env)
+		ok, err := e.matchPattern(arm.Pattern, val, scope)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if arm.Guard != nil {
+			guard, err := e.eval(arm.Guard, scope)
+			if err != nil {
+				return nil, err
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return e.eval(arm.Body, scope)
+	}
+	return nil, fmt.Errorf("match: no arm matches %v", val)
+}
+
+// matchPattern reports whether val matches pattern, binding the variables the
+// pattern names in scope. Bindings made before a mismatch is found are left in
+// place; callers discard the scope in that case.
+func (e *Executor) matchPattern(pattern models.Node, val interface{}, scope *Environment) (bool, error) {
+	switch p := pattern.(type) {
+	case *models.Variable:
+		if p.Name != "_" {
+			scope.define(p.Name, val)
+		}
+		return true, nil
+
+	case *models.TypePattern:
+		if typeName(val) != p.Type {
+			return false, nil
+		}
+		if p.Pattern == nil {
+			return true, nil
+		}
+		return e.matchPattern(p.Pattern, val, scope)
+
+	case *models.ArrayLiteral:
+		var elems []interface{}
+		switch v := val.(type) {
+		case Tuple:
+			elems = v
+		case []interface{}, []float64:
+			elems, _ = listArg("match", v)
+		default:
+			return false, nil
+		}
+		if len(elems) != len(p.Elements) {
+			return false, nil
+		}
+		for i, elem := range p.Elements {
+			if ok, err := e.matchPattern(elem, elems[i], scope); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case *models.MapLiteral:
+		var fields map[string]interface{}
+		switch v := val.(type) {
+		case map[string]interface{}:
+			fields = v
+		case *Struct:
+			fields = v.fields()
+		default:
+			return false, nil
+		}
+		for _, entry := range p.Entries {
+			key, err := e.eval(entry.Key, scope)
+			if err != nil {
+				return false, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				return false, fmt.Errorf("map keys must be strings, got %v", key)
+			}
+			field, ok := fields[keyStr]
+			if !ok {
+				return false, nil
+			}
+			if ok, err := e.matchPattern(entry.Value, field, scope); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	default:
+		want, err := e.eval(pattern, scope)
+		if err != nil {
+			return false, err
+		}
+		return valuesEqual(want, val), nil
+	}
+}
+
+// typeName names the type of a value, as typeof reports it and TypePattern matches it.
+func typeName(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}, []float64:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	case Tuple:
+		return "tuple"
+	case *Function:
+		return "function"
+	case *Generator:
+		return "generator"
+	case *Matrix:
+		return "matrix"
+	case *Struct:
+		return v.Type.Name
+	case *EnumMember:
+		return v.Enum
+	case *Range:
+		return "range"
+	case time.Time:
+		return "time"
+	case time.Duration:
+		return "duration"
+	case *big.Int:
+		return "bigint"
+	case *Decimal:
+		return "decimal"
+	case *Regex:
+		return "regex"
+	case *Future:
+		return "future"
+	case *Mutex:
+		return "mutex"
+	case *Semaphore:
+		return "semaphore"
+	case *Atomic:
+		return "atomic"
+	case *Enum:
+		return "enum"
+	case *Module:
+		return "module"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"silk/internal/utils"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the builtin latency histogram.
+// They match the Prometheus client libraries' default buckets.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects executor statistics and serves them in the Prometheus text
+// exposition format. One Metrics may be shared by several executors, in which
+// case their statistics are aggregated.
+type Metrics struct {
+	programsRunning atomic.Int64
+	tasksActive     atomic.Int64
+
+	mu            sync.Mutex
+	latencies     map[string]*latencyHistogram // Builtin call latencies, by builtin name.
+	builtinErrors map[string]uint64            // Builtin failures, by builtin name.
+	errors        map[string]uint64            // Failed programs, by error kind.
+}
+
+type latencyHistogram struct {
+	counts []uint64 // Cumulative counts per bucket in latencyBuckets.
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics creates an empty collector.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latencies:     make(map[string]*latencyHistogram),
+		builtinErrors: make(map[string]uint64),
+		errors:        make(map[string]uint64),
+	}
+}
+
+// SetMetrics starts recording this executor's statistics into m. Passing nil stops recording.
+func (e *Executor) SetMetrics(m *Metrics) {
+	e.metrics = m
+	e.scheduler.metrics = m
+}
+
+func (m *Metrics) observeBuiltin(name string, elapsed time.Duration, err error) {
+	seconds := elapsed.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.latencies[name]
+	if !ok {
+		h = &latencyHistogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latencies[name] = h
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+	if err != nil {
+		m.builtinErrors[name]++
+	}
+}
+
+func (m *Metrics) observeError(err error) {
+	m.mu.Lock()
+	m.errors[errorKind(err)]++
+	m.mu.Unlock()
+}
+
+// errorKind classifies an error for the silk_errors_total metric.
+func errorKind(err error) string {
+	var builtinErr *utils.BuiltinError
+	var permissionErr *utils.PermissionError
+	var assertionErr *utils.AssertionError
+	switch {
+	case errors.As(err, &assertionErr):
+		return "assertion"
+	case errors.As(err, &permissionErr):
+		return "permission"
+	case errors.As(err, &builtinErr):
+		return "builtin"
+	default:
+		return "runtime"
+	}
+}
+
+// ServeHTTP writes the current metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the current metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	writeHeader(cw, "silk_programs_running", "gauge", "Number of programs currently executing.")
+	fmt.Fprintf(cw, "silk_programs_running %d\n", m.programsRunning.Load())
+	writeHeader(cw, "silk_parallel_tasks_active", "gauge", "Number of parallel branches currently running.")
+	fmt.Fprintf(cw, "silk_parallel_tasks_active %d\n", m.tasksActive.Load())
+
+	m.mu.Lock()
+	writeHeader(cw, "silk_builtin_call_duration_seconds", "histogram", "Latency of builtin function calls.")
+	for _, name := range sortedKeys(m.latencies) {
+		h := m.latencies[name]
+		label := escapeLabel(name)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(cw, "silk_builtin_call_duration_seconds_bucket{builtin=\"%s\",le=\"%g\"} %d\n", label, bound, h.counts[i])
+		}
+		fmt.Fprintf(cw, "silk_builtin_call_duration_seconds_bucket{builtin=\"%s\",le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(cw, "silk_builtin_call_duration_seconds_sum{builtin=\"%s\"} %g\n", label, h.sum)
+		fmt.Fprintf(cw, "silk_builtin_call_duration_seconds_count{builtin=\"%s\"} %d\n", label, h.count)
+	}
+	writeHeader(cw, "silk_builtin_errors_total", "counter", "Number of builtin calls that returned an error.")
+	for _, name := range sortedKeys(m.builtinErrors) {
+		fmt.Fprintf(cw, "silk_builtin_errors_total{builtin=\"%s\"} %d\n", escapeLabel(name), m.builtinErrors[name])
+	}
+	writeHeader(cw, "silk_errors_total", "counter", "Number of programs that failed, by error kind.")
+	for _, kind := range sortedKeys(m.errors) {
+		fmt.Fprintf(cw, "silk_errors_total{kind=\"%s\"} %d\n", escapeLabel(kind), m.errors[kind])
+	}
+	m.mu.Unlock()
+
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countingWriter tracks the bytes written and the first error for WriteTo.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
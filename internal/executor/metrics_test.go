@@ -0,0 +1,19 @@
+package executor
+
+import "testing"
+
+func TestMetricsCountEachRunOnce(t *testing.T) {
+	e := NewExecutor()
+	m := NewMetrics()
+	e.SetMetrics(m)
+	// A failure in a program nested in another is one failed run.
+	if _, err := e.Execute(program(program(call("undefined")))); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := m.errors["runtime"]; n != 1 {
+		t.Errorf("recorded %d runtime errors, want 1", n)
+	}
+	if n := m.programsRunning.Load(); n != 0 {
+		t.Errorf("%d programs running after Execute returned, want 0", n)
+	}
+}
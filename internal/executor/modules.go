@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// ModuleLoader finds the program named by an import path. Implementations must
+// be safe for concurrent use.
+type ModuleLoader interface {
+	LoadModule(path string) (*models.Program, error)
+}
+
+// ModuleRegistry is a ModuleLoader for programs registered by the host.
+type ModuleRegistry struct {
+	mu       sync.RWMutex
+	programs map[string]*models.Program
+}
+
+// NewModuleRegistry creates an empty module registry.
+func NewModuleRegistry() *ModuleRegistry {
+	return &ModuleRegistry{programs: make(map[string]*models.Program)}
+}
+
+// Register makes program importable as path, replacing any program registered
+// under the same path. Executors that already imported path keep their copy.
+func (r *ModuleRegistry) Register(path string, program *models.Program) {
+	r.mu.Lock()
+	r.programs[path] = program
+	r.mu.Unlock()
+}
+
+// LoadModule returns the program registered as path.
+func (r *ModuleRegistry) LoadModule(path string) (*models.Program, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	program, ok := r.programs[path]
+	if !ok {
+		return nil, fmt.Errorf("module %s not found", path)
+	}
+	return program, nil
+}
+
+// FSModuleLoader reads modules written by models.EncodeProgram from a file
+// system, such as an embed.FS. Import paths are slash-separated file names
+// within the file system.
+type FSModuleLoader struct {
+	FS fs.FS
+}
+
+// NewFSModuleLoader creates a loader that reads modules from fsys.
+func NewFSModuleLoader(fsys fs.FS) *FSModuleLoader {
+	return &FSModuleLoader{FS: fsys}
+}
+
+// NewFileModuleLoader creates a loader that reads modules from the directory dir.
+func NewFileModuleLoader(dir string) *FSModuleLoader {
+	return NewFSModuleLoader(os.DirFS(dir))
+}
+
+// LoadModule decodes the program stored in the file named path.
+func (l *FSModuleLoader) LoadModule(path string) (*models.Program, error) {
+	f, err := l.FS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	program, err := models.DecodeProgram(f)
+	if err != nil {
+		return nil, fmt.Errorf("module %s: %w", path, err)
+	}
+	return program, nil
+}
+
+// SetModuleLoader sets where import statements find modules. Without a loader,
+// imports fail.
+func (e *Executor) SetModuleLoader(loader ModuleLoader) {
+	e.loader = loader
+}
+
+// Module is the value bound by an aliased import. Each module runs once per
+// executor, in a scope of its own, and its exports are shared by every import.
+type Module struct {
+	Path      string
+	exports   map[string]interface{}              // Top-level functions and constants, by name.
+	constants map[string]*models.ConstDeclaration // Declarations of the exported constants.
+	importer  *Module                             // Module whose import is loading this one; nil once loaded.
+	done      chan struct{}                       // Closed when loading finishes.
+	err       error
+}
+
+// String renders the module for output.
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Path)
+}
+
+// currentModule returns the module whose top-level scope encloses env, or nil
+// for the main program.
+func (env *Environment) currentModule() *Module {
+	scope := env
+	for scope.parent != nil {
+		scope = scope.parent
+	}
+	return scope.module
+}
+
+// handleImport binds the exports of the imported module in env.
+func (e *Executor) handleImport(n *models.ImportStatement, env *Environment) error {
+	if n.Alias != "" && len(n.Names) > 0 {
+		return fmt.Errorf("import %s: cannot both alias the module and import names from it", n.Path)
+	}
+	m, err := e.importModule(n.Path, env.currentModule())
+	if err != nil {
+		return err
+	}
+	if n.Alias != "" {
+		return env.declare(n.Alias, m)
+	}
+
+	names := n.Names
+	if len(names) == 0 {
+		for name := range m.exports {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	for _, name := range names {
+		val, ok := m.exports[name]
+		if !ok {
+			return fmt.Errorf("module %s does not export %s", m.Path, name)
+		}
+		if decl, isConst := m.constants[name]; isConst {
+			err = env.importConst(decl, val)
+		} else {
+			err = env.declare(name, val)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importModule returns the module at path, running it first if this executor
+// has not imported it yet. from is the module performing the import, used to
+// detect import cycles.
+func (e *Executor) importModule(path string, from *Module) (*Module, error) {
+	for m := from; m != nil; m = m.importer {
+		if m.Path == path {
+			return nil, fmt.Errorf("import cycle: %s", importChain(from, path))
+		}
+	}
+
+	e.modulesMu.Lock()
+	if m, ok := e.modules[path]; ok {
+		e.modulesMu.Unlock()
+		<-m.done
+		if m.err != nil {
+			return nil, m.err
+		}
+		return m, nil
+	}
+	if e.loader == nil {
+		e.modulesMu.Unlock()
+		return nil, fmt.Errorf("cannot import %s: no module loader is set", path)
+	}
+	m := &Module{Path: path, importer: from, done: make(chan struct{})}
+	e.modules[path] = m
+	e.modulesMu.Unlock()
+
+	m.err = e.loadModule(m)
+	m.importer = nil
+	if m.err != nil {
+		// Forget the failure so that a later import can try again.
+		e.modulesMu.Lock()
+		delete(e.modules, path)
+		e.modulesMu.Unlock()
+	}
+	close(m.done)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m, nil
+}
+
+// loadModule runs the module's program in a new top-level scope and collects
+// its exports.
+func (e *Executor) loadModule(m *Module) error {
+	program, err := e.loader.LoadModule(m.Path)
+	if err != nil {
+		return fmt.Errorf("cannot import %s: %w", m.Path, err)
+	}
+	env := newEnvironment(nil)
+	env.module = m
+	for _, stmt := range program.Body {
+		if _, err := e.eval(stmt, env); err != nil {
+			return fmt.Errorf("module %s: %w", m.Path, loopSignalError(err))
+		}
+	}
+
+	m.exports = make(map[string]interface{})
+	m.constants = env.constants
+	for name, val := range env.variables {
+		if _, ok := val.(*Function); ok || env.constants[name] != nil {
+			m.exports[name] = val
+		}
+	}
+	return nil
+}
+
+// importChain describes the cycle formed by importing path from the module from.
+func importChain(from *Module, path string) string {
+	chain := []string{path}
+	for m := from; m.Path != path; m = m.importer {
+		chain = append(chain, m.Path)
+	}
+	slices.Reverse(chain)
+	return path + " -> " + strings.Join(chain, " -> ")
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"silk/internal/models"
+)
+
+func constDecl(name string, val models.Node) *models.ConstDeclaration {
+	return &models.ConstDeclaration{Variable: ref(name), Value: val}
+}
+
+func moduleExecutor() *Executor {
+	registry := NewModuleRegistry()
+	registry.Register("consts", program(constDecl("PI", num(3))))
+	e := NewExecutor()
+	e.SetModuleLoader(registry)
+	return e
+}
+
+func TestReimportConstant(t *testing.T) {
+	e := moduleExecutor()
+	imp := &models.ImportStatement{Path: "consts"}
+	val, err := e.Execute(program(imp, imp, ref("PI")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != int64(3) {
+		t.Errorf("PI = %v, want 3", val)
+	}
+	// A later run sees the constant from the first and may import it again.
+	if _, err := e.Execute(program(imp)); err != nil {
+		t.Errorf("importing again in a new run: %v", err)
+	}
+}
+
+func TestImportConflictingConstant(t *testing.T) {
+	e := moduleExecutor()
+	_, err := e.Execute(program(constDecl("PI", num(4)), &models.ImportStatement{Path: "consts"}))
+	if err == nil || !strings.Contains(err.Error(), "constant PI is already declared") {
+		t.Errorf("err = %v, want a redeclaration error", err)
+	}
+}
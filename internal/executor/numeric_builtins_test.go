@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"silk/internal/models"
+)
+
+func TestNumericBuiltinsWidenIntegers(t *testing.T) {
+	e := NewExecutor()
+	e.RegisterVectorBuiltins()
+	e.RegisterStatsBuiltins()
+	e.RegisterMatrixBuiltins()
+	e.RegisterFormatBuiltins()
+	half := &models.Number{Value: 2.5}
+	tests := []struct {
+		call *models.FunctionCall
+		want interface{}
+	}{
+		{call("mean", list(num(1), num(2), num(3))), 2.0},
+		{call("sum", list(num(1), num(2), num(3))), int64(6)},
+		{call("sum", list(num(1), half)), 3.5},
+		{call("dot", list(num(1), num(2)), list(num(3), half)), 8.0},
+		{call("min", list(num(3), num(1), half)), int64(1)},
+		{call("max", list(num(3), num(1), half)), int64(3)},
+		{call("percentile", list(num(1), num(2), num(3)), num(50)), 2.0},
+		{call("formatNumber", num(1234)), "1,234"},
+		{call("formatCurrency", num(5)), "$5.00"},
+		{call("formatDate", num(86400), str("iso")), "1970-01-02T00:00:00Z"},
+		{call("unix", num(86400)), 86400.0},
+		{call("duration", num(5)), 5 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := e.Execute(tt.call)
+		if err != nil {
+			t.Errorf("%s: %v", tt.call.Name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v (%T), want %v (%T)", tt.call.Name, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+func TestAllocationBounds(t *testing.T) {
+	e := NewExecutor()
+	e.RegisterStatsBuiltins()
+	e.RegisterMatrixBuiltins()
+	for i, c := range []*models.FunctionCall{
+		call("histogram", list(num(1), &models.Number{Value: math.Inf(1)}), num(4)),
+		call("histogram", list(num(1), &models.Number{Value: math.NaN()}), num(4)),
+		call("histogram", list(num(1), num(2)), num(1<<40)),
+		call("percentile", list(num(1), num(2)), &models.Number{Value: math.NaN()}),
+		call("semaphore", num(1<<40)),
+		call("matrix", num(1<<32), num(1<<32), list()),
+		call("identity", num(1<<40)),
+	} {
+		if _, err := e.Execute(c); err == nil {
+			t.Errorf("call %d to %s succeeded, want an error", i, c.Name)
+		}
+	}
+}
+
+func TestLooseCompareWidensIntegers(t *testing.T) {
+	e := NewExecutor()
+	e.SetCoercion(CoercionLoose)
+	for i, c := range []*models.ComparisonExpression{
+		{Left: num(1), Operator: "<", Right: str("2")},
+		{Left: str("10"), Operator: ">", Right: num(9)},
+	} {
+		if got, err := e.Execute(c); err != nil || got != true {
+			t.Errorf("comparison %d = %v, %v; want true", i, got, err)
+		}
+	}
+}
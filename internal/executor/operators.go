@@ -0,0 +1,70 @@
+package executor
+
+import "fmt"
+
+// Struct types overload an operator by declaring a method named after it, such
+// as "+" or "==". The method receives the right operand and its receiver is
+// the left operand. Comparisons a type does not overload are derived from "=="
+// and "<" where possible:
+//
+//	a != b  is  !(a == b)
+//	a > b   is  b < a
+//	a <= b  is  !(b < a)
+//	a >= b  is  !(a < b)
+//
+// and "==" and "!=" also use the right operand's method when only it has one.
+
+// overloadedBinary applies an arithmetic operator overloaded by the left
+// operand, reporting false if it has no method for the operator.
+func (e *Executor) overloadedBinary(site callSite, operator string, left, right interface{}) (interface{}, bool, error) {
+	fn, ok := e.boundMethod(left, operator)
+	if !ok {
+		return nil, false, nil
+	}
+	val, err := e.callFunctionIn(site, fn, []interface{}{right})
+	return val, true, err
+}
+
+// overloadedCompare applies a comparison operator overloaded by one of the
+// operands, directly or through the derivations above, reporting false if
+// neither operand overloads it.
+func (e *Executor) overloadedCompare(site callSite, operator string, left, right interface{}) (interface{}, bool, error) {
+	call := func(op string, receiver, arg interface{}, negate bool) (interface{}, bool, error) {
+		fn, ok := e.boundMethod(receiver, op)
+		if !ok {
+			return nil, false, nil
+		}
+		val, err := e.callFunctionIn(site, fn, []interface{}{arg})
+		if err != nil {
+			return nil, true, err
+		}
+		b, ok := val.(bool)
+		if !ok {
+			return nil, true, fmt.Errorf("operator method %s must return a bool, got %v", fn.Name, val)
+		}
+		return b != negate, true, nil
+	}
+
+	if val, ok, err := call(operator, left, right, false); ok {
+		return val, ok, err
+	}
+	switch operator {
+	case "==":
+		return call("==", right, left, false)
+	case "!=":
+		if val, ok, err := call("==", left, right, true); ok {
+			return val, ok, err
+		}
+		if val, ok, err := call("!=", right, left, false); ok {
+			return val, ok, err
+		}
+		return call("==", right, left, true)
+	case ">":
+		return call("<", right, left, false)
+	case "<=":
+		return call("<", right, left, true)
+	case ">=":
+		return call("<", left, right, true)
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+)
+
+// SetOutput directs the output of print-style builtins and RenderError to the
+// given writers, so hosts can capture each run's output separately. A nil
+// writer leaves the corresponding stream unchanged.
+func (e *Executor) SetOutput(stdout, stderr io.Writer) {
+	if stdout != nil {
+		e.stdout = stdout
+	}
+	if stderr != nil {
+		e.stderr = stderr
+	}
+}
+
+// Stdout returns the writer used for program output.
+func (e *Executor) Stdout() io.Writer {
+	return e.stdout
+}
+
+// Stderr returns the writer used for diagnostics.
+func (e *Executor) Stderr() io.Writer {
+	return e.stderr
+}
+
+// RenderError writes err to the executor's Stderr in the standard format.
+func (e *Executor) RenderError(err error) {
+	e.writeOutput(e.stderr, func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "error: %v\n", err)
+		return err
+	})
+}
+
+// writeOutput serializes writes to the output streams so that lines printed by
+// parallel branches are not interleaved mid-line.
+func (e *Executor) writeOutput(w io.Writer, write func(io.Writer) error) error {
+	e.outputMu.Lock()
+	defer e.outputMu.Unlock()
+	return write(w)
+}
@@ -0,0 +1,483 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"silk/internal/models"
+	"silk/internal/utils"
+)
+
+// handleParallelForLoop runs the iterations of a parallel loop on the
+// scheduler and collects their values in order.
+func (e *Executor) handleParallelForLoop(n *models.ParallelForLoop, env *Environment) (interface{}, error) {
+	collection, err := e.eval(n.Collection, env)
+	if err != nil {
+		return nil, err
+	}
+	keys, vals, err := e.loopItems(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	cancel := e.newCancelScope(env.cancel, 0)
+	if n.Priority != 0 {
+		cancel.priority = n.Priority
+	}
+	group := e.scheduler.group()
+	group.limit = n.MaxConcurrency
+	group.priority = cancel.priority
+	boundQueue(group, n.QueueSize, n.Overflow)
+	sched := e.beginSchedule(n, len(vals))
+	if sched.serial() {
+		group.limit = 1
+	}
+	results := make([]interface{}, len(vals))
+	var errs []*utils.BranchError
+	var mu sync.Mutex
+	completed := 0
+	overflowed := false
+	for _, i := range sched.order(len(vals)) {
+		i := i
+		accepted := group.Go(func() {
+			mu.Lock()
+			skip := n.FailFast && len(errs) > 0
+			mu.Unlock()
+			if skip {
+				return
+			}
+			val, err := protect(n, func() (interface{}, error) {
+				return e.parallelIteration(n, env, cancel, keys[i], vals[i])
+			})
+			// Each goroutine writes only its own element.
+			results[i] = val
+			mu.Lock()
+			if err != nil && !errors.Is(err, errCancelled) {
+				errs = append(errs, &utils.BranchError{Index: i, Err: branchSignalError(err)})
+				if n.FailFast {
+					cancel.cancel()
+				}
+			}
+			completed++
+			e.reportProgress(ProgressParallel, n, completed, len(vals), false)
+			mu.Unlock()
+			sched.done(i)
+		})
+		if !accepted && n.Overflow == models.OverflowError {
+			overflowed = true
+			cancel.cancel()
+			break
+		}
+	}
+	group.Wait()
+	sched.end(len(vals))
+	e.reportProgress(ProgressParallel, n, completed, len(vals), true)
+	switch {
+	case env.cancel.interrupted():
+		return nil, ErrInterrupted
+	case env.cancel.cancelled():
+		return nil, errCancelled
+	case overflowed:
+		return nil, queueFull(n.QueueSize)
+	case len(errs) == 0:
+		return results, nil
+	case n.FailFast:
+		return nil, errs[0].Err
+	default:
+		return nil, parallelError(errs)
+	}
+}
+
+// ErrQueueFull is wrapped by the error of a parallel construct with an
+// Overflow of models.OverflowError whose queue was full.
+var ErrQueueFull = errors.New("parallel queue full")
+
+// boundQueue applies the QueueSize and Overflow of a parallel construct to
+// its group.
+func boundQueue(group *taskGroup, size int, overflow models.Overflow) {
+	group.maxHeld = size
+	group.block = overflow != models.OverflowDrop && overflow != models.OverflowError
+}
+
+// queueFull is the error of a construct that could not queue a branch.
+func queueFull(size int) error {
+	return fmt.Errorf("%w: more than %d branches waiting to start", ErrQueueFull, size)
+}
+
+// parallelError reports the errors of the failed branches of a parallel
+// construct, collected as they failed, in the order of the branches.
+func parallelError(errs []*utils.BranchError) error {
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return &utils.ParallelError{Errors: errs}
+}
+
+// loopItems returns the keys and elements a ForEach loop over collection
+// would visit, reading an iterator to its end.
+func (e *Executor) loopItems(collection interface{}) ([]interface{}, []interface{}, error) {
+	var keys, vals []interface{}
+	if it, ok := e.iterator(collection); ok {
+		for {
+			val, ok, err := it.Next()
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				return keys, vals, nil
+			}
+			keys = append(keys, float64(len(keys)))
+			vals = append(vals, val)
+		}
+	}
+	all, lookup, err := loopElements(collection)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, key := range all {
+		if val, ok := lookup(i); ok {
+			keys = append(keys, key)
+			vals = append(vals, val)
+		}
+	}
+	return keys, vals, nil
+}
+
+// parallelIteration runs one iteration of a parallel loop in a scope of its
+// own, under cancel, and returns the value of its last statement.
+func (e *Executor) parallelIteration(n *models.ParallelForLoop, env *Environment, cancel *cancelScope, key, val interface{}) (interface{}, error) {
+	scope := newEnvironment(env)
+	scope.cancel = cancel
+	if n.Key != nil {
+		scope.define(n.Key.Name, key)
+	}
+	if n.Value != nil {
+		scope.define(n.Value.Name, val)
+	}
+	var result interface{}
+	for _, stmt := range n.Body {
+		if isComment(stmt) {
+			continue
+		}
+		res, err := e.eval(stmt, scope)
+		switch err {
+		case nil:
+			result = res
+		case errContinue:
+			return nil, nil
+		case errBreak:
+			return nil, errors.New("break is not allowed in a parallel loop")
+		default:
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// handleRace runs the statements of n on goroutines of their own rather than
+// on the scheduler, so that all of them start at once however busy the
+// scheduler is. The losers are cancelled, which the builtins they are blocked
+// in see as the end of their context.
+func (e *Executor) handleRace(n *models.Race, env *Environment) (interface{}, error) {
+	if len(n.Body) == 0 {
+		return nil, nil
+	}
+	cancel := e.newCancelScope(env.cancel, 0)
+	defer cancel.stop()
+
+	type outcome struct {
+		index int
+		val   interface{}
+		err   error
+	}
+	done := make(chan outcome, len(n.Body))
+	for i, stmt := range n.Body {
+		i, stmt := i, stmt
+		go func() {
+			val, err := protect(stmt, func() (interface{}, error) {
+				scope := newEnvironment(env)
+				scope.cancel = cancel
+				return e.eval(stmt, scope)
+			})
+			done <- outcome{i, val, err}
+		}()
+	}
+
+	// Once a statement succeeds, cancel the others and wait for them to stop,
+	// so that none is left running after the race.
+	var errs []*utils.BranchError
+	var winner *outcome
+	for range n.Body {
+		out := <-done
+		switch {
+		case winner != nil:
+		case out.err == nil:
+			winner = &out
+			cancel.cancel()
+		case !errors.Is(out.err, errCancelled):
+			errs = append(errs, &utils.BranchError{Index: out.index, Err: branchSignalError(out.err)})
+		}
+	}
+	switch {
+	case winner != nil:
+		return winner.val, nil
+	case env.cancel.interrupted():
+		return nil, ErrInterrupted
+	case env.cancel.cancelled():
+		return nil, errCancelled
+	}
+	return nil, parallelError(errs)
+}
+
+// handlePipeline runs the stages of n on goroutines of their own rather than
+// on the scheduler: a stage waits on its neighbours, so running stages one
+// after another on the goroutine waiting for the pipeline could deadlock. The
+// pipeline returns only once every stage has stopped.
+func (e *Executor) handlePipeline(n *models.Pipeline, env *Environment) (interface{}, error) {
+	source, err := e.eval(n.Source, env)
+	if err != nil {
+		return nil, err
+	}
+	stages := make([]*Function, len(n.Stages))
+	for i, stage := range n.Stages {
+		v, err := e.eval(stage, env)
+		if err != nil {
+			return nil, err
+		}
+		if stages[i], err = e.functionArg("pipeline", v); err != nil {
+			return nil, err
+		}
+	}
+
+	cancel := e.newCancelScope(env.cancel, 0)
+	defer cancel.stop()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failure error
+	fail := func(err error) {
+		mu.Lock()
+		if failure == nil && !errors.Is(err, errCancelled) {
+			failure = err
+		}
+		mu.Unlock()
+		cancel.cancel()
+	}
+
+	buffer := max(n.Buffer, 1)
+	elements := make(chan interface{}, buffer)
+	wg.Add(1 + len(stages))
+	go func() {
+		defer wg.Done()
+		defer close(elements)
+		_, err := protect(n, func() (interface{}, error) {
+			return nil, e.pipelineSource(source, elements, cancel)
+		})
+		if err != nil {
+			fail(err)
+		}
+	}()
+	in := elements
+	for _, stage := range stages {
+		stage, from, to := stage, in, make(chan interface{}, buffer)
+		go func() {
+			defer wg.Done()
+			defer close(to)
+			// After a failure, keep reading so that earlier stages can finish.
+			for val := range from {
+				if cancel.cancelled() {
+					continue
+				}
+				res, err := protect(n, func() (interface{}, error) {
+					return e.callFunctionIn(callSite{cancel: cancel, depth: env.depth}, stage, []interface{}{val})
+				})
+				if err != nil {
+					fail(err)
+					continue
+				}
+				select {
+				case to <- res:
+				case <-cancel.ctx.Done():
+				}
+			}
+		}()
+		in = to
+	}
+
+	results := []interface{}{}
+	for val := range in {
+		results = append(results, val)
+	}
+	wg.Wait()
+	switch {
+	case env.cancel.interrupted():
+		return nil, ErrInterrupted
+	case env.cancel.cancelled():
+		return nil, errCancelled
+	case failure != nil:
+		return nil, failure
+	}
+	return results, nil
+}
+
+// pipelineSource sends the elements of source to out until they run out or
+// cancel is cancelled.
+func (e *Executor) pipelineSource(source interface{}, out chan<- interface{}, cancel *cancelScope) error {
+	send := func(val interface{}) bool {
+		select {
+		case out <- val:
+			return true
+		case <-cancel.ctx.Done():
+			return false
+		}
+	}
+	if it, ok := e.iterator(source); ok {
+		for {
+			val, ok, err := it.Next()
+			if err != nil || !ok {
+				return err
+			}
+			if !send(val) {
+				return nil
+			}
+		}
+	}
+	_, vals, err := e.loopItems(source)
+	if err != nil {
+		return err
+	}
+	for _, val := range vals {
+		if !send(val) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// registerParallelBuiltins registers the concurrent counterparts of the
+// collection builtins. Unlike map and reduce, they take the function first,
+// and parallelReduce always takes an initial value:
+//
+//	parallelMap(fn, list)       list of fn(element) for every element, calling fn
+//	                            on the elements concurrently
+//	parallelReduce(fn, list, init)
+//	                            list combined by fn(acc, element) starting from
+//	                            init, as by reduce, reducing parts of the list
+//	                            concurrently
+//
+// The results keep the order of the list. Once a call fails, calls not yet
+// started are skipped, and the error of the earliest failing element is
+// returned.
+//
+// parallelReduce splits the list into as many runs of neighbouring elements
+// as the executor may run goroutines, reduces each run, and then combines the
+// results of the runs in order, starting from init. It therefore
+// gives the same result as reduce only if fn is associative: fn(fn(a, b), c)
+// must equal fn(a, fn(b, c)). Order is kept, so fn need not be commutative,
+// and init is used once, so it need not be an identity of fn. The executor
+// cannot check this contract; with any other fn, such as subtraction, the
+// result depends on how the list was split.
+func (e *Executor) registerParallelBuiltins() {
+	e.RegisterContextBuiltin("parallelMap", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		fn, list, err := e.functionListArgs("parallelMap", args)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, len(list))
+		errs := make([]error, len(list))
+		var failed atomic.Bool
+		group := e.scheduler.group()
+		for i, elem := range list {
+			i, elem := i, elem
+			group.Go(func() {
+				if failed.Load() {
+					return
+				}
+				// Each goroutine writes only its own elements.
+				out[i], errs[i] = protect(nil, func() (interface{}, error) {
+					return e.callFunctionCtx(ctx, fn, []interface{}{elem})
+				})
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			})
+		}
+		group.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	})
+
+	e.RegisterContextBuiltin("parallelReduce", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("parallelReduce", args, 3); err != nil {
+			return nil, err
+		}
+		fn, list, err := e.functionListArgs("parallelReduce", args[:2])
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return args[2], nil
+		}
+		runs := min(len(list), e.scheduler.size())
+		partials := make([]interface{}, runs)
+		errs := make([]error, runs)
+		var failed atomic.Bool
+		group := e.scheduler.group()
+		for i := 0; i < runs; i++ {
+			i, run := i, list[i*len(list)/runs:(i+1)*len(list)/runs]
+			group.Go(func() {
+				if failed.Load() {
+					return
+				}
+				// Each goroutine writes only its own elements.
+				partials[i], errs[i] = protect(nil, func() (interface{}, error) {
+					return e.fold(ctx, fn, run[0], run[1:])
+				})
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			})
+		}
+		group.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+		return e.fold(ctx, fn, args[2], partials)
+	})
+}
+
+// functionListArgs checks the arguments of a builtin taking a function and
+// then a list.
+func (e *Executor) functionListArgs(name string, args []interface{}) (*Function, []interface{}, error) {
+	if err := expectArgs(name, args, 2); err != nil {
+		return nil, nil, err
+	}
+	fn, err := e.functionArg(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := e.sequenceArg(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return fn, list, nil
+}
+
+// protect calls fn on a goroutine the executor started for node, converting a
+// panic into a *utils.PanicError rather than letting it end the process.
+func protect(node models.Node, fn func() (interface{}, error)) (val interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			val, err = nil, &utils.PanicError{Node: node, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
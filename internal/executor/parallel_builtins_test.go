@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"silk/internal/models"
+)
+
+func TestParallelMap(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(4)
+	val, err := e.Execute(program(
+		function("double", []string{"x"}, ret(binop(ref("x"), "*", num(2)))),
+		call("parallelMap", ref("double"), &models.ArrayLiteral{Elements: []models.Node{num(1), num(2), num(3), num(4), num(5)}}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(2), int64(4), int64(6), int64(8), int64(10)}
+	if !reflect.DeepEqual(val, want) {
+		t.Errorf("parallelMap = %v, want %v", val, want)
+	}
+}
+
+func TestParallelReduce(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(3)
+	e.SetCoercion(CoercionLoose)
+	letters := &models.ArrayLiteral{}
+	for _, s := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		letters.Elements = append(letters.Elements, str(s))
+	}
+	// Concatenation is associative but not commutative, so the runs must be
+	// combined in order.
+	val, err := e.Execute(program(
+		function("concat", []string{"acc", "x"}, ret(binop(ref("acc"), "+", ref("x")))),
+		call("parallelReduce", ref("concat"), letters, str(">")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != ">abcdefg" {
+		t.Errorf("parallelReduce = %v, want >abcdefg", val)
+	}
+}
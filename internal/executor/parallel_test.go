@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"silk/internal/models"
+)
+
+func TestReturnInBranchReportsFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		node models.Node
+	}{
+		{"block", &models.ParallelBlock{Body: []models.Node{ret(num(1)), call("fail")}}},
+		{"for", parallelFor(list(num(1), num(2)), ret(num(1)), call("fail"))},
+		{"race", &models.Race{Body: []models.Node{ret(num(1)), call("fail")}}},
+		{"graph", &models.TaskGraph{Tasks: []*models.GraphTask{
+			{Name: "a", Body: []models.Node{ret(num(1))}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExecutor()
+			e.RegisterBuiltin("fail", func(args []interface{}) (interface{}, error) {
+				return nil, errors.New("boom")
+			})
+			// A return in a branch must not end f with the branch's value.
+			val, err := e.Execute(program(function("f", nil, tt.node), call("f")))
+			if err == nil {
+				t.Errorf("f() = %v, want an error", val)
+			}
+		})
+	}
+}
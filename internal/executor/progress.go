@@ -0,0 +1,49 @@
+package executor
+
+import "silk/internal/models"
+
+// ProgressKind identifies the kind of construct reporting progress.
+type ProgressKind string
+
+const (
+	ProgressLoop     ProgressKind = "loop"
+	ProgressParallel ProgressKind = "parallel"
+)
+
+// Progress describes how far a loop or parallel block has advanced.
+type Progress struct {
+	Kind      ProgressKind
+	Node      models.Node // The loop or parallel block reporting progress.
+	Completed int         // Iterations run or branches finished so far.
+	Total     int         // Known total, or -1 when it cannot be determined up front.
+	Done      bool        // Set on the final report for the construct.
+}
+
+// Percent returns the completion percentage, or -1 when the total is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total < 0 {
+		return -1
+	}
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Completed) / float64(p.Total) * 100
+}
+
+// ProgressFunc receives progress reports. Reports from a single construct are
+// delivered in order, but reports from parallel branches may arrive concurrently.
+type ProgressFunc func(Progress)
+
+// SetProgressHook installs a callback that is notified as loops iterate and
+// parallel branches complete. Passing nil disables reporting.
+func (e *Executor) SetProgressHook(fn ProgressFunc) {
+	e.progress = fn
+}
+
+// reportProgress forwards a progress report to the hook, if one is installed.
+func (e *Executor) reportProgress(kind ProgressKind, node models.Node, completed, total int, done bool) {
+	if e.progress == nil {
+		return
+	}
+	e.progress(Progress{Kind: kind, Node: node, Completed: completed, Total: total, Done: done})
+}
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueHandlersShareSlots(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(2)
+	q := NewMemoryQueue()
+	e.RegisterQueue("q", q)
+	var running, most atomic.Int64
+	e.RegisterBuiltin("work", func(args []interface{}) (interface{}, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	})
+	_, err := e.Execute(program(
+		function("handle", []string{"m"}, call("work")),
+		call("subscribe", str("q"), str("jobs"), ref("handle")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The host delivers messages from goroutines of its own.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Publish("jobs", i)
+		}()
+	}
+	wg.Wait()
+	if n := most.Load(); n > 2 {
+		t.Errorf("%d handlers ran at once, want at most 2", n)
+	}
+}
+
+func TestQueuePublishFromHandler(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(1)
+	e.RegisterQueue("q", NewMemoryQueue())
+	got := make(chan interface{}, 1)
+	e.RegisterBuiltin("done", func(args []interface{}) (interface{}, error) {
+		got <- args[0]
+		return nil, nil
+	})
+	// A handler that publishes must not wait for the slot its publisher holds.
+	go e.Execute(program(
+		function("first", []string{"m"}, call("publish", str("q"), str("b"), ref("m"))),
+		function("second", []string{"m"}, call("done", ref("m"))),
+		call("subscribe", str("q"), str("a"), ref("first")),
+		call("subscribe", str("q"), str("b"), ref("second")),
+		call("publish", str("q"), str("a"), str("hi")),
+	))
+	select {
+	case m := <-got:
+		if m != "hi" {
+			t.Errorf("message = %v, want hi", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publishing from a handler deadlocked")
+	}
+}
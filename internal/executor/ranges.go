@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"silk/internal/models"
+)
+
+// Range is the value of a Range node: the numbers Start, Start+Step, ... up to
+// End. Iterating a range always starts from the beginning, so a range can be
+// looped over any number of times.
+type Range struct {
+	Start     float64
+	End       float64
+	Step      float64
+	Exclusive bool // Whether End itself is left out.
+}
+
+// evalRange evaluates a range node, using eval for its operands.
+func evalRange(n *models.Range, eval func(models.Node) (interface{}, error)) (interface{}, error) {
+	var bounds [3]float64
+	for i, node := range []models.Node{n.Start, n.End, n.Step} {
+		if node == nil {
+			continue
+		}
+		val, err := eval(node)
+		if err != nil {
+			return nil, err
+		}
+		num, ok := toFloat(val)
+		if !ok {
+			return nil, fmt.Errorf("range bounds must be numbers, got %v", val)
+		}
+		bounds[i] = num
+	}
+	r := &Range{Start: bounds[0], End: bounds[1], Step: bounds[2], Exclusive: n.Exclusive}
+	switch {
+	case n.Step == nil && r.End < r.Start:
+		r.Step = -1
+	case n.Step == nil:
+		r.Step = 1
+	case r.Step == 0:
+		return nil, errors.New("range step must not be zero")
+	}
+	return r, nil
+}
+
+// String renders the range, giving the step only if it is not the default.
+func (r *Range) String() string {
+	op := ".."
+	if r.Exclusive {
+		op = "..<"
+	}
+	s := fmt.Sprint(r.Start) + op + fmt.Sprint(r.End)
+	step := 1.0
+	if r.End < r.Start {
+		step = -1
+	}
+	if r.Step != step {
+		s += fmt.Sprintf(" step %v", r.Step)
+	}
+	return s
+}
+
+// count returns the number of values in the range. A step leading away from
+// End gives an empty range.
+func (r *Range) count() int {
+	// Allow for rounding error in fractional steps, so that 0..1 step 0.1
+	// ends at 1.
+	const epsilon = 1e-9
+	steps := (r.End - r.Start) / r.Step
+	if r.Exclusive {
+		return int(max(math.Ceil(steps-epsilon), 0))
+	}
+	if steps < -epsilon {
+		return 0
+	}
+	return int(math.Floor(steps+epsilon)) + 1
+}
+
+// at returns the value at index i of the range.
+func (r *Range) at(i int) float64 {
+	return r.Start + float64(i)*r.Step
+}
+
+// rangeIterator produces the values of a range in order.
+type rangeIterator struct {
+	r    *Range
+	next int
+}
+
+func (it *rangeIterator) Next() (interface{}, bool, error) {
+	if it.next >= it.r.count() {
+		return nil, false, nil
+	}
+	val := it.r.at(it.next)
+	it.next++
+	return val, true, nil
+}
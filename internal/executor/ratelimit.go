@@ -0,0 +1,70 @@
+package executor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"silk/internal/models"
+)
+
+// rateLimiter enforces a models.RateLimit. Tokens may go negative: each
+// caller takes a token at once and waits until the bucket would have refilled
+// it, so waiting callers are served in the order they arrived.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second.
+	burst  float64 // Most tokens the bucket holds.
+	tokens float64
+	last   time.Time // When tokens was last brought up to date.
+}
+
+// newRateLimiter creates a full bucket for limit, or returns nil if limit
+// does not restrict anything.
+func newRateLimiter(limit *models.RateLimit) *rateLimiter {
+	if limit == nil || limit.PerSecond <= 0 {
+		return nil
+	}
+	burst := float64(max(limit.Burst, 1))
+	return &rateLimiter{rate: limit.PerSecond, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// acting on it.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// SetRateLimit limits how often programs may call the builtin or user-defined
+// function named name, such as 10 calls a second to fetchUser. The limit is
+// shared by all the calls the executor makes, and calls beyond it wait for
+// their turn. A nil limit or one with a PerSecond of zero or less removes it.
+func (e *Executor) SetRateLimit(name string, limit *models.RateLimit) {
+	l := newRateLimiter(limit)
+	if l == nil {
+		delete(e.rateLimits, name)
+		return
+	}
+	if e.rateLimits == nil {
+		e.rateLimits = make(map[string]*rateLimiter)
+	}
+	e.rateLimits[name] = l
+}
+
+// throttle waits for a token from l, if l is not nil, on behalf of code
+// running under the timeout cancel.
+func (e *Executor) throttle(l *rateLimiter, cancel *cancelScope) error {
+	if l == nil {
+		return nil
+	}
+	return e.sleep(l.reserve(), cancel)
+}
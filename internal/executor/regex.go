@@ -0,0 +1,174 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"silk/internal/models"
+)
+
+// Regex is a compiled regular expression, the value of a Regex node or of the
+// regex builtin.
+type Regex struct {
+	Pattern string
+	Flags   string
+	re      *regexp.Regexp
+}
+
+// regexFlags maps each regex flag to the inline flag of Go's regexp syntax.
+var regexFlags = map[rune]string{'i': "i", 'm': "m", 's': "s"}
+
+// compileRegex compiles pattern with the given flags.
+func compileRegex(pattern, flags string) (*Regex, error) {
+	var inline strings.Builder
+	for _, f := range flags {
+		flag, ok := regexFlags[f]
+		if !ok {
+			return nil, fmt.Errorf("unknown regex flag %q", f)
+		}
+		inline.WriteString(flag)
+	}
+	source := pattern
+	if inline.Len() > 0 {
+		source = "(?" + inline.String() + ")" + pattern
+	}
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex /%s/: %w", pattern, err)
+	}
+	return &Regex{Pattern: pattern, Flags: flags, re: re}, nil
+}
+
+// regexLiteral returns the compiled regex of n, compiling it only the first
+// time, so that a literal inside a loop is compiled once. Patterns built at
+// run time are not cached, which would let a program grow the cache without
+// bound.
+func (e *Executor) regexLiteral(n *models.Regex) (*Regex, error) {
+	if r, ok := e.regexes.Load(n); ok {
+		return r.(*Regex), nil
+	}
+	r, err := compileRegex(n.Pattern, n.Flags)
+	if err != nil {
+		return nil, err
+	}
+	e.regexes.Store(n, r)
+	return r, nil
+}
+
+// String renders the regex as a literal, as in "/^a+$/i".
+func (r *Regex) String() string {
+	return "/" + r.Pattern + "/" + r.Flags
+}
+
+// GobEncode encodes the regex as a literal, so that regexes can be sent to
+// remote workers.
+func (r *Regex) GobEncode() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// GobDecode decodes a regex encoded by GobEncode.
+func (r *Regex) GobDecode(data []byte) error {
+	text := strings.TrimPrefix(string(data), "/")
+	i := strings.LastIndex(text, "/")
+	if i < 0 {
+		return fmt.Errorf("invalid regex %q", data)
+	}
+	compiled, err := compileRegex(text[:i], text[i+1:])
+	if err != nil {
+		return err
+	}
+	*r = *compiled
+	return nil
+}
+
+// evalMatches evaluates a matches expression, using eval for its operands.
+func evalMatches(n *models.MatchesExpression, eval func(models.Node) (interface{}, error)) (interface{}, error) {
+	subject, err := eval(n.Subject)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := subject.(string)
+	if !ok {
+		return nil, fmt.Errorf("=~ expects a string to match, got %v", subject)
+	}
+	pattern, err := eval(n.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	r, err := regexArg("=~", pattern)
+	if err != nil {
+		return nil, err
+	}
+	return r.match(text), nil
+}
+
+// regexArg converts a regex or the text of one to a regex.
+func regexArg(name string, v interface{}) (*Regex, error) {
+	switch v := v.(type) {
+	case *Regex:
+		return v, nil
+	case string:
+		return compileRegex(v, "")
+	default:
+		return nil, fmt.Errorf("%s: expected a regex or string, got %v", name, v)
+	}
+}
+
+// match returns the leftmost match of the regex in text, or nil if there is
+// none. The match is a map of:
+//
+//	"match"   the matched text
+//	"index"   the position of the match, in bytes
+//	"groups"  the text of each capture group in order, null for groups that
+//	          did not take part in the match
+//	"named"   a map from the name of each named group to its text
+func (r *Regex) match(text string) interface{} {
+	loc := r.re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil
+	}
+	groups := make([]interface{}, r.re.NumSubexp())
+	named := make(map[string]interface{})
+	for i, name := range r.re.SubexpNames()[1:] {
+		start, end := loc[2*(i+1)], loc[2*(i+1)+1]
+		if start >= 0 {
+			groups[i] = text[start:end]
+		}
+		if name != "" {
+			named[name] = groups[i]
+		}
+	}
+	return map[string]interface{}{
+		"match":  text[loc[0]:loc[1]],
+		"index":  float64(loc[0]),
+		"groups": groups,
+		"named":  named,
+	}
+}
+
+// registerRegexBuiltins registers builtins for regular expressions:
+//
+//	regex(pattern[, flags])   regex compiled from text, with flags as for a Regex literal
+func (e *Executor) registerRegexBuiltins() {
+	e.RegisterBuiltin("regex", func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("regex expects 1 or 2 arguments, but got %d", len(args))
+		}
+		pattern, err := stringArg("regex", args[0])
+		if err != nil {
+			return nil, err
+		}
+		flags := ""
+		if len(args) == 2 {
+			if flags, err = stringArg("regex", args[1]); err != nil {
+				return nil, err
+			}
+		}
+		r, err := compileRegex(pattern, flags)
+		if err != nil {
+			return nil, fmt.Errorf("regex: %w", err)
+		}
+		return r, nil
+	})
+}
@@ -0,0 +1,373 @@
+package executor
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"silk/internal/models"
+)
+
+func init() {
+	// Composite values that may appear in a task's environment or result.
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register(Tuple{})
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register(new(big.Int))
+	gob.Register(&Decimal{})
+	gob.Register(&Regex{})
+	gob.Register(&Range{})
+	gob.Register([]float64{})
+	gob.Register(&Matrix{})
+	gob.Register(&Struct{})
+	gob.Register(&Enum{})
+	gob.Register(&EnumMember{})
+	gob.Register(&remoteFunction{})
+}
+
+// RemoteTask is a subtree shipped to a worker together with the slice of the
+// environment it reads and the user-defined functions, struct types, and
+// methods it may use. Function values in the environment, such as closures
+// held in variables, travel with the variables their bodies read; builtins
+// travel by name and must be registered on the worker.
+type RemoteTask struct {
+	Node      models.Node
+	Env       map[string]interface{}
+	Functions map[string]*models.FunctionDeclaration
+	Structs   map[string]*models.StructDeclaration
+	Methods   []*models.MethodDeclaration
+}
+
+// RemoteResult is the outcome of a RemoteTask. Errors travel as text because
+// arbitrary error values cannot be serialized.
+type RemoteResult struct {
+	Value interface{}
+	Error string
+}
+
+// Dispatcher runs tasks on remote workers. When set on an Executor, the branches
+// of ParallelBlock nodes and the tasks of TaskGraph nodes are dispatched instead
+// of run on local goroutines.
+// Implementations must be safe for concurrent use.
+type Dispatcher interface {
+	Dispatch(task *RemoteTask) (*RemoteResult, error)
+}
+
+// SetDispatcher routes parallel branches and graph tasks to remote workers.
+// Passing nil restores local execution. Assignments made by a remote branch or
+// task are not copied back into this executor's environment; only its result
+// and error are returned.
+func (e *Executor) SetDispatcher(d Dispatcher) {
+	e.dispatcher = d
+}
+
+// remoteFunction is a function value sent to or from a worker.
+type remoteFunction struct {
+	Name     string
+	Builtin  bool                        // Whether Name is a builtin registered on the receiving side.
+	Decl     *models.FunctionDeclaration // Parameters and body of a user-defined function.
+	Captured map[string]interface{}      // Variables the body reads from the scope the function was created in.
+	Self     string                      // Captured name bound to the function itself, for a recursive closure.
+}
+
+// RunTask executes a task received from a dispatcher, binding its environment
+// slice and functions before evaluating the subtree.
+func (e *Executor) RunTask(task *RemoteTask) *RemoteResult {
+	for name, fn := range task.Functions {
+		e.RegisterFunction(name, fn)
+	}
+	e.declsMu.Lock()
+	for name, decl := range task.Structs {
+		e.structs[name] = decl
+	}
+	e.declsMu.Unlock()
+	for _, method := range task.Methods {
+		if err := e.declareMethod(method); err != nil {
+			return &RemoteResult{Error: err.Error()}
+		}
+	}
+	for name, val := range task.Env {
+		val, err := e.localValue(val)
+		if err != nil {
+			return &RemoteResult{Error: err.Error()}
+		}
+		e.globals.define(name, val)
+	}
+	val, err := e.Execute(task.Node)
+	if err == nil {
+		val, err = e.newTaskPacker(nil).value(val)
+	}
+	if err != nil {
+		return &RemoteResult{Error: err.Error()}
+	}
+	return &RemoteResult{Value: val}
+}
+
+// executeRemote packages node as a RemoteTask and runs it through the dispatcher.
+func (e *Executor) executeRemote(node models.Node, env *Environment) (interface{}, error) {
+	task, err := e.remoteTask(node, env)
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.dispatcher.Dispatch(task)
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	return e.localValue(res.Value)
+}
+
+// remoteTask collects the variables referenced by node that are visible from
+// env, along with the user-defined functions reachable from it and the struct
+// types and methods it may use. It fails if a variable holds a value that
+// cannot be sent to a worker.
+func (e *Executor) remoteTask(node models.Node, env *Environment) (*RemoteTask, error) {
+	task := &RemoteTask{
+		Node:      node,
+		Env:       make(map[string]interface{}),
+		Functions: make(map[string]*models.FunctionDeclaration),
+		Structs:   make(map[string]*models.StructDeclaration),
+	}
+	p := e.newTaskPacker(task)
+	if err := p.visit(node, env, task.Env, nil); err != nil {
+		return nil, err
+	}
+
+	// Methods can be called on any struct the task constructs or reads, and
+	// their bodies may in turn use further functions and struct types.
+	shipped := make(map[*models.MethodDeclaration]bool)
+	for changed := true; changed; {
+		changed = false
+		for name := range task.Structs {
+			for _, method := range e.methodsOf(name) {
+				if !shipped[method] {
+					shipped[method] = true
+					task.Methods = append(task.Methods, method)
+					if err := p.visit(method.Function, e.globals, task.Env, nil); err != nil {
+						return nil, err
+					}
+					changed = true
+				}
+			}
+		}
+	}
+	return task, nil
+}
+
+// taskPacker converts values into the form they are sent to a worker in,
+// adding the declarations they depend on to a task.
+type taskPacker struct {
+	e       *Executor
+	task    *RemoteTask // Task to add declarations to; nil when packing a result.
+	packing map[*Function]*remoteFunction
+}
+
+func (e *Executor) newTaskPacker(task *RemoteTask) *taskPacker {
+	return &taskPacker{e: e, task: task, packing: make(map[*Function]*remoteFunction)}
+}
+
+// visit records in vars the values of the variables root reads from env, and
+// ships the top-level functions and struct types it uses. self is the
+// function whose body root is, if any.
+func (p *taskPacker) visit(root models.Node, env *Environment, vars map[string]interface{}, self *Function) error {
+	var err error
+	models.Walk(root, func(n models.Node) bool {
+		switch n := n.(type) {
+		case *models.Variable:
+			err = p.reference(n.Name, env, vars, self)
+		case *models.FunctionCall:
+			if n.Callee != nil {
+				name, _ := qualifiedName(n.Callee)
+				err = p.shipFunction(name)
+			} else {
+				err = p.reference(n.Name, env, vars, self)
+			}
+		case *models.StructLiteral:
+			if decl, ok := p.e.structType(n.Name); ok && p.task != nil {
+				p.task.Structs[n.Name] = decl
+			}
+		}
+		return err == nil
+	})
+	return err
+}
+
+// reference records the variable name as read from env, or ships the
+// top-level function of that name if no variable is visible.
+func (p *taskPacker) reference(name string, env *Environment, vars map[string]interface{}, self *Function) error {
+	val, ok := env.Lookup(name)
+	if !ok {
+		return p.shipFunction(name)
+	}
+	if _, done := vars[name]; done {
+		return nil
+	}
+	if fn, ok := val.(*Function); ok && fn == self {
+		p.packing[self].Self = name
+		return nil
+	}
+	val, err := p.value(val)
+	if err != nil {
+		return fmt.Errorf("variable %s: %w", name, err)
+	}
+	vars[name] = val
+	return nil
+}
+
+// shipFunction adds the top-level function name, if there is one, and what its
+// body uses to the task.
+func (p *taskPacker) shipFunction(name string) error {
+	if p.task == nil {
+		return nil
+	}
+	decl, ok := p.e.function(name)
+	if !ok {
+		return nil
+	}
+	if _, seen := p.task.Functions[name]; seen {
+		return nil
+	}
+	p.task.Functions[name] = decl
+	return p.visit(decl, p.e.globals, p.task.Env, nil)
+}
+
+// value returns val in the form it is sent in, failing for values that cannot
+// be sent, such as generators, futures, and locks.
+func (p *taskPacker) value(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case nil, bool, int64, float64, string, time.Time, time.Duration, *big.Int,
+		*Decimal, *Regex, *Range, *Enum, *EnumMember, *Matrix, []float64:
+		return v, nil
+	case []interface{}:
+		return p.values(v)
+	case Tuple:
+		elems, err := p.values(v)
+		return Tuple(elems), err
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			packed, err := p.value(elem)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = packed
+		}
+		return out, nil
+	case *Struct:
+		if p.task != nil {
+			p.task.Structs[v.Type.Name] = v.Type
+		}
+		fields, err := p.value(v.fields())
+		if err != nil {
+			return nil, err
+		}
+		return &Struct{Type: v.Type, Fields: fields.(map[string]interface{})}, nil
+	case *Function:
+		return p.function(v)
+	default:
+		return nil, fmt.Errorf("cannot send a %s to a worker", typeName(val))
+	}
+}
+
+func (p *taskPacker) values(list []interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(list))
+	for i, elem := range list {
+		packed, err := p.value(elem)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = packed
+	}
+	return out, nil
+}
+
+// function packs a function value, with the variables its body reads from the
+// scope it was created in. A closure may refer to itself, but not to another
+// closure that refers back to it.
+func (p *taskPacker) function(fn *Function) (*remoteFunction, error) {
+	if fn.builtin != nil {
+		return &remoteFunction{Name: fn.Name, Builtin: true}, nil
+	}
+	if p.packing[fn] != nil {
+		return nil, fmt.Errorf("cannot send mutually recursive function %s to a worker", fn.displayName())
+	}
+	rf := &remoteFunction{Name: fn.Name, Decl: fn.decl, Captured: make(map[string]interface{})}
+	p.packing[fn] = rf
+	defer delete(p.packing, fn)
+	if err := p.visit(fn.decl, fn.env, rf.Captured, fn); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// localValue rebuilds the function values in a value received from a worker
+// or a coordinator.
+func (e *Executor) localValue(val interface{}) (interface{}, error) {
+	switch v := val.(type) {
+	case []interface{}:
+		return e.localValues(v)
+	case Tuple:
+		elems, err := e.localValues(v)
+		return Tuple(elems), err
+	case map[string]interface{}:
+		for key, elem := range v {
+			local, err := e.localValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = local
+		}
+		return v, nil
+	case *Struct:
+		if _, err := e.localValue(v.Fields); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case *remoteFunction:
+		return e.localFunction(v)
+	default:
+		return v, nil
+	}
+}
+
+func (e *Executor) localValues(list []interface{}) ([]interface{}, error) {
+	for i, elem := range list {
+		local, err := e.localValue(elem)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = local
+	}
+	return list, nil
+}
+
+// localFunction rebuilds a function value, in a scope of its own below the
+// top-level scope holding the variables it captured.
+func (e *Executor) localFunction(rf *remoteFunction) (*Function, error) {
+	if rf.Builtin {
+		builtin, ok := e.builtins[rf.Name]
+		if !ok {
+			return nil, fmt.Errorf("undefined function: %s", rf.Name)
+		}
+		return &Function{Name: rf.Name, builtin: builtin}, nil
+	}
+	env := newEnvironment(e.globals)
+	fn := &Function{Name: rf.Name, decl: rf.Decl, env: env}
+	for name, val := range rf.Captured {
+		local, err := e.localValue(val)
+		if err != nil {
+			return nil, err
+		}
+		env.define(name, local)
+	}
+	if rf.Self != "" {
+		env.define(rf.Self, fn)
+	}
+	return fn, nil
+}
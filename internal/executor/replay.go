@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// Recording holds what the executor observed of a run under Record, so that
+// Replay can reproduce it: the results of the builtins whose results vary
+// from run to run, and the order in which the branches of each parallel block
+// and parallel for loop finished.
+//
+// A recording refers to the nodes of the programs it was made from, so it can
+// only be replayed against the same trees. Only builtins that declare a
+// capability, and now, are recorded; other builtins are expected to give the
+// same result for the same arguments.
+type Recording struct {
+	mu        sync.Mutex
+	calls     map[string][]recordedCall // Results of builtin calls, by callKey, in call order.
+	schedules map[models.Node][][]int   // Completion order of the branches of each run of a construct.
+}
+
+// recordedCall is the outcome of one builtin call.
+type recordedCall struct {
+	result interface{}
+	err    string // Message of the error the builtin returned, if any.
+	failed bool
+}
+
+// NewRecording creates an empty recording to pass to Record.
+func NewRecording() *Recording {
+	return &Recording{
+		calls:     make(map[string][]recordedCall),
+		schedules: make(map[models.Node][][]int),
+	}
+}
+
+// replayState tracks the progress of a Record or Replay.
+type replayState struct {
+	rec       *Recording
+	replaying bool
+	mu        sync.Mutex
+	calls     map[string]int      // Calls replayed so far, by callKey.
+	instances map[models.Node]int // Runs of each parallel construct started so far.
+}
+
+// Record makes the executor record the builtin results and parallel schedule
+// of the programs it runs into rec, until Record or Replay is called again. A
+// nil rec stops recording. Record must not be called while programs run.
+func (e *Executor) Record(rec *Recording) {
+	e.setReplay(rec, false)
+}
+
+// Replay makes the executor reproduce the run recorded in rec: recorded
+// builtins return their recorded results instead of being called, and the
+// branches of each parallel construct run one at a time in the order they
+// finished when recorded. A recorded builtin called more often than it was
+// recorded fails. A nil rec stops replaying. Replay must not be called while
+// programs run.
+//
+// The schedule is reproduced branch by branch, not statement by statement, so
+// it reproduces failures that depend on which branch finished first but not
+// races between statements of branches running side by side. Pipelines, task
+// graphs, and spawned tasks are not scheduled, though the builtins they call
+// are replayed.
+func (e *Executor) Replay(rec *Recording) {
+	e.setReplay(rec, true)
+}
+
+func (e *Executor) setReplay(rec *Recording, replaying bool) {
+	if rec == nil {
+		e.replay = nil
+		return
+	}
+	e.replay = &replayState{
+		rec:       rec,
+		replaying: replaying,
+		calls:     make(map[string]int),
+		instances: make(map[models.Node]int),
+	}
+}
+
+// recorded reports whether calls to the builtin name are recorded and
+// replayed.
+func (e *Executor) recorded(name string) bool {
+	return e.replay != nil && (name == "now" || len(e.capabilities[name]) > 0)
+}
+
+// callKey identifies the calls to a builtin with the same arguments.
+func callKey(name string, args []interface{}) string {
+	return name + fmt.Sprint(args)
+}
+
+// recordCall adds the outcome of a call to the recording.
+func (s *replayState) recordCall(name string, args []interface{}, result interface{}, err error) {
+	call := recordedCall{result: result}
+	if err != nil {
+		call = recordedCall{err: err.Error(), failed: true}
+	}
+	key := callKey(name, args)
+	s.rec.mu.Lock()
+	s.rec.calls[key] = append(s.rec.calls[key], call)
+	s.rec.mu.Unlock()
+}
+
+// replayCall returns the recorded outcome of the next call to name with args.
+func (s *replayState) replayCall(name string, args []interface{}) (interface{}, error) {
+	key := callKey(name, args)
+	s.mu.Lock()
+	i := s.calls[key]
+	s.calls[key]++
+	s.mu.Unlock()
+	s.rec.mu.Lock()
+	calls := s.rec.calls[key]
+	s.rec.mu.Unlock()
+	if i >= len(calls) {
+		return nil, fmt.Errorf("replay: no recorded result for call %d to %s with arguments %v", i+1, name, args)
+	}
+	if calls[i].failed {
+		return nil, errors.New(calls[i].err)
+	}
+	return calls[i].result, nil
+}
+
+// parallelSchedule follows one run of a parallel construct under Record or
+// Replay. A nil schedule, used when neither is in effect, runs the branches
+// in order and records nothing.
+type parallelSchedule struct {
+	state    *replayState
+	node     models.Node
+	instance int
+	replay   []int // Order to run the branches in; nil unless replaying.
+	mu       sync.Mutex
+	finished []int // Branches in the order they finished, when recording.
+}
+
+// beginSchedule starts following a run of the construct node, which has the
+// given number of branches.
+func (e *Executor) beginSchedule(node models.Node, branches int) *parallelSchedule {
+	s := e.replay
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	instance := s.instances[node]
+	s.instances[node]++
+	s.mu.Unlock()
+	sched := &parallelSchedule{state: s, node: node, instance: instance}
+	s.rec.mu.Lock()
+	defer s.rec.mu.Unlock()
+	runs := s.rec.schedules[node]
+	if !s.replaying {
+		for len(runs) <= instance {
+			runs = append(runs, nil)
+		}
+		s.rec.schedules[node] = runs
+		return sched
+	}
+	// A run recorded with a different number of branches cannot be
+	// reproduced, so its branches run in order instead.
+	if instance < len(runs) && len(runs[instance]) == branches {
+		sched.replay = runs[instance]
+	}
+	return sched
+}
+
+// serial reports whether the branches must run one at a time.
+func (sched *parallelSchedule) serial() bool {
+	return sched != nil && sched.replay != nil
+}
+
+// order returns the order in which to start the branches.
+func (sched *parallelSchedule) order(branches int) []int {
+	if sched.serial() {
+		return sched.replay
+	}
+	order := make([]int, branches)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// done notes that branch i has finished.
+func (sched *parallelSchedule) done(i int) {
+	if sched == nil || sched.state.replaying {
+		return
+	}
+	sched.mu.Lock()
+	sched.finished = append(sched.finished, i)
+	sched.mu.Unlock()
+}
+
+// end adds the run to the recording once all its branches have finished.
+// Branches skipped after a failure are recorded as finishing last.
+func (sched *parallelSchedule) end(branches int) {
+	if sched == nil || sched.state.replaying {
+		return
+	}
+	seen := make([]bool, branches)
+	for _, i := range sched.finished {
+		seen[i] = true
+	}
+	for i, ok := range seen {
+		if !ok {
+			sched.finished = append(sched.finished, i)
+		}
+	}
+	rec := sched.state.rec
+	rec.mu.Lock()
+	rec.schedules[sched.node][sched.instance] = sched.finished
+	rec.mu.Unlock()
+}
@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"silk/internal/models"
+	"silk/internal/utils"
+)
+
+// handleRetry runs the body of n until an attempt succeeds, waiting between
+// attempts as n's backoff policy prescribes.
+func (e *Executor) handleRetry(n *models.Retry, env *Environment) (interface{}, error) {
+	var retryOn *Function
+	if n.RetryOn != nil {
+		v, err := e.eval(n.RetryOn, env)
+		if err != nil {
+			return nil, err
+		}
+		if retryOn, err = e.functionArg("retry", v); err != nil {
+			return nil, err
+		}
+	}
+
+	delay := n.Delay
+	for attempt := 1; ; attempt++ {
+		scope := newEnvironment(env)
+		scope.boundary = true
+		val, err := e.blockValue(n.Body, scope)
+		if err == nil {
+			return val, nil
+		}
+		if attempt >= n.Attempts || !retryable(err) {
+			return nil, err
+		}
+		if retryOn != nil {
+			retry, callErr := e.callFunctionIn(env.site(), retryOn, []interface{}{retryInfo(err, attempt)})
+			if callErr != nil {
+				return nil, callErr
+			}
+			if !isTruthy(retry) {
+				return nil, err
+			}
+		}
+
+		wait := delay
+		if n.Jitter && wait > 0 {
+			wait = rand.N(wait + 1)
+		}
+		if err := e.sleep(wait, env.cancel); err != nil {
+			return nil, err
+		}
+		if n.Multiplier > 1 {
+			delay = time.Duration(math.Min(float64(delay)*n.Multiplier, math.MaxInt64))
+		}
+		if n.MaxDelay > 0 && delay > n.MaxDelay {
+			delay = n.MaxDelay
+		}
+	}
+}
+
+// retryable reports whether err is a failure that a Retry may try again, as
+// opposed to a control-flow signal or the end of the program.
+func retryable(err error) bool {
+	var ret *returnSignal
+	var tail *tailCall
+	switch {
+	case err == errBreak, err == errContinue, errors.As(err, &ret), errors.As(err, &tail):
+		return false
+	case errors.Is(err, ErrInterrupted), errors.Is(err, errCancelled):
+		return false
+	default:
+		return true
+	}
+}
+
+// retryInfo describes a failed attempt to the RetryOn function of a Retry.
+func retryInfo(err error, attempt int) map[string]interface{} {
+	info := map[string]interface{}{
+		"message": err.Error(),
+		"builtin": nil,
+		"attempt": float64(attempt),
+	}
+	var builtinErr *utils.BuiltinError
+	if errors.As(err, &builtinErr) {
+		info["builtin"] = builtinErr.Name
+	}
+	return info
+}
+
+// sleep waits for d, returning early with an error if code running under the
+// timeout cancel is cancelled or the program is stopped.
+func (e *Executor) sleep(d time.Duration, cancel *cancelScope) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-e.context(cancel).Done():
+		if cancel.interrupted() {
+			return ErrInterrupted
+		}
+		return errCancelled
+	}
+}
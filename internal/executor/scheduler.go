@@ -0,0 +1,276 @@
+package executor
+
+import "sync"
+
+// scheduler runs the branches of parallel constructs on a bounded set of helper
+// goroutines using work stealing.
+//
+// Every parallel construct submits its branches to its own taskGroup. The
+// goroutine that owns a group executes the group's pending tasks itself, newest
+// first, while idle helpers steal the oldest pending tasks from any group,
+// preferring groups of higher priority. Because
+// a goroutine waiting on a group is always either running that group's tasks or
+// waiting on tasks that are already running elsewhere, nested parallel blocks
+// cannot starve one another of slots, and the number of goroutines doing work
+// never exceeds the helper limit plus the goroutines that called Execute.
+type scheduler struct {
+	mu         sync.Mutex
+	idle       *sync.Cond   // Signalled when a helper slot is released.
+	groups     []*taskGroup // Groups with pending tasks, oldest first.
+	helpers    int          // Helper goroutines and callbacks currently running.
+	maxHelpers int          // Upper bound on helper goroutines; may change while tasks run.
+	metrics    *Metrics     // Optional collector for active task counts.
+}
+
+// taskGroup is the set of tasks submitted by a single parallel construct.
+type taskGroup struct {
+	sched    *scheduler
+	limit    int        // If positive, the most tasks of the group that may be pending or running at once.
+	priority int        // Helpers steal from groups of higher priority first.
+	maxHeld  int        // If positive, the most tasks that may be held at once.
+	block    bool       // Whether Go waits for room when maxHeld tasks are held, rather than refusing the task.
+	pending  []func()   // Tasks not yet started; guarded by sched.mu.
+	held     []func()   // Tasks waiting for the number of running tasks to fall below limit; guarded by sched.mu.
+	running  int        // Tasks pending or running; guarded by sched.mu.
+	queued   bool       // Whether the group is listed in sched.groups; guarded by sched.mu.
+	room     *sync.Cond // Signalled when a held task starts.
+	wg       sync.WaitGroup
+}
+
+// newScheduler creates a scheduler that runs tasks on up to maxGoroutines
+// goroutines, counting the goroutine that waits on each group.
+func newScheduler(maxGoroutines int) *scheduler {
+	s := &scheduler{maxHelpers: maxGoroutines - 1}
+	s.idle = sync.NewCond(&s.mu)
+	return s
+}
+
+// setMaxGoroutines changes the limit given to newScheduler. Raising it starts
+// helpers for tasks already pending; when it is lowered, helpers over the new
+// limit exit as they finish their current task.
+func (s *scheduler) setMaxGoroutines(n int) {
+	s.mu.Lock()
+	s.maxHelpers = max(n, 1) - 1
+	pending := 0
+	for _, g := range s.groups {
+		pending += len(g.pending)
+	}
+	spawn := max(min(s.maxHelpers-s.helpers, pending), 0)
+	s.helpers += spawn
+	s.mu.Unlock()
+	s.idle.Broadcast()
+
+	for ; spawn > 0; spawn-- {
+		go s.help()
+	}
+}
+
+// size returns the most goroutines that may run tasks at once, counting the
+// goroutine that waits on a group.
+func (s *scheduler) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.maxHelpers + 1
+}
+
+// runCallback runs fn on the calling goroutine once a slot is free. It is used for
+// work started outside the program's own parallel constructs, such as message
+// handlers invoked from a connection's read loop. Such a goroutine has no parent
+// waiting on it, so it may take the one slot that helpers leave for a waiter.
+// It must not be called from a goroutine that is itself running a task.
+func (s *scheduler) runCallback(fn func()) {
+	s.mu.Lock()
+	for s.helpers > s.maxHelpers {
+		s.idle.Wait()
+	}
+	s.helpers++
+	s.mu.Unlock()
+
+	defer s.release()
+	fn()
+}
+
+// release returns a helper slot and wakes a goroutine waiting for one.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	s.helpers--
+	s.mu.Unlock()
+	s.idle.Signal()
+}
+
+// group starts a new, empty task group.
+func (s *scheduler) group() *taskGroup {
+	return &taskGroup{sched: s, room: sync.NewCond(&s.mu)}
+}
+
+// Go submits a task to the group and wakes a helper to steal it if one is
+// available. If the group's limit is reached, the task is held back until one
+// of the group's running tasks finishes. If maxHeld tasks are held already, Go
+// either waits for one of them to start or refuses the task, and reports
+// whether it accepted it.
+//
+// Go may be called by the goroutine that waits on the group, and, unless
+// maxHeld is set, by the group's own tasks while they run. A task submitting
+// another keeps Wait from returning until the new task has finished, and the
+// goroutine running it, the waiter or a helper, looks for pending tasks once
+// it is done, so the new task cannot be stranded. A task must not call Go on
+// a group with maxHeld set, since waiting there for room could deadlock.
+func (g *taskGroup) Go(task func()) bool {
+	s := g.sched
+	s.mu.Lock()
+	for g.limit > 0 && g.running >= g.limit && g.maxHeld > 0 && len(g.held) >= g.maxHeld {
+		if !g.block {
+			s.mu.Unlock()
+			return false
+		}
+		// No helper may be free to start the group's pending tasks, so run
+		// one here rather than wait for it.
+		if n := len(g.pending); n > 0 {
+			local := g.pending[n-1]
+			g.pending = g.pending[:n-1]
+			s.mu.Unlock()
+			local()
+			s.mu.Lock()
+			continue
+		}
+		g.room.Wait()
+	}
+	g.wg.Add(1)
+	if g.limit > 0 && g.running >= g.limit {
+		g.held = append(g.held, task)
+		s.mu.Unlock()
+		return true
+	}
+	g.running++
+	spawn := g.push(task)
+	s.mu.Unlock()
+
+	if spawn {
+		go s.help()
+	}
+	return true
+}
+
+// push adds task to the group's pending tasks and reports whether a helper
+// should be started to steal it. The caller must hold sched.mu.
+func (g *taskGroup) push(task func()) bool {
+	s := g.sched
+	g.pending = append(g.pending, func() {
+		defer g.wg.Done()
+		// Start the next held task before Done, so that Wait cannot return
+		// while tasks remain.
+		defer g.finish()
+		if m := s.metrics; m != nil {
+			m.tasksActive.Add(1)
+			defer m.tasksActive.Add(-1)
+		}
+		task()
+	})
+	if !g.queued {
+		g.queued = true
+		s.groups = append(s.groups, g)
+	}
+	spawn := s.helpers < s.maxHelpers
+	if spawn {
+		s.helpers++
+	}
+	return spawn
+}
+
+// finish hands the slot of a task that has ended to the oldest held task, if
+// there is one.
+func (g *taskGroup) finish() {
+	s := g.sched
+	s.mu.Lock()
+	if len(g.held) == 0 {
+		g.running--
+		s.mu.Unlock()
+		return
+	}
+	task := g.held[0]
+	g.held = g.held[1:]
+	spawn := g.push(task)
+	s.mu.Unlock()
+	g.room.Signal()
+
+	if spawn {
+		go s.help()
+	}
+}
+
+// Wait runs the group's remaining tasks on the calling goroutine, then blocks
+// until tasks stolen by helpers have finished.
+func (g *taskGroup) Wait() {
+	for {
+		task := g.sched.popLocal(g)
+		if task == nil {
+			break
+		}
+		task()
+	}
+	g.wg.Wait()
+}
+
+// popLocal removes the most recently submitted pending task of g.
+func (s *scheduler) popLocal(g *taskGroup) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(g.pending) == 0 {
+		return nil
+	}
+	task := g.pending[len(g.pending)-1]
+	g.pending = g.pending[:len(g.pending)-1]
+	return task
+}
+
+// steal removes the oldest pending task from the group of highest priority
+// that has one, taking the oldest of the groups of equal priority. It returns
+// nil while there are more helpers than the limit allows.
+func (s *scheduler) steal() func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.helpers > s.maxHelpers {
+		return nil
+	}
+	var best *taskGroup
+	live := s.groups[:0]
+	for _, g := range s.groups {
+		if len(g.pending) == 0 {
+			g.queued = false
+			continue
+		}
+		live = append(live, g)
+		if best == nil || g.priority > best.priority {
+			best = g
+		}
+	}
+	clear(s.groups[len(live):])
+	s.groups = live
+	if best == nil {
+		return nil
+	}
+	task := best.pending[0]
+	best.pending = best.pending[1:]
+	return task
+}
+
+// help runs stolen tasks until none are left, or until the helper limit falls
+// below the number of helpers, then exits.
+func (s *scheduler) help() {
+	for {
+		task := s.steal()
+		if task == nil {
+			s.mu.Lock()
+			// Re-check under the lock so a task submitted while exiting is not stranded.
+			if len(s.groups) == 0 || s.helpers > s.maxHelpers {
+				s.helpers--
+				s.mu.Unlock()
+				s.idle.Signal()
+				return
+			}
+			s.mu.Unlock()
+			continue
+		}
+		task()
+	}
+}
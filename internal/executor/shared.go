@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"fmt"
+	"maps"
+
+	"silk/internal/models"
+)
+
+// SetSharedContainers controls whether assigning to an element of a list or
+// map, as in m["k"] = v or m.k = v, changes the container in place. By
+// default it does, so the change is visible through every variable holding
+// the container, but parallel statements that write to the same container
+// race, and the process can abort with a concurrent map write.
+//
+// With shared containers, such an assignment instead stores an updated copy
+// of the container in the variable it was reached through, so containers
+// are never changed once other code may be reading them. The copy is made
+// and stored under the lock of the variable's scope, so parallel statements
+// may assign to elements of a shared container: assignments to different
+// elements are all kept, and of two assignments to the same element the last
+// wins. In exchange, other variables holding the container no longer see the
+// change, and each assignment costs a copy of the container. Fields of
+// structs are still assigned in place, since structs guard their own fields.
+// Assignments through anything but a variable, such as f()["k"] = v, and the
+// deleteKey builtin still change the container in place.
+func (e *Executor) SetSharedContainers(shared bool) {
+	e.sharedContainers = shared
+}
+
+// pathStep is one index or field access on the way from a variable to the
+// element an assignment replaces.
+type pathStep struct {
+	key    interface{}
+	member bool // Whether key names a field, as in m.k, rather than an index.
+}
+
+func (s pathStep) get(container interface{}) (interface{}, error) {
+	if s.member {
+		return memberValue(container, s.key.(string))
+	}
+	return indexValue(container, s.key)
+}
+
+func (s pathStep) set(container, val interface{}) error {
+	if s.member {
+		return setMember(container, s.key.(string), val)
+	}
+	return setIndex(container, s.key, val)
+}
+
+// sharedTarget reports whether containers are shared and target, the
+// container an assignment changes, is reached from a variable through
+// indexes and fields, so that the assignment can replace it.
+func (e *Executor) sharedTarget(target models.Node) bool {
+	if !e.sharedContainers {
+		return false
+	}
+	for {
+		switch n := target.(type) {
+		case *models.Variable:
+			return true
+		case *models.IndexExpression:
+			target = n.Object
+		case *models.MemberExpression:
+			target = n.Object
+		default:
+			return false
+		}
+	}
+}
+
+// assignShared stores val in the element of target selected by last, where
+// sharedTarget(target) holds, by replacing the containers on the way to it.
+func (e *Executor) assignShared(target models.Node, last pathStep, val interface{}, env *Environment) error {
+	path := []pathStep{last}
+	for {
+		switch n := target.(type) {
+		case *models.Variable:
+			// Steps were collected innermost first.
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return env.modify(n.Name, func(container interface{}) (interface{}, error) {
+				return replaceElement(container, path, val)
+			})
+		case *models.IndexExpression:
+			index, err := e.eval(n.Index, env)
+			if err != nil {
+				return err
+			}
+			path = append(path, pathStep{key: index})
+			target = n.Object
+		case *models.MemberExpression:
+			path = append(path, pathStep{key: n.Property, member: true})
+			target = n.Object
+		}
+	}
+}
+
+// replaceElement returns a copy of container in which the element at path is
+// val, copying each list and map along the path.
+func replaceElement(container interface{}, path []pathStep, val interface{}) (interface{}, error) {
+	step := path[0]
+	if len(path) > 1 {
+		elem, err := step.get(container)
+		if err != nil {
+			return nil, err
+		}
+		if val, err = replaceElement(elem, path[1:], val); err != nil {
+			return nil, err
+		}
+	}
+	updated := copyContainer(container)
+	if err := step.set(updated, val); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// copyContainer returns a shallow copy of a list or map. Other values are
+// returned as they are.
+func copyContainer(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return maps.Clone(v)
+	case []interface{}:
+		return append([]interface{}(nil), v...)
+	case []float64:
+		return append([]float64(nil), v...)
+	default:
+		return v
+	}
+}
+
+// modify replaces the value of the variable name, in the nearest scope that
+// defines it, with the result of update applied to it. The scope stays locked
+// throughout, so concurrent modifications are applied one after another.
+func (env *Environment) modify(name string, update func(interface{}) (interface{}, error)) error {
+	for scope := env; scope != nil; scope = scope.parent {
+		scope.mu.Lock()
+		val, ok := scope.variables[name]
+		if !ok {
+			scope.mu.Unlock()
+			continue
+		}
+		defer scope.mu.Unlock()
+		updated, err := update(val)
+		if err != nil {
+			return err
+		}
+		scope.variables[name] = updated
+		return nil
+	}
+	return fmt.Errorf("undefined variable: %s", name)
+}
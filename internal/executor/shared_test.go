@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"io"
+	"testing"
+
+	"silk/internal/models"
+)
+
+// parallelFor runs body for every element of collection, bound to x.
+func parallelFor(collection models.Node, body ...models.Node) *models.ParallelForLoop {
+	return &models.ParallelForLoop{Value: ref("x"), Collection: collection, Body: body}
+}
+
+func TestSharedContainersParallelWrites(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(8)
+	e.SetSharedContainers(true)
+	_, err := e.Execute(program(
+		assign("m", &models.MapLiteral{}),
+		assign("l", &models.ArrayLiteral{Elements: []models.Node{num(0), num(0)}}),
+		assign("nested", &models.MapLiteral{Entries: []models.MapEntry{{Key: str("inner"), Value: &models.MapLiteral{}}}}),
+		parallelFor(keys(200),
+			call("sleep", num(1)),
+			&models.IndexAssignment{Object: ref("m"), Index: ref("x"), Value: ref("x")},
+			&models.IndexAssignment{Object: ref("l"), Index: num(1), Value: ref("x")},
+			&models.IndexAssignment{Object: &models.MemberExpression{Object: ref("nested"), Property: "inner"}, Index: ref("x"), Value: num(1)},
+			call("len", ref("m")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"m", "nested"} {
+		val, _ := e.globals.Lookup(name)
+		m := val.(map[string]interface{})
+		if name == "nested" {
+			m = m["inner"].(map[string]interface{})
+		}
+		if len(m) != 200 {
+			t.Errorf("%s has %d keys, want 200", name, len(m))
+		}
+	}
+}
+
+func TestSharedContainersCopyOnWrite(t *testing.T) {
+	e := NewExecutor()
+	e.SetSharedContainers(true)
+	_, err := e.Execute(program(
+		assign("a", &models.MapLiteral{}),
+		assign("b", ref("a")),
+		&models.IndexAssignment{Object: ref("a"), Index: str("k"), Value: num(1)},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := e.globals.Lookup("a")
+	b, _ := e.globals.Lookup("b")
+	if len(a.(map[string]interface{})) != 1 || len(b.(map[string]interface{})) != 0 {
+		t.Errorf("a = %v, b = %v; want only a changed", a, b)
+	}
+}
+
+func TestStructFieldsParallelWrites(t *testing.T) {
+	e := NewExecutor()
+	e.SetMaxGoroutines(8)
+	e.SetOutput(io.Discard, nil)
+	got, err := e.Execute(program(
+		&models.StructDeclaration{Name: "Point", Fields: []string{"x"}},
+		assign("p", &models.StructLiteral{Name: "Point"}),
+		parallelFor(keys(200),
+			call("sleep", num(1)),
+			&models.MemberAssignment{Object: ref("p"), Property: "x", Value: ref("x")},
+			&models.MemberExpression{Object: ref("p"), Property: "x"},
+			call("print", ref("p")),
+		),
+		&models.MemberExpression{Object: ref("p"), Property: "x"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got.(string); !ok {
+		t.Errorf("p.x = %v, want one of the keys", got)
+	}
+}
@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// Struct is an instance of a struct type declared by the program. Like maps,
+// structs are shared by reference, so assigning to a field is visible through
+// every variable holding the instance. Parallel statements may assign to the
+// fields of a shared struct; each read or assignment of a field is atomic,
+// and the last assignment wins.
+type Struct struct {
+	Type   *models.StructDeclaration
+	Fields map[string]interface{}
+	mu     sync.RWMutex // Guards Fields once the struct has been constructed.
+}
+
+// String renders the struct with its fields in declaration order.
+func (s *Struct) String() string {
+	fields := s.fields()
+	parts := make([]string, len(s.Type.Fields))
+	for i, name := range s.Type.Fields {
+		parts[i] = fmt.Sprintf("%s: %v", name, fields[name])
+	}
+	return s.Type.Name + "{" + strings.Join(parts, ", ") + "}"
+}
+
+// declareStruct registers a struct type, rejecting duplicate field names.
+func (e *Executor) declareStruct(n *models.StructDeclaration) error {
+	seen := make(map[string]bool, len(n.Fields))
+	for _, field := range n.Fields {
+		if seen[field] {
+			return fmt.Errorf("struct %s declares field %s more than once", n.Name, field)
+		}
+		seen[field] = true
+	}
+	e.declsMu.Lock()
+	defer e.declsMu.Unlock()
+	e.structs[n.Name] = n
+	return nil
+}
+
+// declareMethod attaches a method to a declared struct type. A method may not
+// share its name with a field of the type.
+func (e *Executor) declareMethod(n *models.MethodDeclaration) error {
+	e.declsMu.Lock()
+	defer e.declsMu.Unlock()
+	decl, ok := e.structs[n.Type]
+	if !ok {
+		return fmt.Errorf("undefined struct type: %s", n.Type)
+	}
+	name := n.Function.Name
+	for _, field := range decl.Fields {
+		if field == name {
+			return fmt.Errorf("struct %s has both a field and a method named %s", n.Type, name)
+		}
+	}
+	if e.methods[n.Type] == nil {
+		e.methods[n.Type] = make(map[string]*models.MethodDeclaration)
+	}
+	e.methods[n.Type][name] = n
+	return nil
+}
+
+// boundMethod returns the method name of a struct value as a function value
+// whose scope binds the receiver, so that calling it needs only the
+// remaining arguments.
+func (e *Executor) boundMethod(object interface{}, name string) (*Function, bool) {
+	s, ok := object.(*Struct)
+	if !ok {
+		return nil, false
+	}
+	e.declsMu.RLock()
+	method, ok := e.methods[s.Type.Name][name]
+	e.declsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	env := newEnvironment(e.globals)
+	if method.Receiver != nil {
+		env.define(method.Receiver.Name, s)
+	}
+	return &Function{Name: s.Type.Name + "." + name, decl: method.Function, env: env}, true
+}
+
+// newStruct evaluates a struct literal in env.
+func (e *Executor) newStruct(n *models.StructLiteral, env *Environment) (*Struct, error) {
+	decl, ok := e.structType(n.Name)
+	if !ok {
+		return nil, fmt.Errorf("undefined struct type: %s", n.Name)
+	}
+	s := &Struct{Type: decl, Fields: make(map[string]interface{}, len(decl.Fields))}
+	for _, name := range decl.Fields {
+		s.Fields[name] = nil
+	}
+	for _, field := range n.Fields {
+		if _, ok := s.Fields[field.Name]; !ok {
+			return nil, fmt.Errorf("struct %s has no field %s", decl.Name, field.Name)
+		}
+		val, err := e.eval(field.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		s.Fields[field.Name] = val
+	}
+	return s, nil
+}
+
+// memberValue reads a field of a struct or a key of a map. Missing map keys
+// yield nil, as with indexing.
+func memberValue(object interface{}, name string) (interface{}, error) {
+	switch object := object.(type) {
+	case *Struct:
+		object.mu.RLock()
+		val, ok := object.Fields[name]
+		object.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("struct %s has no field or method %s", object.Type.Name, name)
+		}
+		return val, nil
+	case map[string]interface{}:
+		return object[name], nil
+	case *Module:
+		val, ok := object.exports[name]
+		if !ok {
+			return nil, fmt.Errorf("module %s does not export %s", object.Path, name)
+		}
+		return val, nil
+	case *Enum:
+		member, ok := object.member(name)
+		if !ok {
+			return nil, fmt.Errorf("enum %s has no member %s", object.Name, name)
+		}
+		return member, nil
+	default:
+		return nil, fmt.Errorf("cannot access field %s of %v", name, object)
+	}
+}
+
+// setMember stores val in a field of a struct or under a key of a map.
+func setMember(object interface{}, name string, val interface{}) error {
+	switch object := object.(type) {
+	case *Struct:
+		object.mu.Lock()
+		defer object.mu.Unlock()
+		if _, ok := object.Fields[name]; !ok {
+			return fmt.Errorf("struct %s has no field %s", object.Type.Name, name)
+		}
+		object.Fields[name] = val
+		return nil
+	case map[string]interface{}:
+		object[name] = val
+		return nil
+	default:
+		return fmt.Errorf("cannot assign to field %s of %v", name, object)
+	}
+}
+
+// structType returns the struct type named name.
+func (e *Executor) structType(name string) (*models.StructDeclaration, bool) {
+	e.declsMu.RLock()
+	defer e.declsMu.RUnlock()
+	decl, ok := e.structs[name]
+	return decl, ok
+}
+
+// methodsOf returns the methods declared for the struct type named name.
+func (e *Executor) methodsOf(name string) []*models.MethodDeclaration {
+	e.declsMu.RLock()
+	defer e.declsMu.RUnlock()
+	methods := make([]*models.MethodDeclaration, 0, len(e.methods[name]))
+	for _, method := range e.methods[name] {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// fields returns a copy of the fields of s.
+func (s *Struct) fields() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.Fields)
+}
@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"silk/internal/models"
+)
+
+// blockingExecutor returns an executor with a builtin "block" that runs until
+// its context ends, counting the calls still running, and builtins "soon" and
+// "fail" that shortly succeed and fail.
+func blockingExecutor(running *atomic.Int64) *Executor {
+	e := NewExecutor()
+	e.RegisterContextBuiltin("block", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		running.Add(1)
+		defer running.Add(-1)
+		<-ctx.Done()
+		// Linger, so that a construct that returns without waiting is caught.
+		time.Sleep(10 * time.Millisecond)
+		return nil, ctx.Err()
+	})
+	e.RegisterBuiltin("soon", func(args []interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return "soon", nil
+	})
+	e.RegisterBuiltin("fail", func(args []interface{}) (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, errors.New("failed")
+	})
+	return e
+}
+
+func TestNoBranchOutlivesConstruct(t *testing.T) {
+	nested := &models.ParallelBlock{Body: []models.Node{
+		call("block"),
+		&models.ParallelBlock{Body: []models.Node{call("block"), call("block")}},
+	}}
+	tests := []struct {
+		name string
+		node models.Node
+	}{
+		{"race", &models.Race{Body: []models.Node{nested, call("soon")}}},
+		{"timeout", &models.WithTimeout{Timeout: 5 * time.Millisecond, Body: []models.Node{nested}, Default: str("late")}},
+		{"failure", &models.ParallelBlock{Body: []models.Node{nested, call("fail")}}},
+		{"pipeline", &models.WithTimeout{Timeout: 5 * time.Millisecond, Body: []models.Node{&models.Pipeline{
+			Source: &models.ArrayLiteral{Elements: []models.Node{num(1), num(2)}},
+			Stages: []models.Node{ref("block")},
+		}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running atomic.Int64
+			e := blockingExecutor(&running)
+			e.SetMaxGoroutines(8)
+			e.Execute(program(tt.node))
+			if n := running.Load(); n != 0 {
+				t.Errorf("%d branches still running after the construct returned", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"silk/internal/models"
+)
+
+// countdownIn declares f(n), which returns "done" at zero and otherwise runs
+// wrap around a self call in tail position.
+func countdownIn(wrap func(...models.Node) models.Node, before ...models.Node) models.Node {
+	body := append(before, ret(call("f", binop(ref("n"), "-", num(1)))))
+	return function("f", []string{"n"},
+		&models.IfStatement{
+			Condition:  &models.ComparisonExpression{Left: ref("n"), Operator: "<=", Right: num(0)},
+			Consequent: ret(call("base")),
+		},
+		wrap(body...),
+	)
+}
+
+func TestTailCallKeepsTimeout(t *testing.T) {
+	e := NewExecutor()
+	e.RegisterBuiltin("base", func(args []interface{}) (interface{}, error) { return "done", nil })
+	timeout := func(body ...models.Node) models.Node {
+		return &models.WithTimeout{Timeout: 30 * time.Millisecond, Body: body}
+	}
+	_, err := e.Execute(program(countdownIn(timeout, call("sleep", num(10))), call("f", num(8))))
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+}
+
+func TestTailCallKeepsRetry(t *testing.T) {
+	e := NewExecutor()
+	calls := 0
+	e.RegisterBuiltin("base", func(args []interface{}) (interface{}, error) {
+		if calls++; calls < 3 {
+			return nil, errors.New("flaky")
+		}
+		return "done", nil
+	})
+	retry := func(body ...models.Node) models.Node {
+		return &models.Retry{Attempts: 3, Body: body}
+	}
+	val, err := e.Execute(program(countdownIn(retry), call("f", num(1))))
+	if err != nil || val != "done" {
+		t.Errorf("f(1) = %v, %v; want done after retries", val, err)
+	}
+}
+
+// below returns the condition n <= 0.
+func below(name string) models.Node {
+	return &models.ComparisonExpression{Left: ref(name), Operator: "<=", Right: num(0)}
+}
+
+func TestTailCallDoesNotGrowDepth(t *testing.T) {
+	// sum(n, acc) adds n, ..., 1 to acc, with its self call in tail position
+	// under an if, an else, and a loop.
+	sum := function("sum", []string{"n", "acc"},
+		&models.IfStatement{Condition: below("n"), Consequent: ret(ref("acc"))},
+		&models.IfStatement{
+			Condition:  &models.ComparisonExpression{Left: binop(ref("n"), "%", num(2)), Operator: "==", Right: num(0)},
+			Consequent: ret(call("sum", binop(ref("n"), "-", num(1)), binop(ref("acc"), "+", ref("n")))),
+			Alternate: &models.WhileLoop{Condition: &models.Boolean{Value: true}, Body: []models.Node{
+				ret(call("sum", binop(ref("n"), "-", num(1)), binop(ref("acc"), "+", ref("n")))),
+			}},
+		},
+	)
+	e := NewExecutor()
+	e.SetMaxCallDepth(50)
+	val, err := e.Execute(program(sum, call("sum", num(1000), num(0))))
+	if err != nil || val != int64(500500) {
+		t.Errorf("sum(1000, 0) = %v, %v; want 500500", val, err)
+	}
+}
+
+func TestTailCallArguments(t *testing.T) {
+	// swap(a, b, n) swaps a and b n times; the new arguments must be
+	// evaluated before any parameter is rebound.
+	swap := function("swap", []string{"a", "b", "n"},
+		&models.IfStatement{Condition: below("n"), Consequent: ret(list(ref("a"), ref("b")))},
+		ret(call("swap", ref("b"), ref("a"), binop(ref("n"), "-", num(1)))),
+	)
+	// start(n, from) counts down to from, which an omitted argument resets
+	// to its default.
+	start := &models.FunctionDeclaration{
+		Name:       "start",
+		Parameters: []*models.Variable{ref("n"), ref("from")},
+		Defaults:   []models.Node{nil, str("default")},
+		Body: []models.Node{
+			&models.IfStatement{Condition: below("n"), Consequent: ret(ref("from"))},
+			ret(call("start", binop(ref("n"), "-", num(1)))),
+		},
+	}
+	e := NewExecutor()
+	val, err := e.Execute(program(swap, call("swap", num(1), num(2), num(3))))
+	if err != nil || !reflect.DeepEqual(val, []interface{}{int64(2), int64(1)}) {
+		t.Errorf("swap(1, 2, 3) = %v, %v; want [2 1]", val, err)
+	}
+	val, err = e.Execute(program(start, call("start", num(2), str("given"))))
+	if err != nil || val != "default" {
+		t.Errorf("start(2, \"given\") = %v, %v; want default", val, err)
+	}
+}
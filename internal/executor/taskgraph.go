@@ -0,0 +1,126 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"silk/internal/models"
+)
+
+// handleTaskGraph runs the tasks of n on the scheduler in dependency order.
+// A task is submitted by whichever task completes its last dependency, as
+// taskGroup.Go allows, so that it can start without waiting for unrelated
+// tasks to finish.
+func (e *Executor) handleTaskGraph(n *models.TaskGraph, env *Environment) (interface{}, error) {
+	tasks, err := taskGraphOrder(n)
+	if err != nil {
+		return nil, err
+	}
+	waiting := make(map[string]int, len(tasks))
+	dependents := make(map[string][]*models.GraphTask)
+	for _, task := range tasks {
+		waiting[task.Name] = len(task.DependsOn)
+		for _, dep := range task.DependsOn {
+			dependents[dep] = append(dependents[dep], task)
+		}
+	}
+
+	cancel := e.newCancelScope(env.cancel, 0)
+	group := e.scheduler.group()
+	group.limit = n.MaxConcurrency
+	group.priority = cancel.priority
+	results := make(map[string]interface{}, len(tasks))
+	var failure error
+	var mu sync.Mutex
+
+	var run func(task *models.GraphTask)
+	run = func(task *models.GraphTask) {
+		group.Go(func() {
+			scope := newEnvironment(env)
+			scope.cancel = cancel
+			mu.Lock()
+			for _, dep := range task.DependsOn {
+				scope.define(dep, results[dep])
+			}
+			mu.Unlock()
+			var val interface{}
+			var err error
+			if e.dispatcher != nil {
+				// The body travels as a program, whose value is that of its
+				// last statement, with the results it depends on.
+				val, err = e.executeRemote(&models.Program{Body: task.Body}, scope)
+			} else {
+				val, err = protect(n, func() (interface{}, error) {
+					return e.blockValue(task.Body, scope)
+				})
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if failure == nil && !errors.Is(err, errCancelled) {
+					failure = fmt.Errorf("task %s: %w", task.Name, branchSignalError(err))
+				}
+				cancel.cancel()
+				return
+			}
+			results[task.Name] = val
+			if cancel.cancelled() {
+				return
+			}
+			for _, next := range dependents[task.Name] {
+				if waiting[next.Name]--; waiting[next.Name] == 0 {
+					run(next)
+				}
+			}
+		})
+	}
+	for _, task := range tasks {
+		if len(task.DependsOn) == 0 {
+			run(task)
+		}
+	}
+	group.Wait()
+
+	switch {
+	case env.cancel.interrupted():
+		return nil, ErrInterrupted
+	case env.cancel.cancelled():
+		return nil, errCancelled
+	case failure != nil:
+		return nil, failure
+	}
+	return results, nil
+}
+
+// taskGraphOrder checks that the tasks of n have distinct names, depend only
+// on tasks of n, and form no cycle, and returns them in a dependency order.
+func taskGraphOrder(n *models.TaskGraph) ([]*models.GraphTask, error) {
+	byName := make(map[string]*models.GraphTask, len(n.Tasks))
+	g := NewGraph()
+	for _, task := range n.Tasks {
+		if _, ok := byName[task.Name]; ok {
+			return nil, fmt.Errorf("task graph: duplicate task %s", task.Name)
+		}
+		byName[task.Name] = task
+		g.AddNode(task.Name)
+	}
+	for _, task := range n.Tasks {
+		for _, dep := range task.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("task graph: task %s depends on unknown task %s", task.Name, dep)
+			}
+			g.AddEdge(dep, task.Name, 0)
+		}
+	}
+	names, err := g.TopologicalSort()
+	if err != nil {
+		return nil, fmt.Errorf("task graph: %w", err)
+	}
+	tasks := make([]*models.GraphTask, len(names))
+	for i, name := range names {
+		tasks[i] = byName[name]
+	}
+	return tasks, nil
+}
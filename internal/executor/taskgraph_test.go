@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"silk/internal/models"
+)
+
+// diamond is a task graph in which d depends on b and c, which depend on a.
+func diamond(maxConcurrency int) *models.TaskGraph {
+	task := func(name string, body models.Node, deps ...string) *models.GraphTask {
+		return &models.GraphTask{Name: name, DependsOn: deps, Body: []models.Node{call("sleep", num(1)), body}}
+	}
+	return &models.TaskGraph{MaxConcurrency: maxConcurrency, Tasks: []*models.GraphTask{
+		task("a", num(1)),
+		task("b", binop(ref("a"), "+", num(1)), "a"),
+		task("c", binop(ref("a"), "+", num(2)), "a"),
+		task("d", binop(ref("b"), "+", ref("c")), "b", "c"),
+	}}
+}
+
+func TestTaskGraphSubmitsFromTasks(t *testing.T) {
+	for _, goroutines := range []int{1, 2, 8} {
+		for _, limit := range []int{0, 1} {
+			t.Run(fmt.Sprintf("goroutines=%d,limit=%d", goroutines, limit), func(t *testing.T) {
+				e := NewExecutor()
+				e.SetMaxGoroutines(goroutines)
+				// Several graphs at once, so that helpers run tasks that
+				// submit others.
+				val, err := e.Execute(program(&models.ParallelBlock{Body: []models.Node{
+					diamond(limit), diamond(limit), diamond(limit),
+				}}))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, graph := range val.([]interface{}) {
+					if d := graph.(map[string]interface{})["d"]; d != int64(5) {
+						t.Errorf("graph %d: d = %v, want 5", i, d)
+					}
+				}
+			})
+		}
+	}
+}
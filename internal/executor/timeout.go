@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"silk/internal/models"
+)
+
+// ErrTimeout is wrapped by the error of a WithTimeout node whose body ran past
+// its deadline.
+var ErrTimeout = errors.New("timeout exceeded")
+
+// errCancelled ends the evaluation of code whose cancelScope was cancelled. The
+// construct that cancelled the scope does not report it; it only escapes
+// through a task that outlives the construct, such as one started by Spawn and
+// joined after a timeout.
+var errCancelled = errors.New("evaluation cancelled")
+
+// cancelScope is the cancellation of the body of a WithTimeout, or of the
+// statements of a parallel construct when one fails or, in a Race, when one
+// succeeds. Environments created
+// while running the code, including those of the functions it calls and of
+// its nested parallel constructs and tasks, refer to it, and eval checks it
+// before every node. Scopes form a tree, so cancelling one cancels the scopes
+// nested in it.
+type cancelScope struct {
+	parent   *cancelScope       // Enclosing scope, if any.
+	ctx      context.Context    // Context passed to builtins called by the code.
+	stop     context.CancelFunc // Cancels ctx.
+	priority int                // Priority of the parallel constructs run under the scope.
+	done     atomic.Bool
+	stopped  atomic.Bool // Set on the scope of an Execute call that Stop aborted.
+}
+
+// newCancelScope creates a scope nested in parent, with the same priority.
+// Its context expires after timeout if timeout is positive.
+func (e *Executor) newCancelScope(parent *cancelScope, timeout time.Duration) *cancelScope {
+	c := &cancelScope{parent: parent}
+	if parent != nil {
+		c.priority = parent.priority
+	}
+	if timeout > 0 {
+		c.ctx, c.stop = context.WithTimeout(e.context(parent), timeout)
+	} else {
+		c.ctx, c.stop = context.WithCancel(e.context(parent))
+	}
+	return c
+}
+
+// cancel stops the code running under c at its next node.
+func (c *cancelScope) cancel() {
+	c.done.Store(true)
+	c.stop()
+}
+
+// context returns the context for a builtin called under cancel.
+func (e *Executor) context(cancel *cancelScope) context.Context {
+	if cancel == nil {
+		return context.Background()
+	}
+	return cancel.ctx
+}
+
+// interrupted reports whether the Execute call running the code under c has
+// been aborted by Stop.
+func (c *cancelScope) interrupted() bool {
+	for ; c != nil; c = c.parent {
+		if c.stopped.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelled reports whether c or an enclosing scope has been cancelled. A nil
+// scope is never cancelled.
+func (c *cancelScope) cancelled() bool {
+	for ; c != nil; c = c.parent {
+		if c.done.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// handleWithTimeout runs the body of n under a cancel scope that expires
+// after n.Timeout. A body that misses the deadline stops at its next node, and
+// the builtin it is blocked in, if any, sees its context expire. The body is
+// run on the calling goroutine, so nothing it started outlives the node.
+func (e *Executor) handleWithTimeout(n *models.WithTimeout, env *Environment) (interface{}, error) {
+	scope := newEnvironment(env)
+	scope.cancel = e.newCancelScope(env.cancel, n.Timeout)
+	defer scope.cancel.stop()
+
+	// The body may see the deadline pass, through its context, before the
+	// timer fires, so expiry is judged by the clock.
+	deadline := time.Now().Add(n.Timeout)
+	if n.Timeout > 0 {
+		timer := time.AfterFunc(n.Timeout, scope.cancel.cancel)
+		defer timer.Stop()
+	}
+	val, err := e.blockValue(n.Body, scope)
+	if n.Timeout <= 0 || time.Now().Before(deadline) {
+		return val, err
+	}
+	if n.Default != nil {
+		return e.eval(n.Default, env)
+	}
+	return nil, fmt.Errorf("%w after %v", ErrTimeout, n.Timeout)
+}
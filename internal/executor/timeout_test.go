@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"silk/internal/models"
+)
+
+func TestNestedTimeoutReportsOuterDeadline(t *testing.T) {
+	e := NewExecutor()
+	// Each level waits 10ms before starting the next, so the outermost
+	// deadline passes while the inner ones are still running.
+	var node models.Node = str("done")
+	for i := 0; i < 8; i++ {
+		node = &models.WithTimeout{Timeout: 30 * time.Millisecond, Body: []models.Node{call("sleep", num(10)), node}}
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := e.Execute(program(node)); !errors.Is(err, ErrTimeout) {
+			t.Fatalf("err = %v, want a timeout", err)
+		}
+	}
+}
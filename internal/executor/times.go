@@ -0,0 +1,244 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Times are time.Time values and durations are time.Duration values. The
+// arithmetic operators combine them as follows, and two times or two
+// durations may be compared:
+//
+//	time + duration, duration + time   time
+//	time - duration                    time
+//	time - time                        duration
+//	duration + duration, - likewise    duration
+//	duration * number, number * duration, duration / number
+//	                                   duration
+//	duration / duration                number
+
+// temporalBinary applies an arithmetic operator to times and durations,
+// reporting false if neither operand is one.
+func temporalBinary(operator string, left, right interface{}) (interface{}, bool, error) {
+	if !isTemporal(left) && !isTemporal(right) {
+		return nil, false, nil
+	}
+	// Integers scale durations as numbers do.
+	if i, ok := left.(int64); ok {
+		left = float64(i)
+	}
+	if i, ok := right.(int64); ok {
+		right = float64(i)
+	}
+	switch l := left.(type) {
+	case time.Time:
+		switch r := right.(type) {
+		case time.Duration:
+			switch operator {
+			case "+":
+				return l.Add(r), true, nil
+			case "-":
+				return l.Add(-r), true, nil
+			}
+		case time.Time:
+			if operator == "-" {
+				return l.Sub(r), true, nil
+			}
+		}
+	case time.Duration:
+		switch r := right.(type) {
+		case time.Time:
+			if operator == "+" {
+				return r.Add(l), true, nil
+			}
+		case time.Duration:
+			switch operator {
+			case "+":
+				return l + r, true, nil
+			case "-":
+				return l - r, true, nil
+			case "/":
+				if r == 0 {
+					return nil, true, errors.New("division by zero")
+				}
+				return float64(l) / float64(r), true, nil
+			}
+		case float64:
+			switch operator {
+			case "*":
+				return time.Duration(float64(l) * r), true, nil
+			case "/":
+				if r == 0 {
+					return nil, true, errors.New("division by zero")
+				}
+				return time.Duration(float64(l) / r), true, nil
+			}
+		}
+	case float64:
+		if r, ok := right.(time.Duration); ok && operator == "*" {
+			return time.Duration(l * float64(r)), true, nil
+		}
+	}
+	return nil, true, fmt.Errorf("operator %s is not defined for %s and %s", operator, typeName(left), typeName(right))
+}
+
+// temporalOrder compares two times or two durations, returning -1, 0, or +1.
+// It reports false for any other operands.
+func temporalOrder(left, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return l.Compare(r), true
+		}
+	case time.Duration:
+		if r, ok := right.(time.Duration); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			default:
+				return 0, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func isTemporal(v interface{}) bool {
+	switch v.(type) {
+	case time.Time, time.Duration:
+		return true
+	default:
+		return false
+	}
+}
+
+// registerTimeBuiltins registers builtins for times and durations:
+//
+//	now()                       the current time
+//	duration(value)             duration given as text, such as "1h30m" or "250ms",
+//	                            or as a number of seconds
+//	seconds(duration)           length of a duration in seconds
+//	parseTime(text[, layout])   time parsed from RFC 3339 text, or with a Go time layout
+//	formatTime(time[, layout])  time as RFC 3339 text, or formatted with a Go time layout
+//	unix(time)                  seconds since the Unix epoch
+//	sleep(ms)                   null after waiting ms milliseconds, or a duration
+//
+// A sleep ends early when the program is stopped or the code calling it is
+// cancelled, as by the deadline of a WithTimeout, so no goroutine is left
+// waiting on behalf of a program that has finished.
+func (e *Executor) registerTimeBuiltins() {
+	e.RegisterBuiltin("now", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("now", args, 0); err != nil {
+			return nil, err
+		}
+		return time.Now(), nil
+	})
+	e.RegisterBuiltin("duration", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("duration", args, 1); err != nil {
+			return nil, err
+		}
+		switch v := args[0].(type) {
+		case time.Duration:
+			return v, nil
+		case float64:
+			return time.Duration(v * float64(time.Second)), nil
+		case int64:
+			return time.Duration(v) * time.Second, nil
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("duration: %w", err)
+			}
+			return d, nil
+		default:
+			return nil, fmt.Errorf("duration: expected a string or number, got %v", v)
+		}
+	})
+	e.RegisterBuiltin("seconds", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("seconds", args, 1); err != nil {
+			return nil, err
+		}
+		d, ok := args[0].(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("seconds: expected a duration, got %v", args[0])
+		}
+		return d.Seconds(), nil
+	})
+	e.RegisterBuiltin("parseTime", func(args []interface{}) (interface{}, error) {
+		layout, err := layoutArg("parseTime", args)
+		if err != nil {
+			return nil, err
+		}
+		text, err := stringArg("parseTime", args[0])
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(layout, text)
+		if err != nil {
+			return nil, fmt.Errorf("parseTime: %w", err)
+		}
+		return t, nil
+	})
+	e.RegisterBuiltin("formatTime", func(args []interface{}) (interface{}, error) {
+		layout, err := layoutArg("formatTime", args)
+		if err != nil {
+			return nil, err
+		}
+		t, err := timeArg("formatTime", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return t.Format(layout), nil
+	})
+	e.RegisterBuiltin("unix", func(args []interface{}) (interface{}, error) {
+		if err := expectArgs("unix", args, 1); err != nil {
+			return nil, err
+		}
+		t, err := timeArg("unix", args[0])
+		if err != nil {
+			return nil, err
+		}
+		return float64(t.UnixNano()) / 1e9, nil
+	})
+	e.RegisterContextBuiltin("sleep", func(ctx context.Context, args []interface{}) (interface{}, error) {
+		if err := expectArgs("sleep", args, 1); err != nil {
+			return nil, err
+		}
+		d, ok := args[0].(time.Duration)
+		if !ok {
+			ms, isNumber := toFloat(args[0])
+			if !isNumber {
+				return nil, fmt.Errorf("sleep: expected a number of milliseconds or a duration, got %v", args[0])
+			}
+			d = time.Duration(ms * float64(time.Millisecond))
+		}
+		if d <= 0 {
+			return nil, nil
+		}
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, errCancelled
+		}
+	})
+}
+
+// layoutArg checks that a builtin got one argument and an optional time
+// layout, and returns the layout, which defaults to RFC 3339.
+func layoutArg(name string, args []interface{}) (string, error) {
+	switch len(args) {
+	case 1:
+		return time.RFC3339, nil
+	case 2:
+		return stringArg(name, args[1])
+	default:
+		return "", fmt.Errorf("%s expects 1 or 2 arguments, but got %d", name, len(args))
+	}
+}
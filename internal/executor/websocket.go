@@ -0,0 +1,257 @@
+package executor
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// WebSocketConn is an open WebSocket connection carrying text messages.
+// Send may be called concurrently with Receive.
+type WebSocketConn interface {
+	Send(message string) error
+	// Receive blocks for the next message. It returns io.EOF once the peer closes the connection.
+	Receive() (string, error)
+	Close() error
+}
+
+// WebSocketDialer opens WebSocket connections for the ws* builtins. The executor
+// ships with a minimal RFC 6455 client; hosts can substitute their own, for
+// example to add authentication headers or use a different library.
+type WebSocketDialer interface {
+	Dial(url string) (WebSocketConn, error)
+}
+
+// WebSocketDialerFunc adapts a function to the WebSocketDialer interface.
+type WebSocketDialerFunc func(url string) (WebSocketConn, error)
+
+// Dial calls f(url).
+func (f WebSocketDialerFunc) Dial(url string) (WebSocketConn, error) {
+	return f(url)
+}
+
+// DefaultWebSocketDialer dials ws:// and wss:// URLs using only the standard library.
+var DefaultWebSocketDialer WebSocketDialer = WebSocketDialerFunc(dialWebSocket)
+
+// WebSocket opcodes from RFC 6455, section 5.2.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsMaxFrameSize bounds the payload of a single received frame.
+const wsMaxFrameSize = 32 << 20
+
+// wsAcceptGUID is mixed into the handshake key to prove the server speaks WebSocket.
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsClientConn is the standard-library WebSocket client connection.
+type wsClientConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	closed  bool // Whether a close frame has been sent; guarded by writeMu.
+}
+
+// dialWebSocket performs the opening handshake and returns the connection.
+func dialWebSocket(rawURL string) (WebSocketConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		conn, err = net.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		conn, err = tls.Dial("tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket scheme: %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	httpURL := *u
+	httpURL.Scheme = map[string]string{"ws": "http", "wss": "https"}[u.Scheme]
+	req, err := http.NewRequest(http.MethodGet, httpURL.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	if resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, errors.New("websocket handshake failed: invalid Sec-WebSocket-Accept")
+	}
+	return &wsClientConn{conn: conn, reader: reader}, nil
+}
+
+// Send writes message as a single text frame.
+func (c *wsClientConn) Send(message string) error {
+	return c.writeFrame(wsText, []byte(message))
+}
+
+// Receive reads frames until a complete data message arrives, answering pings
+// and reassembling fragmented messages along the way.
+func (c *wsClientConn) Receive() (string, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return "", err
+		}
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return "", err
+			}
+		case wsPong:
+		case wsClose:
+			c.writeFrame(wsClose, payload)
+			return "", io.EOF
+		case wsText, wsBinary, wsContinuation:
+			message = append(message, payload...)
+			if fin {
+				return string(message), nil
+			}
+		default:
+			return "", fmt.Errorf("websocket: unknown opcode %#x", opcode)
+		}
+	}
+}
+
+// Close sends a normal-closure frame and closes the underlying connection.
+func (c *wsClientConn) Close() error {
+	c.writeFrame(wsClose, []byte{0x03, 0xE8}) // Status 1000: normal closure.
+	return c.conn.Close()
+}
+
+// writeFrame writes a single masked frame, as required of clients.
+func (c *wsClientConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	if opcode == wsClose {
+		c.closed = true
+	}
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	header = append(header, mask[:]...)
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	_, err := c.conn.Write(append(header, masked...))
+	return err
+}
+
+// readFrame reads a single frame, unmasking it if necessary.
+func (c *wsClientConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.reader, head[:]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.reader, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxFrameSize {
+		err = fmt.Errorf("websocket: frame of %d bytes exceeds limit", length)
+		return
+	}
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
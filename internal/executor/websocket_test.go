@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chanConn is a WebSocketConn whose incoming messages are sent on in.
+type chanConn struct {
+	in   chan string
+	once sync.Once
+}
+
+func (c *chanConn) Send(message string) error { return nil }
+
+func (c *chanConn) Receive() (string, error) {
+	m, ok := <-c.in
+	if !ok {
+		return "", io.EOF
+	}
+	return m, nil
+}
+
+func (c *chanConn) Close() error {
+	c.once.Do(func() { close(c.in) })
+	return nil
+}
+
+func TestWebSocketCloseFromHandler(t *testing.T) {
+	conn := &chanConn{in: make(chan string, 1)}
+	conn.in <- "hello"
+	e := NewExecutor()
+	e.RegisterWebSocketBuiltins()
+	e.SetWebSocketDialer(WebSocketDialerFunc(func(url string) (WebSocketConn, error) {
+		return conn, nil
+	}))
+	closed := make(chan struct{})
+	e.RegisterBuiltin("closed", func(args []interface{}) (interface{}, error) {
+		close(closed)
+		return nil, nil
+	})
+	_, err := e.Execute(program(
+		assign("ws", call("wsOpen", str("ws://test"))),
+		function("onMessage", []string{"m"}, call("wsClose", ref("ws")), call("closed")),
+		call("wsOnMessage", ref("ws"), ref("onMessage")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("wsClose called from the message handler did not return")
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/gob"
+	"io"
+)
+
+// Node types are registered with encoding/gob so that subtrees can be shipped
+// to remote workers through interface-typed fields.
+func init() {
+	gob.Register(&Program{})
+	gob.Register(&Comment{})
+	gob.Register(&AsyncCall{})
+	gob.Register(&Await{})
+	gob.Register(&Spawn{})
+	gob.Register(&WithTimeout{})
+	gob.Register(&Retry{})
+	gob.Register(&Pipeline{})
+	gob.Register(&TaskGraph{})
+	gob.Register(&Race{})
+	gob.Register(&ParallelForLoop{})
+	gob.Register(&Number{})
+	gob.Register(&Variable{})
+	gob.Register(&BinaryExpression{})
+	gob.Register(&UnaryExpression{})
+	gob.Register(&LogicalExpression{})
+	gob.Register(&Assignment{})
+	gob.Register(&VariableDeclaration{})
+	gob.Register(&ConstDeclaration{})
+	gob.Register(&MultiAssignment{})
+	gob.Register(&CompoundAssignment{})
+	gob.Register(&IncDecStatement{})
+	gob.Register(&IfStatement{})
+	gob.Register(&String{})
+	gob.Register(&Duration{})
+	gob.Register(&Integer{})
+	gob.Register(&BigInt{})
+	gob.Register(&Decimal{})
+	gob.Register(&Regex{})
+	gob.Register(&MatchesExpression{})
+	gob.Register(&Boolean{})
+	gob.Register(&Null{})
+	gob.Register(&TemplateString{})
+	gob.Register(&ArrayLiteral{})
+	gob.Register(&Spread{})
+	gob.Register(&IndexExpression{})
+	gob.Register(&MapLiteral{})
+	gob.Register(&SliceExpression{})
+	gob.Register(&Range{})
+	gob.Register(&IndexAssignment{})
+	gob.Register(&StructDeclaration{})
+	gob.Register(&EnumDeclaration{})
+	gob.Register(&ImportStatement{})
+	gob.Register(&MethodDeclaration{})
+	gob.Register(&StructLiteral{})
+	gob.Register(&MemberExpression{})
+	gob.Register(&MemberAssignment{})
+	gob.Register(&MatchExpression{})
+	gob.Register(&TypePattern{})
+	gob.Register(&ComparisonExpression{})
+	gob.Register(&ComparisonChain{})
+	gob.Register(&ParallelBlock{})
+	gob.Register(&FunctionCall{})
+	gob.Register(&FunctionDeclaration{})
+	gob.Register(&FunctionLiteral{})
+	gob.Register(&ForLoop{})
+	gob.Register(&WhileLoop{})
+	gob.Register(&ForEachLoop{})
+	gob.Register(&Break{})
+	gob.Register(&Continue{})
+	gob.Register(&YieldStatement{})
+	gob.Register(&Assert{})
+	gob.Register(&ReturnStatement{})
+	gob.Register(&Cached{})
+}
+
+// EncodeProgram writes program to w in the gob encoding read by DecodeProgram.
+func EncodeProgram(w io.Writer, program *Program) error {
+	return gob.NewEncoder(w).Encode(program)
+}
+
+// DecodeProgram reads a program written by EncodeProgram.
+func DecodeProgram(r io.Reader) (*Program, error) {
+	var program Program
+	if err := gob.NewDecoder(r).Decode(&program); err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func tag(name string) Metadata { return Metadata{"node": name} }
+
+func TestMetadataRoundTrip(t *testing.T) {
+	nodes := []Node{
+		&Comment{Metadata: tag("Comment")},
+		&Program{Metadata: tag("Program")},
+		&Number{Metadata: tag("Number")},
+		&Variable{Metadata: tag("Variable")},
+		&BinaryExpression{Metadata: tag("BinaryExpression")},
+		&UnaryExpression{Metadata: tag("UnaryExpression")},
+		&LogicalExpression{Metadata: tag("LogicalExpression")},
+		&Assignment{Metadata: tag("Assignment")},
+		&VariableDeclaration{Metadata: tag("VariableDeclaration")},
+		&ConstDeclaration{Metadata: tag("ConstDeclaration")},
+		&MultiAssignment{Metadata: tag("MultiAssignment")},
+		&CompoundAssignment{Metadata: tag("CompoundAssignment")},
+		&IncDecStatement{Metadata: tag("IncDecStatement")},
+		&IfStatement{Metadata: tag("IfStatement")},
+		&String{Metadata: tag("String")},
+		&Integer{Metadata: tag("Integer")},
+		&BigInt{Metadata: tag("BigInt")},
+		&Decimal{Metadata: tag("Decimal")},
+		&Duration{Metadata: tag("Duration")},
+		&Boolean{Metadata: tag("Boolean")},
+		&Null{Metadata: tag("Null")},
+		&TemplateString{Metadata: tag("TemplateString")},
+		&Spread{Metadata: tag("Spread")},
+		&ArrayLiteral{Metadata: tag("ArrayLiteral")},
+		&MapLiteral{Metadata: tag("MapLiteral")},
+		&IndexExpression{Metadata: tag("IndexExpression")},
+		&Range{Metadata: tag("Range")},
+		&SliceExpression{Metadata: tag("SliceExpression")},
+		&IndexAssignment{Metadata: tag("IndexAssignment")},
+		&StructDeclaration{Metadata: tag("StructDeclaration")},
+		&ImportStatement{Metadata: tag("ImportStatement")},
+		&EnumDeclaration{Metadata: tag("EnumDeclaration")},
+		&MethodDeclaration{Metadata: tag("MethodDeclaration")},
+		&StructLiteral{Metadata: tag("StructLiteral")},
+		&MemberExpression{Metadata: tag("MemberExpression")},
+		&MemberAssignment{Metadata: tag("MemberAssignment")},
+		&MatchExpression{Metadata: tag("MatchExpression")},
+		&TypePattern{Metadata: tag("TypePattern")},
+		&ComparisonChain{Metadata: tag("ComparisonChain")},
+		&Regex{Metadata: tag("Regex")},
+		&MatchesExpression{Metadata: tag("MatchesExpression")},
+		&ComparisonExpression{Metadata: tag("ComparisonExpression")},
+		&ParallelBlock{Metadata: tag("ParallelBlock")},
+		&AsyncCall{Metadata: tag("AsyncCall")},
+		&Await{Metadata: tag("Await")},
+		&Spawn{Metadata: tag("Spawn")},
+		&FunctionCall{Metadata: tag("FunctionCall")},
+		&FunctionDeclaration{Metadata: tag("FunctionDeclaration")},
+		&FunctionLiteral{Metadata: tag("FunctionLiteral")},
+		&ForLoop{Metadata: tag("ForLoop")},
+		&WhileLoop{Metadata: tag("WhileLoop")},
+		&ForEachLoop{Metadata: tag("ForEachLoop")},
+		&ParallelForLoop{Metadata: tag("ParallelForLoop")},
+		&Pipeline{Metadata: tag("Pipeline")},
+		&TaskGraph{Metadata: tag("TaskGraph")},
+		&Race{Metadata: tag("Race")},
+		&Break{Metadata: tag("Break")},
+		&Continue{Metadata: tag("Continue")},
+		&YieldStatement{Metadata: tag("YieldStatement")},
+		&Assert{Metadata: tag("Assert")},
+		&ReturnStatement{Metadata: tag("ReturnStatement")},
+		&Cached{Metadata: tag("Cached")},
+		&WithTimeout{Metadata: tag("WithTimeout")},
+		&Retry{Metadata: tag("Retry")},
+	}
+	program := &Program{Body: nodes}
+	var buf bytes.Buffer
+	if err := EncodeProgram(&buf, program); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeProgram(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Body) != len(nodes) {
+		t.Fatalf("decoded %d nodes, want %d", len(decoded.Body), len(nodes))
+	}
+	for i, node := range decoded.Body {
+		name := reflect.TypeOf(nodes[i]).Elem().Name()
+		if got := MetadataOf(node); !reflect.DeepEqual(got, tag(name)) {
+			t.Errorf("%s: metadata after round trip = %v, want %v", name, got, tag(name))
+		}
+		if got, want := fmt.Sprintf("%T", node), fmt.Sprintf("%T", nodes[i]); got != want {
+			t.Errorf("node %d decoded as %s, want %s", i, got, want)
+		}
+	}
+}
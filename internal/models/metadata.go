@@ -0,0 +1,142 @@
+package models
+
+// Metadata holds annotations that tools attach to the nodes they generate, such
+// as the source a node was translated from or its author. The executor ignores
+// metadata; EncodeProgram and DecodeProgram preserve it.
+type Metadata map[string]string
+
+// MetadataOf returns the metadata attached to node, or nil if it has none.
+func MetadataOf(node Node) Metadata {
+	switch n := node.(type) {
+	case *Comment:
+		return n.Metadata
+	case *Program:
+		return n.Metadata
+	case *Number:
+		return n.Metadata
+	case *Variable:
+		return n.Metadata
+	case *BinaryExpression:
+		return n.Metadata
+	case *UnaryExpression:
+		return n.Metadata
+	case *LogicalExpression:
+		return n.Metadata
+	case *Assignment:
+		return n.Metadata
+	case *VariableDeclaration:
+		return n.Metadata
+	case *ConstDeclaration:
+		return n.Metadata
+	case *MultiAssignment:
+		return n.Metadata
+	case *CompoundAssignment:
+		return n.Metadata
+	case *IncDecStatement:
+		return n.Metadata
+	case *IfStatement:
+		return n.Metadata
+	case *String:
+		return n.Metadata
+	case *Integer:
+		return n.Metadata
+	case *BigInt:
+		return n.Metadata
+	case *Decimal:
+		return n.Metadata
+	case *Duration:
+		return n.Metadata
+	case *Boolean:
+		return n.Metadata
+	case *Null:
+		return n.Metadata
+	case *TemplateString:
+		return n.Metadata
+	case *Spread:
+		return n.Metadata
+	case *ArrayLiteral:
+		return n.Metadata
+	case *MapLiteral:
+		return n.Metadata
+	case *IndexExpression:
+		return n.Metadata
+	case *Range:
+		return n.Metadata
+	case *SliceExpression:
+		return n.Metadata
+	case *IndexAssignment:
+		return n.Metadata
+	case *StructDeclaration:
+		return n.Metadata
+	case *ImportStatement:
+		return n.Metadata
+	case *EnumDeclaration:
+		return n.Metadata
+	case *MethodDeclaration:
+		return n.Metadata
+	case *StructLiteral:
+		return n.Metadata
+	case *MemberExpression:
+		return n.Metadata
+	case *MemberAssignment:
+		return n.Metadata
+	case *MatchExpression:
+		return n.Metadata
+	case *TypePattern:
+		return n.Metadata
+	case *ComparisonChain:
+		return n.Metadata
+	case *Regex:
+		return n.Metadata
+	case *MatchesExpression:
+		return n.Metadata
+	case *ComparisonExpression:
+		return n.Metadata
+	case *ParallelBlock:
+		return n.Metadata
+	case *AsyncCall:
+		return n.Metadata
+	case *Await:
+		return n.Metadata
+	case *Spawn:
+		return n.Metadata
+	case *FunctionCall:
+		return n.Metadata
+	case *FunctionDeclaration:
+		return n.Metadata
+	case *FunctionLiteral:
+		return n.Metadata
+	case *ForLoop:
+		return n.Metadata
+	case *WhileLoop:
+		return n.Metadata
+	case *ForEachLoop:
+		return n.Metadata
+	case *ParallelForLoop:
+		return n.Metadata
+	case *Pipeline:
+		return n.Metadata
+	case *TaskGraph:
+		return n.Metadata
+	case *Race:
+		return n.Metadata
+	case *Break:
+		return n.Metadata
+	case *Continue:
+		return n.Metadata
+	case *YieldStatement:
+		return n.Metadata
+	case *Assert:
+		return n.Metadata
+	case *ReturnStatement:
+		return n.Metadata
+	case *Cached:
+		return n.Metadata
+	case *WithTimeout:
+		return n.Metadata
+	case *Retry:
+		return n.Metadata
+	default:
+		return nil
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type NodeType string
 
 const (
@@ -11,14 +13,76 @@ const (
 	NodeTypeIf              NodeType = "IfStatement"
 	NodeTypeFunctionCall    NodeType = "FunctionCall"
 	NodeTypeReturnStatement NodeType = "ReturnStatement"
+	NodeTypeCached          NodeType = "Cached"
+	NodeTypeBoolean         NodeType = "Boolean"
+	NodeTypeNull            NodeType = "Null"
+	NodeTypeArrayLiteral    NodeType = "ArrayLiteral"
+	NodeTypeIndexExpression NodeType = "IndexExpression"
+	NodeTypeMapLiteral      NodeType = "MapLiteral"
+	NodeTypeIndexAssignment NodeType = "IndexAssignment"
+	NodeTypeLogicalExpr     NodeType = "LogicalExpression"
+	NodeTypeUnaryExpr       NodeType = "UnaryExpression"
+	NodeTypeCompoundAssign  NodeType = "CompoundAssignment"
+	NodeTypeIncDec          NodeType = "IncDecStatement"
+	NodeTypeBreak           NodeType = "Break"
+	NodeTypeContinue        NodeType = "Continue"
+	NodeTypeForEach         NodeType = "ForEachLoop"
+	NodeTypeFunctionLiteral NodeType = "FunctionLiteral"
+	NodeTypeMultiAssign     NodeType = "MultiAssignment"
+	NodeTypeStructDecl      NodeType = "StructDeclaration"
+	NodeTypeStructLiteral   NodeType = "StructLiteral"
+	NodeTypeMember          NodeType = "MemberExpression"
+	NodeTypeMemberAssign    NodeType = "MemberAssignment"
+	NodeTypeMethodDecl      NodeType = "MethodDeclaration"
+	NodeTypeAssert          NodeType = "Assert"
+	NodeTypeMatch           NodeType = "MatchExpression"
+	NodeTypeTypePattern     NodeType = "TypePattern"
+	NodeTypeYield           NodeType = "YieldStatement"
+	NodeTypeTemplate        NodeType = "TemplateString"
+	NodeTypeSlice           NodeType = "SliceExpression"
+	NodeTypeConst           NodeType = "ConstDeclaration"
+	NodeTypeVarDecl         NodeType = "VariableDeclaration"
+	NodeTypeEnumDecl        NodeType = "EnumDeclaration"
+	NodeTypeImport          NodeType = "ImportStatement"
+	NodeTypeRange           NodeType = "Range"
+	NodeTypeSpread          NodeType = "Spread"
+	NodeTypeChain           NodeType = "ComparisonChain"
+	NodeTypeDuration        NodeType = "Duration"
+	NodeTypeInteger         NodeType = "Integer"
+	NodeTypeBigInt          NodeType = "BigInt"
+	NodeTypeDecimal         NodeType = "Decimal"
+	NodeTypeRegex           NodeType = "Regex"
+	NodeTypeMatches         NodeType = "MatchesExpression"
+	NodeTypeComment         NodeType = "Comment"
+	NodeTypeAsyncCall       NodeType = "AsyncCall"
+	NodeTypeAwait           NodeType = "Await"
+	NodeTypeParallelFor     NodeType = "ParallelForLoop"
+	NodeTypeSpawn           NodeType = "Spawn"
+	NodeTypeWithTimeout     NodeType = "WithTimeout"
+	NodeTypeRetry           NodeType = "Retry"
+	NodeTypePipeline        NodeType = "Pipeline"
+	NodeTypeTaskGraph       NodeType = "TaskGraph"
+	NodeTypeRace            NodeType = "Race"
 )
 
 type Node interface {
 	GetType() NodeType
 }
 
+// Comment is a comment kept in the AST, so that tools generating silk can
+// carry notes through it. Executing a comment does nothing.
+type Comment struct {
+	Text     string
+	Metadata Metadata
+}
+
+func (c *Comment) GetType() NodeType {
+	return NodeTypeComment
+}
+
 type Program struct {
-	Body []Node
+	Body     []Node
+	Metadata Metadata
 }
 
 func (p *Program) GetType() NodeType {
@@ -26,7 +90,8 @@ func (p *Program) GetType() NodeType {
 }
 
 type Number struct {
-	Value float64
+	Value    float64
+	Metadata Metadata
 }
 
 func (n *Number) GetType() NodeType {
@@ -34,7 +99,8 @@ func (n *Number) GetType() NodeType {
 }
 
 type Variable struct {
-	Name string
+	Name     string
+	Metadata Metadata
 }
 
 func (v *Variable) GetType() NodeType {
@@ -45,25 +111,122 @@ type BinaryExpression struct {
 	Operator string
 	Left     Node
 	Right    Node
+	Pos      Position
+	Metadata Metadata
 }
 
 func (be *BinaryExpression) GetType() NodeType {
 	return NodeTypeBinaryExpr
 }
 
+// UnaryExpression applies a prefix operator to Operand: "-" negates a number
+// and "!" negates a condition.
+type UnaryExpression struct {
+	Operator string
+	Operand  Node
+	Metadata Metadata
+}
+
+func (ue *UnaryExpression) GetType() NodeType {
+	return NodeTypeUnaryExpr
+}
+
+// LogicalExpression combines conditions with "&&" or "||", evaluating Right only
+// when Left does not already decide the result. The "!" operator negates Right
+// and ignores Left.
+type LogicalExpression struct {
+	Operator string
+	Left     Node
+	Right    Node
+	Metadata Metadata
+}
+
+func (le *LogicalExpression) GetType() NodeType {
+	return NodeTypeLogicalExpr
+}
+
 type Assignment struct {
 	Variable *Variable
 	Value    Node
+	Pos      Position
+	Metadata Metadata
 }
 
 func (a *Assignment) GetType() NodeType {
 	return NodeTypeAssignment
 }
 
+// VariableDeclaration declares Variable in the current scope, as in "let x = 1",
+// shadowing any variable of the same name in an enclosing scope. A nil Value
+// initializes the variable to null.
+type VariableDeclaration struct {
+	Variable *Variable
+	Type     string // Optional type annotation, checked by package typecheck.
+	Value    Node
+	Pos      Position
+	Metadata Metadata
+}
+
+func (vd *VariableDeclaration) GetType() NodeType {
+	return NodeTypeVarDecl
+}
+
+// ConstDeclaration binds Variable to Value in the current scope. Any later
+// assignment to the name in that scope fails; the value itself, such as a list
+// or map, may still be modified in place.
+type ConstDeclaration struct {
+	Variable *Variable
+	Type     string // Optional type annotation, checked by package typecheck.
+	Value    Node
+	Pos      Position // Where the declaration appears in the source, if known.
+	Metadata Metadata
+}
+
+func (cd *ConstDeclaration) GetType() NodeType {
+	return NodeTypeConst
+}
+
+// MultiAssignment unpacks a tuple or list into several variables, as in
+// "q, r = divmod(7, 2)". The value must have exactly one element per variable.
+type MultiAssignment struct {
+	Variables []*Variable
+	Value     Node
+	Metadata  Metadata
+}
+
+func (ma *MultiAssignment) GetType() NodeType {
+	return NodeTypeMultiAssign
+}
+
+// CompoundAssignment updates a variable in place, as in "x += 1". Operator is
+// an arithmetic or bitwise operator followed by "=", such as "+=" or "<<=".
+type CompoundAssignment struct {
+	Variable *Variable
+	Operator string
+	Value    Node
+	Metadata Metadata
+}
+
+func (ca *CompoundAssignment) GetType() NodeType {
+	return NodeTypeCompoundAssign
+}
+
+// IncDecStatement adds one to a variable ("++") or subtracts one from it ("--").
+type IncDecStatement struct {
+	Variable *Variable
+	Operator string
+	Metadata Metadata
+}
+
+func (ids *IncDecStatement) GetType() NodeType {
+	return NodeTypeIncDec
+}
+
 type IfStatement struct {
 	Condition  Node
 	Consequent Node
 	Alternate  Node
+	Metadata   Metadata
 }
 
 func (ifs *IfStatement) GetType() NodeType {
@@ -71,34 +234,480 @@ func (ifs *IfStatement) GetType() NodeType {
 }
 
 type String struct {
-	Value string
+	Value    string
+	Metadata Metadata
 }
 
 func (s *String) GetType() NodeType {
 	return "String"
 }
 
+// Integer is a 64-bit integer literal, as in "42i".
+type Integer struct {
+	Value    int64
+	Metadata Metadata
+}
+
+func (i *Integer) GetType() NodeType {
+	return NodeTypeInteger
+}
+
+// BigInt is an arbitrary-precision integer literal, as in "9007199254740993n".
+// Value holds its decimal digits, optionally preceded by a sign.
+type BigInt struct {
+	Value    string
+	Metadata Metadata
+}
+
+func (b *BigInt) GetType() NodeType {
+	return NodeTypeBigInt
+}
+
+// Decimal is an exact decimal literal, as in "19.99d". Value holds its text,
+// with any digits after the point kept, so "2.50" keeps its scale of two.
+type Decimal struct {
+	Value    string
+	Metadata Metadata
+}
+
+func (d *Decimal) GetType() NodeType {
+	return NodeTypeDecimal
+}
+
+// Duration is a literal length of time, as in "5m" or "250ms".
+type Duration struct {
+	Value    time.Duration
+	Metadata Metadata
+}
+
+func (d *Duration) GetType() NodeType {
+	return NodeTypeDuration
+}
+
+// Boolean is a true or false literal.
+type Boolean struct {
+	Value    bool
+	Metadata Metadata
+}
+
+func (b *Boolean) GetType() NodeType {
+	return NodeTypeBoolean
+}
+
+// Null is the literal that represents the absence of a value.
+type Null struct {
+	Metadata Metadata
+}
+
+func (n *Null) GetType() NodeType {
+	return NodeTypeNull
+}
+
+// TemplateString concatenates the text of its Parts, as in "Hello, ${name}!".
+// Literal text is given as String parts; any other part is an expression whose
+// value is converted to text.
+type TemplateString struct {
+	Parts    []Node
+	Metadata Metadata
+}
+
+func (ts *TemplateString) GetType() NodeType {
+	return NodeTypeTemplate
+}
+
+// Spread expands the list, tuple, or iterator that Value evaluates to into
+// separate values, as in "f(...args)" or "[0, ...rest]". It may appear only
+// among the arguments of a FunctionCall or the elements of an ArrayLiteral.
+type Spread struct {
+	Value    Node
+	Metadata Metadata
+}
+
+func (s *Spread) GetType() NodeType {
+	return NodeTypeSpread
+}
+
+// ArrayLiteral constructs a list from the values of its elements.
+type ArrayLiteral struct {
+	Elements []Node
+	Metadata Metadata
+}
+
+func (al *ArrayLiteral) GetType() NodeType {
+	return NodeTypeArrayLiteral
+}
+
+// MapLiteral constructs a map from string keys to values.
+type MapLiteral struct {
+	Entries  []MapEntry
+	Metadata Metadata
+}
+
+// MapEntry is a single key/value pair of a MapLiteral. Key must evaluate to a string.
+type MapEntry struct {
+	Key   Node
+	Value Node
+}
+
+func (ml *MapLiteral) GetType() NodeType {
+	return NodeTypeMapLiteral
+}
+
+// IndexExpression reads the element at Index of the list or map that Object
+// evaluates to. If Optional is set, as in `m?["k"]`, a nil Object yields nil
+// instead of an error, and Index is not evaluated.
+type IndexExpression struct {
+	Object   Node
+	Index    Node
+	Optional bool
+	Metadata Metadata
+}
+
+func (ie *IndexExpression) GetType() NodeType {
+	return NodeTypeIndexExpression
+}
+
+// Range evaluates to the numbers from Start to End, as in "1..10", counting by
+// Step. End is included unless Exclusive is set, as in "0..<n". If Step is
+// nil, the range counts up by 1, or down by 1 when End is less than Start. The
+// numbers are produced lazily, as an iterator.
+type Range struct {
+	Start     Node
+	End       Node
+	Step      Node
+	Exclusive bool
+	Metadata  Metadata
+}
+
+func (r *Range) GetType() NodeType {
+	return NodeTypeRange
+}
+
+// SliceExpression copies the elements of a list, or the characters of a string,
+// from Start up to but not including End, as in "list[1:4]". Either bound may
+// be nil to slice from the beginning or to the end, and a negative bound counts
+// back from the end.
+type SliceExpression struct {
+	Object   Node
+	Start    Node
+	End      Node
+	Metadata Metadata
+}
+
+func (se *SliceExpression) GetType() NodeType {
+	return NodeTypeSlice
+}
+
+// IndexAssignment stores Value at Index of the list, or under the key Index of
+// the map, that Object evaluates to.
+type IndexAssignment struct {
+	Object   Node
+	Index    Node
+	Value    Node
+	Metadata Metadata
+}
+
+func (ia *IndexAssignment) GetType() NodeType {
+	return NodeTypeIndexAssignment
+}
+
+// StructDeclaration declares a record type with the named fields.
+type StructDeclaration struct {
+	Name     string
+	Fields   []string
+	Metadata Metadata
+}
+
+func (sd *StructDeclaration) GetType() NodeType {
+	return NodeTypeStructDecl
+}
+
+// ImportStatement loads the module at Path and binds the functions and
+// constants declared at its top level. Names, if set, limits the import to
+// those names. Alias, if set, binds the module itself under that name instead,
+// and its exports are read as members ("alias.name"); Names must then be empty.
+type ImportStatement struct {
+	Path     string
+	Names    []string
+	Alias    string
+	Metadata Metadata
+}
+
+func (is *ImportStatement) GetType() NodeType {
+	return NodeTypeImport
+}
+
+// EnumDeclaration binds Name to a set of symbolic constants, read as members
+// of it ("Color.Red"). A variable declared with the enum's name as its type may
+// only hold members of the enum, or null.
+type EnumDeclaration struct {
+	Name     string
+	Members  []string
+	Metadata Metadata
+}
+
+func (ed *EnumDeclaration) GetType() NodeType {
+	return NodeTypeEnumDecl
+}
+
+// MethodDeclaration attaches Function to the struct type Type. When the method
+// is called as "value.name(args)", Receiver is bound to the value.
+type MethodDeclaration struct {
+	Type     string
+	Receiver *Variable
+	Function *FunctionDeclaration
+	Metadata Metadata
+}
+
+func (md *MethodDeclaration) GetType() NodeType {
+	return NodeTypeMethodDecl
+}
+
+// StructLiteral creates an instance of the struct type Name. Fields that are
+// not listed start out null.
+type StructLiteral struct {
+	Name     string
+	Fields   []FieldValue
+	Metadata Metadata
+}
+
+// FieldValue initializes one field of a StructLiteral.
+type FieldValue struct {
+	Name  string
+	Value Node
+}
+
+func (sl *StructLiteral) GetType() NodeType {
+	return NodeTypeStructLiteral
+}
+
+// MemberExpression reads the field Property of the struct that Object
+// evaluates to, as in "point.x". On a map it reads the key Property. If
+// Optional is set, as in "point?.x", a nil Object yields nil instead of an
+// error. Each optional access guards only its own object, so a chain that may
+// break at several points is written "a?.b?.c".
+type MemberExpression struct {
+	Object   Node
+	Property string
+	Optional bool
+	Metadata Metadata
+}
+
+func (me *MemberExpression) GetType() NodeType {
+	return NodeTypeMember
+}
+
+// MemberAssignment stores Value in the field Property of the struct, or under
+// the key Property of the map, that Object evaluates to.
+type MemberAssignment struct {
+	Object   Node
+	Property string
+	Value    Node
+	Metadata Metadata
+}
+
+func (ma *MemberAssignment) GetType() NodeType {
+	return NodeTypeMemberAssign
+}
+
+// MatchExpression evaluates Value and then the Body of the first arm whose
+// pattern matches it and whose Guard, if set, is truthy.
+//
+// A pattern is one of:
+//   - a Variable, which matches anything and binds it ("_" binds nothing);
+//   - a TypePattern, which matches values of a type;
+//   - an ArrayLiteral of patterns, which matches a list or tuple of the same length;
+//   - a MapLiteral of patterns, which matches a map or struct having each key;
+//   - any other expression, which matches values equal to it.
+//
+// Variables bound by a pattern are visible to the arm's Guard and Body only.
+type MatchExpression struct {
+	Value    Node
+	Arms     []MatchArm
+	Metadata Metadata
+}
+
+// MatchArm is one case of a MatchExpression.
+type MatchArm struct {
+	Pattern Node
+	Guard   Node
+	Body    Node
+}
+
+func (me *MatchExpression) GetType() NodeType {
+	return NodeTypeMatch
+}
+
+// TypePattern matches values whose type is Type: "null", "number", "string",
+// "bool", "list", "map", "tuple", "function", "generator", "matrix", "range",
+// or the name of a struct or enum type. If Pattern is set, the value must also
+// match it.
+type TypePattern struct {
+	Type     string
+	Pattern  Node
+	Metadata Metadata
+}
+
+func (tp *TypePattern) GetType() NodeType {
+	return NodeTypeTypePattern
+}
+
+// ComparisonChain compares each operand with the next, as in "0 < x < 100",
+// and is true if every comparison holds. Operators[i] compares Operands[i]
+// with Operands[i+1]. Each operand is evaluated at most once, and evaluation
+// stops at the first comparison that fails.
+type ComparisonChain struct {
+	Operands  []Node
+	Operators []string
+	Metadata  Metadata
+}
+
+func (cc *ComparisonChain) GetType() NodeType {
+	return NodeTypeChain
+}
+
+// Regex is a regular expression literal, as in "/^a+$/i". Pattern uses the
+// syntax of Go's regexp package. Flags may contain "i" to ignore case, "m" for
+// ^ and $ to match at line breaks, and "s" for . to match a line break.
+type Regex struct {
+	Pattern  string
+	Flags    string
+	Metadata Metadata
+}
+
+func (r *Regex) GetType() NodeType {
+	return NodeTypeRegex
+}
+
+// MatchesExpression tests the string Subject against Pattern, as in
+// "line =~ /(\d+)-(\d+)/". Pattern may evaluate to a regex or to a string
+// holding one. The value is null if Subject does not match, and otherwise a
+// map describing the leftmost match.
+type MatchesExpression struct {
+	Subject  Node
+	Pattern  Node
+	Metadata Metadata
+}
+
+func (m *MatchesExpression) GetType() NodeType {
+	return NodeTypeMatches
+}
+
 type ComparisonExpression struct {
 	Operator string
 	Left     Node
 	Right    Node
+	Metadata Metadata
 }
 
 func (ce *ComparisonExpression) GetType() NodeType {
 	return "ComparisonExpression"
 }
 
+// ParallelBlock runs each statement of Body concurrently. Its value is a list
+// holding the value of each statement, in the order of Body.
+//
+// Each statement runs in a scope of its own nested in the enclosing scope, so
+// variables it declares are private to it. It may read and assign the
+// variables of enclosing scopes; each read or assignment is atomic, but
+// statements that assign the same variable race, and the last assignment wins.
+// Statements may assign to elements of a shared list or map only if the
+// executor shares containers; see its SetSharedContainers method.
+//
+// The first statement to fail cancels the others: each stops before its next
+// node, as do the parallel constructs and tasks it started. The block returns
+// only once every statement has stopped, and reports the errors of those that
+// failed rather than of those it cancelled.
+//
+// If MaxConcurrency is positive, at most that many of the statements run at
+// once, as for a block calling a rate-limited service. The executor's own
+// limit on goroutines still applies. If RateLimit is set, statements also
+// start no faster than it allows. Statements beyond MaxConcurrency wait in a
+// queue; if QueueSize is positive, at most that many wait, and Overflow says
+// what happens to the statements that do not fit.
+//
+// When parallel constructs contend for the executor's goroutines, those of
+// higher Priority have their statements started first. Zero is the default,
+// and bulk work can use a negative priority. Parallel constructs nested in a
+// statement take the block's priority unless they set their own.
 type ParallelBlock struct {
-	Body []Node
+	Body           []Node
+	MaxConcurrency int
+	RateLimit      *RateLimit
+	Priority       int
+	QueueSize      int
+	Overflow       Overflow
+	Metadata       Metadata
+}
+
+// Overflow says what a parallel construct does with a statement or iteration
+// that finds its queue full. The zero value blocks, as OverflowBlock does.
+type Overflow string
+
+const (
+	OverflowBlock Overflow = "block" // Wait until a queued one starts.
+	OverflowDrop  Overflow = "drop"  // Skip it; its value is nil.
+	OverflowError Overflow = "error" // Cancel the construct and fail.
+)
+
+// RateLimit is a token bucket: up to Burst events may happen at once, and
+// after that PerSecond events a second. A Burst below one counts as one.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
 }
 
 func (pb *ParallelBlock) GetType() NodeType {
 	return "ParallelBlock"
 }
 
+// AsyncCall starts Call in the background and evaluates to a future for its
+// result. The call, including its arguments, is evaluated in a scope of its
+// own nested in the current one, as a statement of a ParallelBlock is.
+type AsyncCall struct {
+	Call     *FunctionCall
+	Metadata Metadata
+}
+
+func (ac *AsyncCall) GetType() NodeType {
+	return NodeTypeAsyncCall
+}
+
+// Await waits for the future that Value evaluates to and yields the result of
+// its call. An error raised by the call is raised by Await instead.
+type Await struct {
+	Value    Node
+	Metadata Metadata
+}
+
+func (a *Await) GetType() NodeType {
+	return NodeTypeAwait
+}
+
+// Spawn starts Body in the background and evaluates to a task handle, a future
+// for the value of Body's last statement. Body runs in a scope of its own, as
+// a statement of a ParallelBlock does, and cannot break out of an enclosing
+// loop or return from an enclosing function. Await, or the join and joinAll
+// builtins, wait for the task.
+type Spawn struct {
+	Body     []Node
+	Metadata Metadata
+}
+
+func (s *Spawn) GetType() NodeType {
+	return NodeTypeSpawn
+}
+
 type FunctionCall struct {
 	Name string
-	Args []Node
+	// Callee, if set, is evaluated to obtain the function to call, and Name is ignored.
+	Callee Node
+	Args   []Node
+	// IdempotencyKey optionally identifies a side-effecting builtin call so that
+	// it is not repeated when a workflow is retried or resumed.
+	IdempotencyKey Node
+	Pos            Position
+	Metadata       Metadata
 }
 
 func (fc *FunctionCall) GetType() NodeType {
@@ -108,18 +717,46 @@ func (fc *FunctionCall) GetType() NodeType {
 type FunctionDeclaration struct {
 	Name       string
 	Parameters []*Variable
+	// Defaults optionally holds a default value expression for each parameter,
+	// aligned with Parameters; nil entries mark required parameters. A default
+	// is evaluated in the call's scope when its argument is omitted, so it may
+	// refer to earlier parameters.
+	Defaults []Node
+	// ParamTypes and ReturnType are optional type annotations, checked by
+	// package typecheck. ParamTypes is aligned with Parameters; empty entries
+	// leave a parameter unannotated.
+	ParamTypes []string
+	ReturnType string
 	Body       []Node
+	Pos        Position
+	Metadata   Metadata
 }
 
 func (fd *FunctionDeclaration) GetType() NodeType {
 	return "FunctionDeclaration"
 }
 
+// FunctionLiteral evaluates to an anonymous function that closes over the scope it is evaluated in.
+type FunctionLiteral struct {
+	Parameters []*Variable
+	Defaults   []Node   // Optional default value expressions, as in FunctionDeclaration.
+	ParamTypes []string // Optional type annotations, as in FunctionDeclaration.
+	ReturnType string
+	Body       []Node
+	Pos        Position
+	Metadata   Metadata
+}
+
+func (fl *FunctionLiteral) GetType() NodeType {
+	return NodeTypeFunctionLiteral
+}
+
 type ForLoop struct {
 	Initialization Node
 	Condition      Node
 	Post           Node
 	Body           []Node
+	Metadata       Metadata
 }
 
 func (fl *ForLoop) GetType() NodeType {
@@ -129,16 +766,229 @@ func (fl *ForLoop) GetType() NodeType {
 type WhileLoop struct {
 	Condition Node
 	Body      []Node
+	Metadata  Metadata
 }
 
 func (wl *WhileLoop) GetType() NodeType {
 	return "WhileLoop"
 }
 
+// ForEachLoop runs Body once for each element of the list, map, or string that
+// Collection evaluates to, binding the element to Value and, if Key is set, its
+// index or map key to Key. Maps are visited in sorted key order and strings one
+// character at a time.
+type ForEachLoop struct {
+	Key        *Variable
+	Value      *Variable
+	Collection Node
+	Body       []Node
+	Metadata   Metadata
+}
+
+func (fel *ForEachLoop) GetType() NodeType {
+	return NodeTypeForEach
+}
+
+// ParallelForLoop runs Body once for each element of Collection, as a
+// ForEachLoop does, but runs the iterations concurrently. Each iteration binds
+// Key and Value in a scope of its own, as a statement of a ParallelBlock runs.
+// The value of the loop is a list holding the value of the last statement of
+// each iteration, in the order of the elements; an iterator is read to its end
+// before any iteration starts.
+//
+// A continue statement ends its iteration with a null value; break is an
+// error. If FailFast is set, the first failing iteration cancels the
+// iterations already running, as a failing statement of a ParallelBlock does,
+// and the others are skipped. Otherwise every iteration runs and their errors
+// are reported together.
+// MaxConcurrency limits the iterations running at once, QueueSize and
+// Overflow bound those waiting to start, and Priority orders them against
+// other parallel work, as for ParallelBlock.
+type ParallelForLoop struct {
+	Key            *Variable
+	Value          *Variable
+	Collection     Node
+	Body           []Node
+	MaxConcurrency int
+	FailFast       bool
+	Priority       int
+	QueueSize      int
+	Overflow       Overflow
+	Metadata       Metadata
+}
+
+func (pfl *ParallelForLoop) GetType() NodeType {
+	return NodeTypeParallelFor
+}
+
+// Pipeline passes each element of Source through Stages, each an expression
+// evaluating to a function of one argument, and evaluates to the list of the
+// results in the order of Source. Every stage runs on a goroutine of its own
+// and hands its results to the next through a channel holding up to Buffer
+// elements (at least one), so the stages work on successive elements at
+// once. An iterator source is read as the first stage is ready for more.
+//
+// The first failing call cancels the pipeline, as a failing statement of a
+// ParallelBlock does, and its error is returned.
+type Pipeline struct {
+	Source   Node
+	Stages   []Node
+	Buffer   int
+	Metadata Metadata
+}
+
+func (p *Pipeline) GetType() NodeType {
+	return NodeTypePipeline
+}
+
+// TaskGraph runs Tasks as a directed acyclic graph: each task starts once the
+// tasks it depends on have finished, and tasks whose dependencies are met run
+// concurrently, at most MaxConcurrency at once if that is positive. The value
+// of the graph is a map from each task's name to the value of its last
+// statement.
+//
+// A task runs in a scope of its own in which the value of each of its
+// dependencies is bound to a variable named after the dependency. The first
+// failing task cancels the others, as a failing statement of a ParallelBlock
+// does, and tasks that have not started are skipped.
+type TaskGraph struct {
+	Tasks          []*GraphTask
+	MaxConcurrency int
+	Metadata       Metadata
+}
+
+// GraphTask is one task of a TaskGraph.
+type GraphTask struct {
+	Name      string
+	DependsOn []string // Names of the tasks whose values the task needs.
+	Body      []Node
+}
+
+func (tg *TaskGraph) GetType() NodeType {
+	return NodeTypeTaskGraph
+}
+
+// Race runs each statement of Body concurrently in a scope of its own, as a
+// ParallelBlock does, and evaluates to the value of the first statement to
+// succeed. The others are then cancelled and Race waits for them to stop, so
+// a statement hedging a slow call across replicas should make it through a
+// builtin that honours cancellation. If every statement fails, their errors
+// are reported together.
+type Race struct {
+	Body     []Node
+	Metadata Metadata
+}
+
+func (r *Race) GetType() NodeType {
+	return NodeTypeRace
+}
+
+// Break ends the innermost enclosing loop.
+type Break struct {
+	Metadata Metadata
+}
+
+func (b *Break) GetType() NodeType {
+	return NodeTypeBreak
+}
+
+// Continue skips the rest of the current iteration of the innermost enclosing loop.
+type Continue struct {
+	Metadata Metadata
+}
+
+func (c *Continue) GetType() NodeType {
+	return NodeTypeContinue
+}
+
+// YieldStatement produces Value from a generator and suspends it until the
+// caller asks for the next value. A function whose body contains a yield
+// statement is a generator function: calling it returns a generator without
+// running the body.
+type YieldStatement struct {
+	Value    Node
+	Metadata Metadata
+}
+
+func (ys *YieldStatement) GetType() NodeType {
+	return NodeTypeYield
+}
+
+// Assert halts execution with an assertion error when Condition is not truthy.
+// Message, if set, is evaluated only on failure and included in the error.
+type Assert struct {
+	Condition Node
+	Message   Node
+	Metadata  Metadata
+}
+
+func (a *Assert) GetType() NodeType {
+	return NodeTypeAssert
+}
+
+// ReturnStatement returns Value from the enclosing function. If Values is set,
+// the function returns a tuple of them instead and Value is ignored.
 type ReturnStatement struct {
-	Value Node
+	Value    Node
+	Values   []Node
+	Pos      Position
+	Metadata Metadata
 }
 
 func (rs *ReturnStatement) GetType() NodeType {
 	return "ReturnStatement"
 }
+
+// Cached executes Body once per distinct Key value and reuses the result until TTL elapses.
+// A zero TTL keeps the result for as long as the cache backend retains it.
+type Cached struct {
+	Key      Node
+	TTL      time.Duration
+	Body     []Node
+	Metadata Metadata
+}
+
+func (c *Cached) GetType() NodeType {
+	return NodeTypeCached
+}
+
+// WithTimeout executes Body in a scope of its own and evaluates to the value of
+// its last statement, unless Body runs longer than Timeout. Body is then
+// cancelled, along with the parallel branches and tasks it started, and
+// WithTimeout evaluates Default if it is set or fails with a timeout error if
+// not. A zero Timeout sets no deadline.
+type WithTimeout struct {
+	Timeout  time.Duration
+	Body     []Node
+	Default  Node
+	Metadata Metadata
+}
+
+func (w *WithTimeout) GetType() NodeType {
+	return NodeTypeWithTimeout
+}
+
+// Retry executes Body, in a fresh scope each time, until it succeeds or
+// Attempts attempts have failed, and evaluates to the value of its last
+// statement. After the last attempt the error of that attempt is returned.
+type Retry struct {
+	Attempts int // Attempts at most; values below one mean a single attempt.
+	// Delay is the wait before the second attempt. Each later wait is the one
+	// before multiplied by Multiplier, which is ignored unless above one, and
+	// capped at MaxDelay if that is set.
+	Delay      time.Duration
+	Multiplier float64
+	MaxDelay   time.Duration
+	Jitter     bool // Whether each wait is drawn uniformly between zero and its full length.
+	// RetryOn, if set, evaluates to a function that decides whether a failed
+	// attempt is retried. It is called with a map holding the error's
+	// "message", the "builtin" that failed or null, and the "attempt" number,
+	// starting at 1, and the attempt is retried if it returns a true value.
+	RetryOn  Node
+	Body     []Node
+	Metadata Metadata
+}
+
+func (r *Retry) GetType() NodeType {
+	return NodeTypeRetry
+}
@@ -0,0 +1,48 @@
+package models
+
+import "fmt"
+
+// Position is a location in silk source text. Lines and columns start at 1; the
+// zero Position means the location is unknown, as for ASTs built in code.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String renders the position as "line:column", or "-" if it is unknown.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// PositionOf returns the source position recorded on node, or the zero
+// Position if the node does not record one.
+func PositionOf(node Node) Position {
+	switch n := node.(type) {
+	case *BinaryExpression:
+		return n.Pos
+	case *Assignment:
+		return n.Pos
+	case *VariableDeclaration:
+		return n.Pos
+	case *ConstDeclaration:
+		return n.Pos
+	case *FunctionCall:
+		return n.Pos
+	case *FunctionDeclaration:
+		return n.Pos
+	case *FunctionLiteral:
+		return n.Pos
+	case *ReturnStatement:
+		return n.Pos
+	default:
+		return Position{}
+	}
+}
@@ -0,0 +1,200 @@
+package models
+
+// Walk traverses an AST in depth-first order, calling fn for each node. If fn
+// returns false, the children of that node are skipped.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkList(n.Body, fn)
+	case *BinaryExpression:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *ComparisonExpression:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *ComparisonChain:
+		walkList(n.Operands, fn)
+	case *MatchesExpression:
+		Walk(n.Subject, fn)
+		Walk(n.Pattern, fn)
+	case *UnaryExpression:
+		Walk(n.Operand, fn)
+	case *LogicalExpression:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case *Assignment:
+		if n.Variable != nil {
+			Walk(n.Variable, fn)
+		}
+		Walk(n.Value, fn)
+	case *VariableDeclaration:
+		if n.Variable != nil {
+			Walk(n.Variable, fn)
+		}
+		Walk(n.Value, fn)
+	case *ConstDeclaration:
+		if n.Variable != nil {
+			Walk(n.Variable, fn)
+		}
+		Walk(n.Value, fn)
+	case *MultiAssignment:
+		for _, v := range n.Variables {
+			Walk(v, fn)
+		}
+		Walk(n.Value, fn)
+	case *CompoundAssignment:
+		if n.Variable != nil {
+			Walk(n.Variable, fn)
+		}
+		Walk(n.Value, fn)
+	case *IncDecStatement:
+		if n.Variable != nil {
+			Walk(n.Variable, fn)
+		}
+	case *TemplateString:
+		walkList(n.Parts, fn)
+	case *ArrayLiteral:
+		walkList(n.Elements, fn)
+	case *MapLiteral:
+		for _, entry := range n.Entries {
+			Walk(entry.Key, fn)
+			Walk(entry.Value, fn)
+		}
+	case *IndexExpression:
+		Walk(n.Object, fn)
+		Walk(n.Index, fn)
+	case *Spread:
+		Walk(n.Value, fn)
+	case *Range:
+		Walk(n.Start, fn)
+		Walk(n.End, fn)
+		Walk(n.Step, fn)
+	case *SliceExpression:
+		Walk(n.Object, fn)
+		Walk(n.Start, fn)
+		Walk(n.End, fn)
+	case *IndexAssignment:
+		Walk(n.Object, fn)
+		Walk(n.Index, fn)
+		Walk(n.Value, fn)
+	case *MethodDeclaration:
+		if n.Receiver != nil {
+			Walk(n.Receiver, fn)
+		}
+		if n.Function != nil {
+			Walk(n.Function, fn)
+		}
+	case *StructLiteral:
+		for _, field := range n.Fields {
+			Walk(field.Value, fn)
+		}
+	case *MemberExpression:
+		Walk(n.Object, fn)
+	case *MemberAssignment:
+		Walk(n.Object, fn)
+		Walk(n.Value, fn)
+	case *MatchExpression:
+		Walk(n.Value, fn)
+		for _, arm := range n.Arms {
+			Walk(arm.Pattern, fn)
+			Walk(arm.Guard, fn)
+			Walk(arm.Body, fn)
+		}
+	case *TypePattern:
+		Walk(n.Pattern, fn)
+	case *IfStatement:
+		Walk(n.Condition, fn)
+		Walk(n.Consequent, fn)
+		Walk(n.Alternate, fn)
+	case *ParallelBlock:
+		walkList(n.Body, fn)
+	case *FunctionCall:
+		Walk(n.Callee, fn)
+		walkList(n.Args, fn)
+		Walk(n.IdempotencyKey, fn)
+	case *FunctionDeclaration:
+		for _, param := range n.Parameters {
+			Walk(param, fn)
+		}
+		walkList(n.Defaults, fn)
+		walkList(n.Body, fn)
+	case *FunctionLiteral:
+		for _, param := range n.Parameters {
+			Walk(param, fn)
+		}
+		walkList(n.Defaults, fn)
+		walkList(n.Body, fn)
+	case *ForLoop:
+		Walk(n.Initialization, fn)
+		Walk(n.Condition, fn)
+		Walk(n.Post, fn)
+		walkList(n.Body, fn)
+	case *WhileLoop:
+		Walk(n.Condition, fn)
+		walkList(n.Body, fn)
+	case *ForEachLoop:
+		if n.Key != nil {
+			Walk(n.Key, fn)
+		}
+		if n.Value != nil {
+			Walk(n.Value, fn)
+		}
+		Walk(n.Collection, fn)
+		walkList(n.Body, fn)
+	case *ParallelForLoop:
+		if n.Key != nil {
+			Walk(n.Key, fn)
+		}
+		if n.Value != nil {
+			Walk(n.Value, fn)
+		}
+		Walk(n.Collection, fn)
+		walkList(n.Body, fn)
+	case *YieldStatement:
+		Walk(n.Value, fn)
+	case *Assert:
+		Walk(n.Condition, fn)
+		Walk(n.Message, fn)
+	case *ReturnStatement:
+		Walk(n.Value, fn)
+		walkList(n.Values, fn)
+	case *Cached:
+		Walk(n.Key, fn)
+		walkList(n.Body, fn)
+	case *AsyncCall:
+		if n.Call != nil {
+			Walk(n.Call, fn)
+		}
+	case *Await:
+		Walk(n.Value, fn)
+	case *Spawn:
+		walkList(n.Body, fn)
+	case *WithTimeout:
+		walkList(n.Body, fn)
+		Walk(n.Default, fn)
+	case *Retry:
+		Walk(n.RetryOn, fn)
+		walkList(n.Body, fn)
+	case *Pipeline:
+		Walk(n.Source, fn)
+		walkList(n.Stages, fn)
+	case *TaskGraph:
+		for _, task := range n.Tasks {
+			if task != nil {
+				walkList(task.Body, fn)
+			}
+		}
+	case *Race:
+		walkList(n.Body, fn)
+	}
+}
+
+func walkList(nodes []Node, fn func(Node) bool) {
+	for _, node := range nodes {
+		Walk(node, fn)
+	}
+}
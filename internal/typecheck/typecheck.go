@@ -0,0 +1,638 @@
+// Package typecheck validates the optional type annotations of a silk program
+// before it runs.
+//
+// Checking is gradual: a value whose type cannot be determined statically,
+// such as an unannotated parameter or the result of a builtin, has type Any and
+// is compatible with every annotation. Only mismatches between types that are
+// both known are reported. Operators are checked under the executor's default
+// strict coercion mode.
+package typecheck
+
+import (
+	"fmt"
+
+	"silk/internal/models"
+)
+
+// Type names accepted in annotations, besides the names of declared struct and enum types.
+const (
+	Any       = "any"
+	Number    = "number"
+	Int       = "int"
+	String    = "string"
+	Bool      = "bool"
+	Null      = "null"
+	List      = "list"
+	Map       = "map"
+	Tuple     = "tuple"
+	Function  = "function"
+	Generator = "generator"
+	Matrix    = "matrix"
+	Range     = "range"
+	Time      = "time"
+	Duration  = "duration"
+	BigInt    = "bigint"
+	Decimal   = "decimal"
+	Regex     = "regex"
+	Future    = "future"
+	Mutex     = "mutex"
+	Semaphore = "semaphore"
+	Atomic    = "atomic"
+)
+
+var builtinTypes = map[string]bool{
+	Any: true, Number: true, Int: true, String: true, Bool: true, Null: true,
+	List: true, Map: true, Tuple: true, Function: true, Generator: true, Matrix: true,
+	Range: true, Time: true, Duration: true, BigInt: true, Decimal: true, Regex: true,
+	Future: true, Mutex: true, Semaphore: true, Atomic: true,
+}
+
+// Error is a type mismatch found in a program.
+type Error struct {
+	Node models.Node     // The node the mismatch was found at.
+	Pos  models.Position // Position of Node, if known.
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Msg
+	}
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Check validates program and returns the mismatches found, in the order the
+// offending nodes appear.
+func Check(program models.Node) []*Error {
+	c := &checker{
+		functions: make(map[string]*models.FunctionDeclaration),
+		types:     make(map[string]bool),
+		enums:     make(map[string]bool),
+		operators: make(map[string]map[string]*models.FunctionDeclaration),
+	}
+	models.Walk(program, func(node models.Node) bool {
+		switch n := node.(type) {
+		case *models.FunctionDeclaration:
+			c.functions[n.Name] = n
+		case *models.StructDeclaration:
+			c.types[n.Name] = true
+		case *models.EnumDeclaration:
+			c.types[n.Name] = true
+			c.enums[n.Name] = true
+		case *models.MethodDeclaration:
+			if c.operators[n.Type] == nil {
+				c.operators[n.Type] = make(map[string]*models.FunctionDeclaration)
+			}
+			c.operators[n.Type][n.Function.Name] = n.Function
+		}
+		return true
+	})
+	c.check(program, newScope(nil), nil)
+	return c.errors
+}
+
+type checker struct {
+	functions map[string]*models.FunctionDeclaration
+	types     map[string]bool // Struct and enum types declared by the program.
+	enums     map[string]bool
+	operators map[string]map[string]*models.FunctionDeclaration // Methods of each struct type, which may overload operators.
+	errors    []*Error
+}
+
+// scope maps the variables visible at a point in the program to their types.
+type scope struct {
+	vars   map[string]string
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{vars: make(map[string]string), parent: parent}
+}
+
+func (s *scope) lookup(name string) (string, bool) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if t, ok := sc.vars[name]; ok {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// signature is the annotated type of a function being checked.
+type signature struct {
+	name       string
+	returnType string
+}
+
+func (c *checker) errorf(node models.Node, format string, args ...interface{}) {
+	c.errors = append(c.errors, &Error{Node: node, Pos: models.PositionOf(node), Msg: fmt.Sprintf(format, args...)})
+}
+
+// annotation validates a type annotation, returning Any for an empty or unknown one.
+func (c *checker) annotation(node models.Node, t string) string {
+	if t == "" {
+		return Any
+	}
+	if !builtinTypes[t] && !c.types[t] {
+		c.errorf(node, "unknown type %q", t)
+		return Any
+	}
+	return t
+}
+
+// assignable reports whether a value of type got may be stored where want is
+// expected. An integer may be used where a number is expected.
+func assignable(want, got string) bool {
+	return want == Any || got == Any || want == got || want == Number && got == Int
+}
+
+// check validates node in sc and returns the type of its value. fn is the
+// function whose body contains node, or nil at the top level.
+func (c *checker) check(node models.Node, sc *scope, fn *signature) string {
+	switch n := node.(type) {
+	case nil:
+		return Any
+	case *models.Program:
+		c.block(n.Body, sc, fn)
+		return Any
+	case *models.Number:
+		return Number
+	case *models.Integer:
+		return Int
+	case *models.Duration:
+		return Duration
+	case *models.BigInt:
+		return BigInt
+	case *models.Decimal:
+		return Decimal
+	case *models.Regex:
+		return Regex
+	case *models.MatchesExpression:
+		if t := c.check(n.Subject, sc, fn); !assignable(String, t) {
+			c.errorf(n, "operator =~ expects a string to match, got %s", t)
+		}
+		if t := c.check(n.Pattern, sc, fn); !assignable(Regex, t) && t != String {
+			c.errorf(n, "operator =~ expects a regex or string pattern, got %s", t)
+		}
+		return Any
+	case *models.String:
+		return String
+	case *models.Boolean:
+		return Bool
+	case *models.Null:
+		return Null
+	case *models.TemplateString:
+		c.block(n.Parts, sc, fn)
+		return String
+	case *models.ArrayLiteral:
+		c.block(n.Elements, sc, fn)
+		return List
+	case *models.MapLiteral:
+		for _, entry := range n.Entries {
+			c.check(entry.Key, sc, fn)
+			c.check(entry.Value, sc, fn)
+		}
+		return Map
+	case *models.StructLiteral:
+		for _, field := range n.Fields {
+			c.check(field.Value, sc, fn)
+		}
+		if c.types[n.Name] {
+			return n.Name
+		}
+		return Any
+	case *models.Variable:
+		if t, ok := sc.lookup(n.Name); ok {
+			return t
+		}
+		return Any
+	case *models.MemberExpression:
+		// A member of an enum has the enum's type.
+		if v, ok := n.Object.(*models.Variable); ok && c.enums[v.Name] {
+			if _, shadowed := sc.lookup(v.Name); !shadowed {
+				return v.Name
+			}
+		}
+		c.check(n.Object, sc, fn)
+		return Any
+
+	case *models.VariableDeclaration:
+		want := c.annotation(n, n.Type)
+		got := Null
+		if n.Value != nil {
+			got = c.check(n.Value, sc, fn)
+		}
+		if !assignable(want, got) {
+			c.errorf(n, "cannot initialize %s of type %s with %s", n.Variable.Name, want, got)
+		}
+		sc.vars[n.Variable.Name] = want
+		return want
+	case *models.ConstDeclaration:
+		want := c.annotation(n, n.Type)
+		got := c.check(n.Value, sc, fn)
+		if !assignable(want, got) {
+			c.errorf(n, "cannot initialize %s of type %s with %s", n.Variable.Name, want, got)
+		}
+		// A constant keeps the type of its value, so it need not be annotated.
+		if want == Any {
+			want = got
+		}
+		sc.vars[n.Variable.Name] = want
+		return want
+	case *models.Assignment:
+		got := c.check(n.Value, sc, fn)
+		if want, ok := sc.lookup(n.Variable.Name); ok {
+			if !assignable(want, got) {
+				c.errorf(n, "cannot assign %s to %s of type %s", got, n.Variable.Name, want)
+			}
+		} else {
+			sc.vars[n.Variable.Name] = Any
+		}
+		return got
+	case *models.MultiAssignment:
+		c.check(n.Value, sc, fn)
+		for _, v := range n.Variables {
+			if _, ok := sc.lookup(v.Name); !ok {
+				sc.vars[v.Name] = Any
+			}
+		}
+		return Any
+	case *models.CompoundAssignment:
+		target, ok := sc.lookup(n.Variable.Name)
+		if ok && !assignable(Number, target) {
+			c.errorf(n, "operator %s expects a number, but %s has type %s", n.Operator, n.Variable.Name, target)
+		}
+		t := c.check(n.Value, sc, fn)
+		if !assignable(Number, t) {
+			c.errorf(n, "operator %s expects a number, got %s", n.Operator, t)
+		}
+		if target == Int && t == Int {
+			return Int
+		}
+		return Number
+	case *models.IncDecStatement:
+		t, ok := sc.lookup(n.Variable.Name)
+		if ok && !assignable(Number, t) {
+			c.errorf(n, "operator %s expects a number, but %s has type %s", n.Operator, n.Variable.Name, t)
+		}
+		if t == Int {
+			return Int
+		}
+		return Number
+
+	case *models.BinaryExpression:
+		left, right := c.check(n.Left, sc, fn), c.check(n.Right, sc, fn)
+		if method, ok := c.operators[left][n.Operator]; ok {
+			return c.result(method.ReturnType)
+		}
+		if left == Time || left == Duration || right == Time || right == Duration {
+			return c.temporal(n, left, right)
+		}
+		if left == Decimal || right == Decimal {
+			for _, t := range []string{left, right} {
+				if !numeric(t) {
+					c.errorf(n, "operator %s expects decimals, big integers, or numbers, got %s", n.Operator, t)
+					break
+				}
+			}
+			return Decimal
+		}
+		if left == BigInt || right == BigInt {
+			for _, t := range []string{left, right} {
+				if t != BigInt && !assignable(Number, t) {
+					c.errorf(n, "operator %s expects big integers or numbers, got %s", n.Operator, t)
+					break
+				}
+			}
+			return BigInt
+		}
+		for _, t := range []string{left, right} {
+			if !assignable(Number, t) {
+				c.errorf(n, "operator %s expects numbers, got %s", n.Operator, t)
+				break
+			}
+		}
+		switch {
+		case left == Int && right == Int:
+			return Int
+		case left == Number || right == Number:
+			return Number
+		default:
+			// Either operand may hold an integer.
+			return Any
+		}
+	case *models.UnaryExpression:
+		t := c.check(n.Operand, sc, fn)
+		if n.Operator == "!" {
+			return Bool
+		}
+		if !assignable(Number, t) {
+			c.errorf(n, "operator %s expects a number, got %s", n.Operator, t)
+		}
+		if t == Int || t == Any {
+			return t
+		}
+		return Number
+	case *models.LogicalExpression:
+		c.check(n.Left, sc, fn)
+		c.check(n.Right, sc, fn)
+		return Bool
+	case *models.ComparisonExpression:
+		c.comparison(n, n.Operator, c.check(n.Left, sc, fn), c.check(n.Right, sc, fn))
+		return Bool
+	case *models.AsyncCall:
+		if n.Call != nil {
+			c.check(n.Call, sc, fn)
+		}
+		return Future
+	case *models.Spawn:
+		c.block(n.Body, newScope(sc), fn)
+		return Future
+	case *models.WithTimeout:
+		c.block(n.Body, newScope(sc), fn)
+		if n.Default != nil {
+			c.check(n.Default, sc, fn)
+		}
+		return Any
+	case *models.TaskGraph:
+		for _, task := range n.Tasks {
+			body := newScope(sc)
+			for _, dep := range task.DependsOn {
+				body.vars[dep] = Any
+			}
+			c.block(task.Body, body, fn)
+		}
+		return Map
+	case *models.Race:
+		for _, stmt := range n.Body {
+			c.check(stmt, sc, fn)
+		}
+		return Any
+	case *models.Pipeline:
+		c.check(n.Source, sc, fn)
+		for _, stage := range n.Stages {
+			if t := c.check(stage, sc, fn); !assignable(Function, t) {
+				c.errorf(stage, "pipeline stage must be a function, got %s", t)
+			}
+		}
+		return List
+	case *models.Retry:
+		if n.RetryOn != nil {
+			if t := c.check(n.RetryOn, sc, fn); !assignable(Function, t) {
+				c.errorf(n, "retry expects a function to classify errors, got %s", t)
+			}
+		}
+		c.block(n.Body, newScope(sc), fn)
+		return Any
+	case *models.Await:
+		if t := c.check(n.Value, sc, fn); !assignable(Future, t) {
+			c.errorf(n, "await expects a future, got %s", t)
+		}
+		return Any
+	case *models.ParallelBlock:
+		for _, stmt := range n.Body {
+			c.check(stmt, sc, fn)
+		}
+		return List
+	case *models.ComparisonChain:
+		types := make([]string, len(n.Operands))
+		for i, operand := range n.Operands {
+			types[i] = c.check(operand, sc, fn)
+		}
+		if len(n.Operands) < 2 || len(n.Operators) != len(n.Operands)-1 {
+			c.errorf(n, "comparison chain has %d operands but %d operators", len(n.Operands), len(n.Operators))
+			return Bool
+		}
+		for i, operator := range n.Operators {
+			c.comparison(n, operator, types[i], types[i+1])
+		}
+		return Bool
+
+	case *models.FunctionDeclaration:
+		c.function(n, n.Name, n.Parameters, n.Defaults, n.ParamTypes, n.ReturnType, n.Body, newScope(sc))
+		if sc.parent != nil {
+			sc.vars[n.Name] = Function
+		}
+		return Any
+	case *models.FunctionLiteral:
+		c.function(n, "", n.Parameters, n.Defaults, n.ParamTypes, n.ReturnType, n.Body, newScope(sc))
+		return Function
+	case *models.MethodDeclaration:
+		body := newScope(sc)
+		if n.Receiver != nil {
+			body.vars[n.Receiver.Name] = c.annotation(n, n.Type)
+		}
+		f := n.Function
+		c.function(f, n.Type+"."+f.Name, f.Parameters, f.Defaults, f.ParamTypes, f.ReturnType, f.Body, body)
+		return Any
+	case *models.FunctionCall:
+		return c.call(n, sc, fn)
+	case *models.ReturnStatement:
+		got := Null
+		if n.Values != nil {
+			c.block(n.Values, sc, fn)
+			got = Tuple
+		} else if n.Value != nil {
+			got = c.check(n.Value, sc, fn)
+		}
+		if fn != nil && !assignable(fn.returnType, got) {
+			c.errorf(n, "function %s returns %s, got %s", fn.name, fn.returnType, got)
+		}
+		return Any
+
+	case *models.Range:
+		for _, bound := range []models.Node{n.Start, n.End, n.Step} {
+			if bound == nil {
+				continue
+			}
+			if t := c.check(bound, sc, fn); !assignable(Number, t) {
+				c.errorf(n, "range bounds must be numbers, got %s", t)
+			}
+		}
+		return Range
+
+	case *models.ForEachLoop:
+		c.check(n.Collection, sc, fn)
+		for _, v := range []*models.Variable{n.Key, n.Value} {
+			if v != nil {
+				if _, ok := sc.lookup(v.Name); !ok {
+					sc.vars[v.Name] = Any
+				}
+			}
+		}
+		c.block(n.Body, sc, fn)
+		return Any
+	case *models.ParallelForLoop:
+		c.check(n.Collection, sc, fn)
+		body := newScope(sc)
+		for _, v := range []*models.Variable{n.Key, n.Value} {
+			if v != nil {
+				body.vars[v.Name] = Any
+			}
+		}
+		c.block(n.Body, body, fn)
+		return List
+
+	case *models.MatchExpression:
+		c.check(n.Value, sc, fn)
+		for _, arm := range n.Arms {
+			// Pattern variables are bound in a scope of their own, as at run time.
+			armScope := newScope(sc)
+			models.Walk(arm.Pattern, func(p models.Node) bool {
+				if v, ok := p.(*models.Variable); ok {
+					armScope.vars[v.Name] = Any
+				}
+				return true
+			})
+			c.check(arm.Guard, armScope, fn)
+			c.check(arm.Body, armScope, fn)
+		}
+		return Any
+
+	default:
+		// Check the children of any other node in order.
+		models.Walk(node, func(child models.Node) bool {
+			if child == node {
+				return true
+			}
+			c.check(child, sc, fn)
+			return false
+		})
+		return Any
+	}
+}
+
+// block checks a sequence of nodes in sc.
+func (c *checker) block(nodes []models.Node, sc *scope, fn *signature) {
+	for _, node := range nodes {
+		c.check(node, sc, fn)
+	}
+}
+
+// function checks the annotations, defaults, and body of a function, with
+// body as the scope for its parameters.
+func (c *checker) function(node models.Node, name string, params []*models.Variable, defaults []models.Node,
+	paramTypes []string, returnType string, stmts []models.Node, body *scope) {
+	for i, param := range params {
+		t := Any
+		if i < len(paramTypes) {
+			t = c.annotation(node, paramTypes[i])
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			if got := c.check(defaults[i], body, nil); !assignable(t, got) {
+				c.errorf(node, "default value of parameter %s has type %s, want %s", param.Name, got, t)
+			}
+		}
+		body.vars[param.Name] = t
+	}
+	if name == "" {
+		name = "<anonymous>"
+	}
+	c.block(stmts, body, &signature{name: name, returnType: c.annotation(node, returnType)})
+}
+
+// comparison checks that operator can compare operands of the given types.
+// Ordering requires two numbers or two strings, unless a struct type
+// overloads it.
+func (c *checker) comparison(node models.Node, operator, left, right string) {
+	if operator == "==" || operator == "!=" || left == Any || right == Any {
+		return
+	}
+	if c.operators[left]["<"] != nil || c.operators[right]["<"] != nil {
+		return
+	}
+	if numeric(left) && numeric(right) {
+		return
+	}
+	if left != right || (left != Number && left != String && left != Time && left != Duration) {
+		c.errorf(node, "operator %s expects two numbers, strings, times, or durations, got %s and %s", operator, left, right)
+	}
+}
+
+// numeric reports whether t is a number type or may hold one.
+func numeric(t string) bool {
+	return t == Any || t == Number || t == Int || t == BigInt || t == Decimal
+}
+
+// temporal checks arithmetic on a time or duration and returns its result type.
+func (c *checker) temporal(n *models.BinaryExpression, left, right string) string {
+	if left == Any || right == Any {
+		return Any
+	}
+	// Integers scale durations as numbers do.
+	if left == Int {
+		left = Number
+	}
+	if right == Int {
+		right = Number
+	}
+	switch [3]string{left, n.Operator, right} {
+	case [3]string{Time, "+", Duration}, [3]string{Duration, "+", Time}, [3]string{Time, "-", Duration}:
+		return Time
+	case [3]string{Time, "-", Time}, [3]string{Duration, "+", Duration}, [3]string{Duration, "-", Duration},
+		[3]string{Duration, "*", Number}, [3]string{Number, "*", Duration}, [3]string{Duration, "/", Number}:
+		return Duration
+	case [3]string{Duration, "/", Duration}:
+		return Number
+	}
+	c.errorf(n, "operator %s is not defined for %s and %s", n.Operator, left, right)
+	return Any
+}
+
+// call checks a function call, comparing its arguments with the annotated
+// parameters of the declared function it calls, and returns the annotated
+// result type.
+func (c *checker) call(n *models.FunctionCall, sc *scope, fn *signature) string {
+	c.check(n.Callee, sc, fn)
+	args := make([]string, len(n.Args))
+	spread := false
+	for i, arg := range n.Args {
+		args[i] = c.check(arg, sc, fn)
+		_, isSpread := arg.(*models.Spread)
+		spread = spread || isSpread
+	}
+	c.check(n.IdempotencyKey, sc, fn)
+
+	// A variable of the same name shadows a declared function.
+	if n.Callee != nil {
+		return Any
+	}
+	if _, shadowed := sc.lookup(n.Name); shadowed {
+		return Any
+	}
+	decl, ok := c.functions[n.Name]
+	if !ok {
+		return Any
+	}
+	// The arguments a spread supplies are known only at run time.
+	if spread {
+		return c.result(decl.ReturnType)
+	}
+
+	required := len(decl.Parameters)
+	for required > 0 && required <= len(decl.Defaults) && decl.Defaults[required-1] != nil {
+		required--
+	}
+	if len(args) < required || len(args) > len(decl.Parameters) {
+		c.errorf(n, "function %s expects %d arguments, but got %d", n.Name, len(decl.Parameters), len(args))
+	}
+	for i, got := range args {
+		if i >= len(decl.Parameters) || i >= len(decl.ParamTypes) {
+			break
+		}
+		want := decl.ParamTypes[i]
+		if want != "" && (builtinTypes[want] || c.types[want]) && !assignable(want, got) {
+			c.errorf(n, "argument %d of %s: cannot use %s as %s", i+1, n.Name, got, want)
+		}
+	}
+	return c.result(decl.ReturnType)
+}
+
+// result returns the type produced by calling a function whose return type is
+// annotated as t, or Any if t is missing or unknown.
+func (c *checker) result(t string) string {
+	if t == "" || (!builtinTypes[t] && !c.types[t]) {
+		return Any
+	}
+	return t
+}
@@ -0,0 +1,128 @@
+package typecheck
+
+import (
+	"strings"
+	"testing"
+
+	"silk/internal/models"
+)
+
+func num(v float64) models.Node { return &models.Number{Value: v} }
+
+func integer(v int64) models.Node { return &models.Integer{Value: v} }
+
+func str(v string) models.Node { return &models.String{Value: v} }
+
+func ref(name string) *models.Variable { return &models.Variable{Name: name} }
+
+func call(name string, args ...models.Node) *models.FunctionCall {
+	return &models.FunctionCall{Name: name, Args: args}
+}
+
+func declare(name, typ string, val models.Node) *models.VariableDeclaration {
+	return &models.VariableDeclaration{Variable: ref(name), Type: typ, Value: val}
+}
+
+func binop(left models.Node, op string, right models.Node) models.Node {
+	return &models.BinaryExpression{Left: left, Operator: op, Right: right}
+}
+
+// scale is "function scale(x: number, by: int = 2): number { return x * by }".
+func scale() *models.FunctionDeclaration {
+	return &models.FunctionDeclaration{
+		Name:       "scale",
+		Parameters: []*models.Variable{ref("x"), ref("by")},
+		Defaults:   []models.Node{nil, integer(2)},
+		ParamTypes: []string{Number, Int},
+		ReturnType: Number,
+		Body:       []models.Node{&models.ReturnStatement{Value: binop(ref("x"), "*", ref("by"))}},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		body []models.Node
+		want string // Substring of the only error expected, or "" for none.
+	}{
+		{"matching annotation", []models.Node{declare("x", Number, num(1.5))}, ""},
+		{"int as number", []models.Node{declare("x", Number, integer(1))}, ""},
+		{"number as int", []models.Node{declare("x", Int, num(1.5))}, "cannot initialize x of type int with number"},
+		{"unknown type", []models.Node{declare("x", "money", num(1))}, `unknown type "money"`},
+		{"builtin result", []models.Node{declare("x", String, call("now"))}, ""},
+		{"assignment", []models.Node{
+			declare("x", String, str("a")),
+			&models.Assignment{Variable: ref("x"), Value: integer(1)},
+		}, "cannot assign int to x of type string"},
+		{"const keeps its type", []models.Node{
+			&models.ConstDeclaration{Variable: ref("N"), Value: str("a")},
+			declare("x", Int, ref("N")),
+		}, "cannot initialize x of type int with string"},
+		{"arithmetic", []models.Node{binop(str("a"), "-", integer(1))}, "operator - expects numbers, got string"},
+		{"ordering", []models.Node{
+			&models.ComparisonExpression{Left: str("a"), Operator: "<", Right: integer(1)},
+		}, "operator < expects two numbers, strings, times, or durations, got string and int"},
+		{"equality", []models.Node{
+			&models.ComparisonExpression{Left: str("a"), Operator: "==", Right: integer(1)},
+		}, ""},
+		{"call", []models.Node{scale(), declare("y", Number, call("scale", integer(3)))}, ""},
+		{"argument type", []models.Node{scale(), call("scale", str("3"))}, "argument 1 of scale: cannot use string as number"},
+		{"too few arguments", []models.Node{scale(), call("scale")}, "function scale expects 2 arguments, but got 0"},
+		{"too many arguments", []models.Node{scale(), call("scale", num(1), integer(2), integer(3))}, "expects 2 arguments, but got 3"},
+		{"result type", []models.Node{scale(), declare("s", String, call("scale", num(1)))}, "cannot initialize s of type string with number"},
+		{"shadowed function", []models.Node{
+			scale(),
+			&models.Assignment{Variable: ref("scale"), Value: str("f")},
+			call("scale", str("3")),
+		}, ""},
+		{"return type", []models.Node{&models.FunctionDeclaration{
+			Name:       "name",
+			ReturnType: String,
+			Body:       []models.Node{&models.ReturnStatement{Value: integer(1)}},
+		}}, "function name returns string, got int"},
+		{"default type", []models.Node{&models.FunctionDeclaration{
+			Name:       "f",
+			Parameters: []*models.Variable{ref("n")},
+			Defaults:   []models.Node{str("x")},
+			ParamTypes: []string{Int},
+		}}, "default value of parameter n has type string, want int"},
+		{"enum member", []models.Node{
+			&models.EnumDeclaration{Name: "Color", Members: []string{"Red"}},
+			declare("c", "Color", &models.MemberExpression{Object: ref("Color"), Property: "Red"}),
+			declare("n", Int, &models.MemberExpression{Object: ref("Color"), Property: "Red"}),
+		}, "cannot initialize n of type int with Color"},
+		{"duration arithmetic", []models.Node{
+			declare("d", Duration, binop(&models.Duration{}, "*", integer(2))),
+		}, ""},
+		{"time arithmetic", []models.Node{binop(&models.Duration{}, "-", str("a"))}, "operator - is not defined for duration and string"},
+		{"decimal arithmetic", []models.Node{
+			declare("d", Decimal, binop(&models.Decimal{}, "+", integer(1))),
+		}, ""},
+		{"inside closures", []models.Node{&models.FunctionLiteral{
+			Body: []models.Node{declare("x", Bool, integer(1))},
+		}}, "cannot initialize x of type bool with int"},
+		{"inside parallel blocks", []models.Node{&models.ParallelBlock{
+			Body: []models.Node{declare("x", Bool, integer(1))},
+		}}, "cannot initialize x of type bool with int"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Check(&models.Program{Body: tt.body})
+			switch {
+			case tt.want == "" && len(errs) > 0:
+				t.Errorf("errors = %v, want none", errs)
+			case tt.want != "" && (len(errs) != 1 || !strings.Contains(errs[0].Error(), tt.want)):
+				t.Errorf("errors = %v, want one containing %q", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	decl := declare("x", Int, str("a"))
+	decl.Pos = models.Position{Line: 3, Column: 5}
+	errs := Check(&models.Program{Body: []models.Node{decl}})
+	if len(errs) != 1 || errs[0].Node != decl || !strings.HasPrefix(errs[0].Error(), "3:5: ") {
+		t.Errorf("errors = %v, want one at 3:5", errs)
+	}
+}
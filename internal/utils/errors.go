@@ -1 +1,118 @@
 package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"silk/internal/models"
+)
+
+// BuiltinError marks an error as having been returned by a builtin function.
+// It does not alter the message of the error it wraps.
+type BuiltinError struct {
+	Name string
+	Err  error
+}
+
+func (e *BuiltinError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BuiltinError) Unwrap() error {
+	return e.Err
+}
+
+// PermissionError reports a builtin call refused because the builtin needs a
+// capability that the executor's policy does not grant.
+type PermissionError struct {
+	Builtin    string
+	Capability string
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied: %s requires the %q capability", e.Builtin, e.Capability)
+}
+
+// AssertionError reports a failed assert statement. When the asserted condition
+// was a comparison, Operator, Left, and Right record it with its evaluated operands.
+type AssertionError struct {
+	Message  string
+	Operator string
+	Left     interface{}
+	Right    interface{}
+}
+
+func (e *AssertionError) Error() string {
+	msg := "assertion failed"
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Operator != "" {
+		msg += fmt.Sprintf(" (%v %s %v)", e.Left, e.Operator, e.Right)
+	}
+	return msg
+}
+
+// PanicError reports a panic recovered on a goroutine the executor started to
+// evaluate Node, such as a statement of a ParallelBlock. Node is nil if the
+// goroutine was calling a function for a builtin, as parallelMap does.
+type PanicError struct {
+	Node  models.Node
+	Value interface{} // Value passed to panic.
+	Stack []byte      // Stack of the panicking goroutine.
+}
+
+func (e *PanicError) Error() string {
+	if e.Node == nil {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+	where := string(e.Node.GetType())
+	if pos := models.PositionOf(e.Node); pos.IsValid() {
+		where += " at " + pos.String()
+	}
+	return fmt.Sprintf("panic in %s: %v", where, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ParallelError reports the failures of the branches of a parallel construct,
+// such as the statements of a ParallelBlock, in the order of the branches.
+// errors.Is and errors.As see each branch's error.
+type ParallelError struct {
+	Errors []*BranchError
+}
+
+func (e *ParallelError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "multiple errors occurred: " + strings.Join(msgs, "; ")
+}
+
+func (e *ParallelError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// BranchError is the error of one branch of a parallel construct: the
+// statement of a ParallelBlock, or the iteration of a parallel loop, at Index.
+type BranchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BranchError) Error() string {
+	return fmt.Sprintf("branch %d: %v", e.Index, e.Err)
+}
+
+func (e *BranchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,99 @@
+// Package worker runs silk subtrees on behalf of remote executors.
+//
+// A worker process calls Serve with a factory that builds an Executor with the
+// builtins its tasks need. Coordinating executors connect with Dial and install
+// the returned Pool as their Dispatcher, after which ParallelBlock branches are
+// spread across the fleet.
+package worker
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"sync/atomic"
+
+	"silk/internal/executor"
+)
+
+// serviceName is the net/rpc service under which workers register.
+const serviceName = "SilkWorker"
+
+// Service executes RemoteTasks, building a fresh Executor for each task so that
+// tasks never observe one another's state.
+type Service struct {
+	newExecutor func() *executor.Executor
+}
+
+// NewService creates a Service that uses newExecutor to prepare an executor per task.
+func NewService(newExecutor func() *executor.Executor) *Service {
+	return &Service{newExecutor: newExecutor}
+}
+
+// Execute runs a single task. It is invoked through net/rpc.
+func (s *Service) Execute(task *executor.RemoteTask, result *executor.RemoteResult) error {
+	*result = *s.newExecutor().RunTask(task)
+	return nil
+}
+
+// Serve accepts connections on l and serves tasks until the listener is closed.
+func Serve(l net.Listener, newExecutor func() *executor.Executor) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, NewService(newExecutor)); err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go server.ServeConn(conn)
+	}
+}
+
+// Pool dispatches tasks across a set of workers in round-robin order.
+// It implements executor.Dispatcher.
+type Pool struct {
+	clients []*rpc.Client
+	next    atomic.Uint64
+}
+
+// Dial connects to every worker address and returns a Pool over them.
+func Dial(network string, addrs ...string) (*Pool, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("worker: no addresses given")
+	}
+	p := &Pool{}
+	for _, addr := range addrs {
+		client, err := rpc.Dial(network, addr)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.clients = append(p.clients, client)
+	}
+	return p, nil
+}
+
+// Dispatch sends task to the next worker in the pool and waits for its result.
+func (p *Pool) Dispatch(task *executor.RemoteTask) (*executor.RemoteResult, error) {
+	client := p.clients[(p.next.Add(1)-1)%uint64(len(p.clients))]
+	var result executor.RemoteResult
+	if err := client.Call(serviceName+".Execute", task, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close closes the connections to all workers.
+func (p *Pool) Close() error {
+	var errs []error
+	for _, client := range p.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"silk/internal/executor"
+	"silk/internal/models"
+)
+
+func ref(name string) *models.Variable { return &models.Variable{Name: name} }
+
+func num(v int64) models.Node { return &models.Integer{Value: v} }
+
+func call(name string, args ...models.Node) models.Node {
+	return &models.FunctionCall{Name: name, Args: args}
+}
+
+func ret(val models.Node) models.Node { return &models.ReturnStatement{Value: val} }
+
+func binop(left models.Node, op string, right models.Node) models.Node {
+	return &models.BinaryExpression{Left: left, Operator: op, Right: right}
+}
+
+func assign(name string, val models.Node) models.Node {
+	return &models.Assignment{Variable: ref(name), Value: val}
+}
+
+// dial starts a worker and returns an executor that dispatches its parallel
+// branches to it.
+func dial(t *testing.T) *executor.Executor {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go Serve(l, executor.NewExecutor)
+	pool, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	e := executor.NewExecutor()
+	e.SetDispatcher(pool)
+	return e
+}
+
+func TestRoundTrip(t *testing.T) {
+	e := dial(t)
+	xs := &models.ArrayLiteral{Elements: []models.Node{num(1), num(2), num(3)}}
+	fact := &models.FunctionLiteral{
+		Parameters: []*models.Variable{ref("n")},
+		Body: []models.Node{
+			&models.IfStatement{
+				Condition:  &models.ComparisonExpression{Left: ref("n"), Operator: "<=", Right: num(1)},
+				Consequent: ret(num(1)),
+			},
+			ret(binop(ref("n"), "*", call("fact", binop(ref("n"), "-", num(1))))),
+		},
+	}
+	val, err := e.Execute(&models.Program{Body: []models.Node{
+		&models.FunctionDeclaration{
+			Name:       "double",
+			Parameters: []*models.Variable{ref("x")},
+			Body:       []models.Node{ret(binop(ref("x"), "*", num(2)))},
+		},
+		assign("k", num(10)),
+		assign("add", &models.FunctionLiteral{
+			Parameters: []*models.Variable{ref("x")},
+			Body:       []models.Node{ret(binop(ref("x"), "+", ref("k")))},
+		}),
+		assign("fact", fact),
+		&models.ParallelBlock{Body: []models.Node{
+			call("map", xs, ref("double")),
+			call("map", xs, ref("add")),
+			call("fact", num(4)),
+			ref("add"),
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := val.([]interface{})
+	want := []interface{}{
+		[]interface{}{int64(2), int64(4), int64(6)},
+		[]interface{}{int64(11), int64(12), int64(13)},
+		int64(24),
+	}
+	if !reflect.DeepEqual(results[:3], want) {
+		t.Errorf("results = %v, want %v", results[:3], want)
+	}
+	// A closure returned by a worker comes back as a function value.
+	if _, ok := results[3].(*executor.Function); !ok {
+		t.Errorf("closure came back as %T", results[3])
+	}
+}
+
+func TestRoundTripUnsendableValue(t *testing.T) {
+	e := dial(t)
+	_, err := e.Execute(&models.Program{Body: []models.Node{
+		assign("m", call("mutex")),
+		&models.ParallelBlock{Body: []models.Node{ref("m")}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "cannot send a mutex") {
+		t.Errorf("err = %v, want an error about sending a mutex", err)
+	}
+}
+
+func TestRoundTripTaskGraph(t *testing.T) {
+	e := dial(t)
+	// A builtin registered only here fails on a worker, which has the
+	// standard builtins alone.
+	e.RegisterBuiltin("local", func(args []interface{}) (interface{}, error) { return int64(0), nil })
+	task := func(name string, body models.Node, deps ...string) *models.GraphTask {
+		return &models.GraphTask{Name: name, DependsOn: deps, Body: []models.Node{body}}
+	}
+	val, err := e.Execute(&models.TaskGraph{Tasks: []*models.GraphTask{
+		task("a", num(2)),
+		task("b", binop(ref("a"), "*", num(10)), "a"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := val.(map[string]interface{})["b"]; b != int64(20) {
+		t.Errorf("b = %v, want 20", b)
+	}
+	_, err = e.Execute(&models.TaskGraph{Tasks: []*models.GraphTask{task("c", call("local"))}})
+	if err == nil {
+		t.Error("a task calling a builtin the worker lacks succeeded, so it did not run on the worker")
+	}
+}